@@ -0,0 +1,548 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	v2 "github.com/neurallog/operator/api/v2"
+)
+
+// Tenant is a conversion spoke: v2 is the hub and the storage version. The
+// server, redis and registry sections map onto the v2 components list, and
+// every other field has the same shape in both versions.
+//
+// Leaf types whose fields are identical in both versions are converted with a
+// plain Go type conversion, so they share pointers, slices and maps with the
+// source object, like generated conversions do.
+
+var _ conversion.Convertible = &Tenant{}
+
+// ConvertTo converts this Tenant to the hub version
+func (src *Tenant) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*v2.Tenant)
+	if !ok {
+		return fmt.Errorf("unexpected hub type %T", dstRaw)
+	}
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec = tenantSpecToV2(&src.Spec)
+	dst.Status = tenantStatusToV2(&src.Status)
+	return nil
+}
+
+// ConvertFrom converts from the hub version to this Tenant
+func (dst *Tenant) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*v2.Tenant)
+	if !ok {
+		return fmt.Errorf("unexpected hub type %T", srcRaw)
+	}
+	spec, err := tenantSpecFromV2(&src.Spec)
+	if err != nil {
+		return err
+	}
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec = spec
+	dst.Status = tenantStatusFromV2(&src.Status)
+	return nil
+}
+
+func tenantSpecToV2(in *TenantSpec) v2.TenantSpec {
+	out := v2.TenantSpec{
+		DisplayName:    in.DisplayName,
+		Description:    in.Description,
+		Plan:           in.Plan,
+		Namespace:      (*v2.NamespaceSpec)(in.Namespace),
+		Resources:      resourcesToV2(in.Resources),
+		NetworkPolicy:  networkPolicyToV2(in.NetworkPolicy),
+		Exposure:       exposureToV2(in.Exposure),
+		Auth:           authToV2(in.Auth),
+		Authorization:  authorizationToV2(in.Authorization),
+		Suspended:      in.Suspended,
+		IdleTimeout:    in.IdleTimeout,
+		DeletionPolicy: v2.DeletionPolicy(in.DeletionPolicy),
+	}
+
+	// Components are listed in a fixed order, so a tenant converted from v1
+	// always comes out the same
+	if in.Server != nil {
+		out.Components = append(out.Components, v2.ComponentSpec{
+			Name:        v2.ComponentServer,
+			Replicas:    in.Server.Replicas,
+			Image:       in.Server.Image,
+			Resources:   resourcesToV2(in.Server.Resources),
+			Env:         envToV2(in.Server.Env),
+			Autoscaling: autoscalingToV2(in.Server.Autoscaling),
+		})
+	}
+	if in.Redis != nil {
+		out.Components = append(out.Components, v2.ComponentSpec{
+			Name:      v2.ComponentRedis,
+			Replicas:  in.Redis.Replicas,
+			Image:     in.Redis.Image,
+			Resources: resourcesToV2(in.Redis.Resources),
+			Storage:   in.Redis.Storage,
+			Config:    in.Redis.Config,
+			Sentinel:  (*v2.SentinelSpec)(in.Redis.Sentinel),
+			Backup:    redisBackupToV2(in.Redis.Backup),
+		})
+	}
+	if in.Registry != nil {
+		out.Components = append(out.Components, v2.ComponentSpec{
+			Name:       v2.ComponentRegistry,
+			Replicas:   in.Registry.Replicas,
+			Image:      in.Registry.Image,
+			Resources:  resourcesToV2(in.Registry.Resources),
+			BaseDomain: in.Registry.BaseDomain,
+		})
+	}
+
+	return out
+}
+
+// tenantSpecFromV2 converts a v2 spec to v1. It fails on components v1 has no
+// room for: a component listed twice, or a setting on the wrong kind of
+// component. The v2 schema rejects both, so they only occur in objects that
+// bypassed validation.
+func tenantSpecFromV2(in *v2.TenantSpec) (TenantSpec, error) {
+	out := TenantSpec{
+		DisplayName:    in.DisplayName,
+		Description:    in.Description,
+		Plan:           in.Plan,
+		Namespace:      (*NamespaceSpec)(in.Namespace),
+		Resources:      resourcesFromV2(in.Resources),
+		NetworkPolicy:  networkPolicyFromV2(in.NetworkPolicy),
+		Exposure:       exposureFromV2(in.Exposure),
+		Auth:           authFromV2(in.Auth),
+		Authorization:  authorizationFromV2(in.Authorization),
+		Suspended:      in.Suspended,
+		IdleTimeout:    in.IdleTimeout,
+		DeletionPolicy: DeletionPolicy(in.DeletionPolicy),
+	}
+
+	for i := range in.Components {
+		component := &in.Components[i]
+		switch component.Name {
+		case v2.ComponentServer:
+			if out.Server != nil {
+				return out, fmt.Errorf("component %s is listed more than once", component.Name)
+			}
+			if component.Storage != "" || component.Config != nil || component.Sentinel != nil ||
+				component.Backup != nil || component.BaseDomain != "" {
+				return out, fmt.Errorf("component %s has settings of another component", component.Name)
+			}
+			out.Server = &ServerSpec{
+				Replicas:    component.Replicas,
+				Image:       component.Image,
+				Resources:   resourcesFromV2(component.Resources),
+				Env:         envFromV2(component.Env),
+				Autoscaling: autoscalingFromV2(component.Autoscaling),
+			}
+		case v2.ComponentRedis:
+			if out.Redis != nil {
+				return out, fmt.Errorf("component %s is listed more than once", component.Name)
+			}
+			if component.Env != nil || component.Autoscaling != nil || component.BaseDomain != "" {
+				return out, fmt.Errorf("component %s has settings of another component", component.Name)
+			}
+			out.Redis = &RedisSpec{
+				Replicas:  component.Replicas,
+				Image:     component.Image,
+				Resources: resourcesFromV2(component.Resources),
+				Storage:   component.Storage,
+				Config:    component.Config,
+				Sentinel:  (*SentinelSpec)(component.Sentinel),
+				Backup:    redisBackupFromV2(component.Backup),
+			}
+		case v2.ComponentRegistry:
+			if out.Registry != nil {
+				return out, fmt.Errorf("component %s is listed more than once", component.Name)
+			}
+			if component.Env != nil || component.Autoscaling != nil || component.Storage != "" ||
+				component.Config != nil || component.Sentinel != nil || component.Backup != nil {
+				return out, fmt.Errorf("component %s has settings of another component", component.Name)
+			}
+			out.Registry = &RegistrySpec{
+				Replicas:   component.Replicas,
+				Image:      component.Image,
+				Resources:  resourcesFromV2(component.Resources),
+				BaseDomain: component.BaseDomain,
+			}
+		default:
+			return out, fmt.Errorf("unknown component %q", component.Name)
+		}
+	}
+
+	return out, nil
+}
+
+func resourcesToV2(in *ResourceRequirements) *v2.ResourceRequirements {
+	if in == nil {
+		return nil
+	}
+	return &v2.ResourceRequirements{
+		CPU:     (*v2.ResourceLimit)(in.CPU),
+		Memory:  (*v2.ResourceLimit)(in.Memory),
+		Storage: (*v2.ResourceLimit)(in.Storage),
+	}
+}
+
+func resourcesFromV2(in *v2.ResourceRequirements) *ResourceRequirements {
+	if in == nil {
+		return nil
+	}
+	return &ResourceRequirements{
+		CPU:     (*ResourceLimit)(in.CPU),
+		Memory:  (*ResourceLimit)(in.Memory),
+		Storage: (*ResourceLimit)(in.Storage),
+	}
+}
+
+func envToV2(in []EnvVar) []v2.EnvVar {
+	if in == nil {
+		return nil
+	}
+	out := make([]v2.EnvVar, len(in))
+	for i, env := range in {
+		out[i] = v2.EnvVar{Name: env.Name, Value: env.Value}
+		if env.ValueFrom != nil {
+			out[i].ValueFrom = &v2.EnvVarSource{
+				ConfigMapKeyRef: (*v2.ConfigMapKeySelector)(env.ValueFrom.ConfigMapKeyRef),
+				SecretKeyRef:    (*v2.SecretKeySelector)(env.ValueFrom.SecretKeyRef),
+			}
+		}
+	}
+	return out
+}
+
+func envFromV2(in []v2.EnvVar) []EnvVar {
+	if in == nil {
+		return nil
+	}
+	out := make([]EnvVar, len(in))
+	for i, env := range in {
+		out[i] = EnvVar{Name: env.Name, Value: env.Value}
+		if env.ValueFrom != nil {
+			out[i].ValueFrom = &EnvVarSource{
+				ConfigMapKeyRef: (*ConfigMapKeySelector)(env.ValueFrom.ConfigMapKeyRef),
+				SecretKeyRef:    (*SecretKeySelector)(env.ValueFrom.SecretKeyRef),
+			}
+		}
+	}
+	return out
+}
+
+func autoscalingToV2(in *AutoscalingSpec) *v2.AutoscalingSpec {
+	if in == nil {
+		return nil
+	}
+	out := &v2.AutoscalingSpec{
+		MinReplicas:             in.MinReplicas,
+		MaxReplicas:             in.MaxReplicas,
+		TargetCPUUtilization:    in.TargetCPUUtilization,
+		TargetMemoryUtilization: in.TargetMemoryUtilization,
+	}
+	if in.Metrics != nil {
+		out.Metrics = make([]v2.AutoscalingMetric, len(in.Metrics))
+		for i, metric := range in.Metrics {
+			out.Metrics[i] = v2.AutoscalingMetric(metric)
+		}
+	}
+	return out
+}
+
+func autoscalingFromV2(in *v2.AutoscalingSpec) *AutoscalingSpec {
+	if in == nil {
+		return nil
+	}
+	out := &AutoscalingSpec{
+		MinReplicas:             in.MinReplicas,
+		MaxReplicas:             in.MaxReplicas,
+		TargetCPUUtilization:    in.TargetCPUUtilization,
+		TargetMemoryUtilization: in.TargetMemoryUtilization,
+	}
+	if in.Metrics != nil {
+		out.Metrics = make([]AutoscalingMetric, len(in.Metrics))
+		for i, metric := range in.Metrics {
+			out.Metrics[i] = AutoscalingMetric(metric)
+		}
+	}
+	return out
+}
+
+func redisBackupToV2(in *RedisBackupSpec) *v2.RedisBackupSpec {
+	if in == nil {
+		return nil
+	}
+	out := &v2.RedisBackupSpec{
+		Schedule:  in.Schedule,
+		Retention: in.Retention,
+		Destination: v2.BackupDestination{
+			PVC: (*v2.PVCBackupDestination)(in.Destination.PVC),
+		},
+		Image: in.Image,
+	}
+	if s3 := in.Destination.S3; s3 != nil {
+		out.Destination.S3 = &v2.S3BackupDestination{
+			Endpoint:             s3.Endpoint,
+			Bucket:               s3.Bucket,
+			Prefix:               s3.Prefix,
+			CredentialsSecretRef: v2.SecretReference(s3.CredentialsSecretRef),
+		}
+	}
+	return out
+}
+
+func redisBackupFromV2(in *v2.RedisBackupSpec) *RedisBackupSpec {
+	if in == nil {
+		return nil
+	}
+	out := &RedisBackupSpec{
+		Schedule:  in.Schedule,
+		Retention: in.Retention,
+		Destination: BackupDestination{
+			PVC: (*PVCBackupDestination)(in.Destination.PVC),
+		},
+		Image: in.Image,
+	}
+	if s3 := in.Destination.S3; s3 != nil {
+		out.Destination.S3 = &S3BackupDestination{
+			Endpoint:             s3.Endpoint,
+			Bucket:               s3.Bucket,
+			Prefix:               s3.Prefix,
+			CredentialsSecretRef: SecretReference(s3.CredentialsSecretRef),
+		}
+	}
+	return out
+}
+
+func networkPolicyToV2(in *NetworkPolicySpec) *v2.NetworkPolicySpec {
+	if in == nil {
+		return nil
+	}
+	return &v2.NetworkPolicySpec{
+		Enabled:           in.Enabled,
+		AllowedNamespaces: in.AllowedNamespaces,
+		IngressRules:      networkPolicyRulesToV2(in.IngressRules),
+		EgressRules:       networkPolicyRulesToV2(in.EgressRules),
+	}
+}
+
+func networkPolicyFromV2(in *v2.NetworkPolicySpec) *NetworkPolicySpec {
+	if in == nil {
+		return nil
+	}
+	return &NetworkPolicySpec{
+		Enabled:           in.Enabled,
+		AllowedNamespaces: in.AllowedNamespaces,
+		IngressRules:      networkPolicyRulesFromV2(in.IngressRules),
+		EgressRules:       networkPolicyRulesFromV2(in.EgressRules),
+	}
+}
+
+func networkPolicyRulesToV2(in []NetworkPolicyRule) []v2.NetworkPolicyRule {
+	if in == nil {
+		return nil
+	}
+	out := make([]v2.NetworkPolicyRule, len(in))
+	for i, rule := range in {
+		out[i] = v2.NetworkPolicyRule{Description: rule.Description, From: rule.From, To: rule.To}
+		if rule.Ports != nil {
+			out[i].Ports = make([]v2.NetworkPolicyPort, len(rule.Ports))
+			for j, port := range rule.Ports {
+				out[i].Ports[j] = v2.NetworkPolicyPort(port)
+			}
+		}
+	}
+	return out
+}
+
+func networkPolicyRulesFromV2(in []v2.NetworkPolicyRule) []NetworkPolicyRule {
+	if in == nil {
+		return nil
+	}
+	out := make([]NetworkPolicyRule, len(in))
+	for i, rule := range in {
+		out[i] = NetworkPolicyRule{Description: rule.Description, From: rule.From, To: rule.To}
+		if rule.Ports != nil {
+			out[i].Ports = make([]NetworkPolicyPort, len(rule.Ports))
+			for j, port := range rule.Ports {
+				out[i].Ports[j] = NetworkPolicyPort(port)
+			}
+		}
+	}
+	return out
+}
+
+func exposureToV2(in *ExposureSpec) *v2.ExposureSpec {
+	if in == nil {
+		return nil
+	}
+	out := &v2.ExposureSpec{
+		Type:             v2.ExposureType(in.Type),
+		Hostname:         in.Hostname,
+		IngressClassName: in.IngressClassName,
+		Annotations:      in.Annotations,
+		GatewayRef:       (*v2.GatewayReference)(in.GatewayRef),
+	}
+	if in.TLS != nil {
+		out.TLS = &v2.ExposureTLS{
+			IssuerRef: (*v2.IssuerReference)(in.TLS.IssuerRef),
+			SecretRef: (*v2.SecretReference)(in.TLS.SecretRef),
+		}
+	}
+	return out
+}
+
+func exposureFromV2(in *v2.ExposureSpec) *ExposureSpec {
+	if in == nil {
+		return nil
+	}
+	out := &ExposureSpec{
+		Type:             ExposureType(in.Type),
+		Hostname:         in.Hostname,
+		IngressClassName: in.IngressClassName,
+		Annotations:      in.Annotations,
+		GatewayRef:       (*GatewayReference)(in.GatewayRef),
+	}
+	if in.TLS != nil {
+		out.TLS = &ExposureTLS{
+			IssuerRef: (*IssuerReference)(in.TLS.IssuerRef),
+			SecretRef: (*SecretReference)(in.TLS.SecretRef),
+		}
+	}
+	return out
+}
+
+func authToV2(in *AuthSpec) *v2.AuthSpec {
+	if in == nil {
+		return nil
+	}
+	return &v2.AuthSpec{
+		AdminUserID:        in.AdminUserID,
+		AdminEmail:         in.AdminEmail,
+		BootstrapSecretRef: (*v2.SecretReference)(in.BootstrapSecretRef),
+	}
+}
+
+func authFromV2(in *v2.AuthSpec) *AuthSpec {
+	if in == nil {
+		return nil
+	}
+	return &AuthSpec{
+		AdminUserID:        in.AdminUserID,
+		AdminEmail:         in.AdminEmail,
+		BootstrapSecretRef: (*SecretReference)(in.BootstrapSecretRef),
+	}
+}
+
+func authorizationToV2(in *AuthorizationSpec) *v2.AuthorizationSpec {
+	if in == nil {
+		return nil
+	}
+	return &v2.AuthorizationSpec{
+		StoreID:  in.StoreID,
+		ModelRef: v2.ConfigMapKeyReference(in.ModelRef),
+	}
+}
+
+func authorizationFromV2(in *v2.AuthorizationSpec) *AuthorizationSpec {
+	if in == nil {
+		return nil
+	}
+	return &AuthorizationSpec{
+		StoreID:  in.StoreID,
+		ModelRef: ConfigMapKeyReference(in.ModelRef),
+	}
+}
+
+func tenantStatusToV2(in *TenantStatus) v2.TenantStatus {
+	out := v2.TenantStatus{
+		Conditions:             in.Conditions,
+		Phase:                  v2.TenantPhase(in.Phase),
+		Namespace:              in.Namespace,
+		NamespaceAdopted:       in.NamespaceAdopted,
+		ServerStatus:           componentStatusToV2(in.ServerStatus),
+		RegistryStatus:         componentStatusToV2(in.RegistryStatus),
+		AdminCredentialsSecret: in.AdminCredentialsSecret,
+		StoppedServerReplicas:  in.StoppedServerReplicas,
+		Authorization:          (*v2.AuthorizationStatus)(in.Authorization),
+		URL:                    in.URL,
+		Quota:                  in.Quota,
+		RedisStatus: v2.RedisStatus{
+			ComponentStatus:  componentStatusToV2(in.RedisStatus.ComponentStatus),
+			Primary:          in.RedisStatus.Primary,
+			LastFailoverTime: in.RedisStatus.LastFailoverTime,
+			LastBackup:       (*v2.BackupStatus)(in.RedisStatus.LastBackup),
+		},
+	}
+	if in.Suspension != nil {
+		out.Suspension = &v2.SuspensionStatus{
+			Reason: v2.SuspensionReason(in.Suspension.Reason),
+			Since:  in.Suspension.Since,
+		}
+	}
+	return out
+}
+
+func tenantStatusFromV2(in *v2.TenantStatus) TenantStatus {
+	out := TenantStatus{
+		Conditions:             in.Conditions,
+		Phase:                  TenantPhase(in.Phase),
+		Namespace:              in.Namespace,
+		NamespaceAdopted:       in.NamespaceAdopted,
+		ServerStatus:           componentStatusFromV2(in.ServerStatus),
+		RegistryStatus:         componentStatusFromV2(in.RegistryStatus),
+		AdminCredentialsSecret: in.AdminCredentialsSecret,
+		StoppedServerReplicas:  in.StoppedServerReplicas,
+		Authorization:          (*AuthorizationStatus)(in.Authorization),
+		URL:                    in.URL,
+		Quota:                  in.Quota,
+		RedisStatus: RedisStatus{
+			ComponentStatus:  componentStatusFromV2(in.RedisStatus.ComponentStatus),
+			Primary:          in.RedisStatus.Primary,
+			LastFailoverTime: in.RedisStatus.LastFailoverTime,
+			LastBackup:       (*BackupStatus)(in.RedisStatus.LastBackup),
+		},
+	}
+	if in.Suspension != nil {
+		out.Suspension = &SuspensionStatus{
+			Reason: SuspensionReason(in.Suspension.Reason),
+			Since:  in.Suspension.Since,
+		}
+	}
+	return out
+}
+
+func componentStatusToV2(in ComponentStatus) v2.ComponentStatus {
+	return v2.ComponentStatus{
+		Phase:         v2.ComponentPhase(in.Phase),
+		Message:       in.Message,
+		ReadyReplicas: in.ReadyReplicas,
+		TotalReplicas: in.TotalReplicas,
+	}
+}
+
+func componentStatusFromV2(in v2.ComponentStatus) ComponentStatus {
+	return ComponentStatus{
+		Phase:         ComponentPhase(in.Phase),
+		Message:       in.Message,
+		ReadyReplicas: in.ReadyReplicas,
+		TotalReplicas: in.TotalReplicas,
+	}
+}
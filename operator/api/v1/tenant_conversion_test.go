@@ -0,0 +1,127 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	fuzz "github.com/google/gofuzz"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v2 "github.com/neurallog/operator/api/v2"
+)
+
+// fuzzRounds is how many random tenants each round-trip test converts
+const fuzzRounds = 500
+
+// hubTenantSpecFuzzer keeps fuzzed v2 specs within the v2 schema. v1 has one
+// field per component, so it can only hold components that are listed once and
+// carry their own settings; converting back lists them in the order v1 does.
+func hubTenantSpecFuzzer(spec *v2.TenantSpec, c fuzz.Continue) {
+	c.FuzzNoCustom(spec)
+
+	spec.Components = nil
+	for _, name := range []v2.ComponentName{v2.ComponentServer, v2.ComponentRedis, v2.ComponentRegistry} {
+		if c.RandBool() {
+			continue
+		}
+		component := v2.ComponentSpec{}
+		c.Fuzz(&component)
+		component.Name = name
+		if name != v2.ComponentServer {
+			component.Env, component.Autoscaling = nil, nil
+		}
+		if name != v2.ComponentRedis {
+			component.Storage, component.Config, component.Sentinel, component.Backup = "", nil, nil, nil
+		}
+		if name != v2.ComponentRegistry {
+			component.BaseDomain = ""
+		}
+		spec.Components = append(spec.Components, component)
+	}
+}
+
+var _ = Describe("Tenant conversion", func() {
+	var fuzzer *fuzz.Fuzzer
+
+	BeforeEach(func() {
+		fuzzer = fuzz.NewWithSeed(GinkgoRandomSeed()).NilChance(0.3).Funcs(hubTenantSpecFuzzer)
+	})
+
+	It("Should round-trip v1 tenants through the hub without loss", func() {
+		for i := 0; i < fuzzRounds; i++ {
+			original := &Tenant{}
+			fuzzer.Fuzz(original)
+			original.TypeMeta = metav1.TypeMeta{}
+
+			hub := &v2.Tenant{}
+			Expect(original.DeepCopy().ConvertTo(hub)).To(Succeed())
+			converted := &Tenant{}
+			Expect(converted.ConvertFrom(hub)).To(Succeed())
+
+			Expect(equality.Semantic.DeepEqual(original, converted)).To(BeTrue(),
+				"round trip changed the tenant:\n%#v\n%#v", original, converted)
+		}
+	})
+
+	It("Should round-trip hub tenants through v1 without loss", func() {
+		for i := 0; i < fuzzRounds; i++ {
+			original := &v2.Tenant{}
+			fuzzer.Fuzz(original)
+			original.TypeMeta = metav1.TypeMeta{}
+
+			spoke := &Tenant{}
+			Expect(spoke.ConvertFrom(original.DeepCopy())).To(Succeed())
+			converted := &v2.Tenant{}
+			Expect(spoke.ConvertTo(converted)).To(Succeed())
+
+			Expect(equality.Semantic.DeepEqual(original, converted)).To(BeTrue(),
+				"round trip changed the tenant:\n%#v\n%#v", original, converted)
+		}
+	})
+
+	It("Should map the v1 component sections onto the components list", func() {
+		replicas := int32(2)
+		tenant := &Tenant{Spec: TenantSpec{
+			Server:   &ServerSpec{Image: "neurallog/server:v2"},
+			Registry: &RegistrySpec{Replicas: &replicas, BaseDomain: "logs.example.com"},
+		}}
+
+		hub := &v2.Tenant{}
+		Expect(tenant.ConvertTo(hub)).To(Succeed())
+
+		Expect(hub.Spec.Components).To(HaveLen(2))
+		Expect(hub.Spec.Components[0].Name).To(Equal(v2.ComponentServer))
+		Expect(hub.Spec.Components[0].Image).To(Equal("neurallog/server:v2"))
+		Expect(hub.Spec.Components[1].Name).To(Equal(v2.ComponentRegistry))
+		Expect(*hub.Spec.Components[1].Replicas).To(Equal(int32(2)))
+		Expect(hub.Spec.Components[1].BaseDomain).To(Equal("logs.example.com"))
+	})
+
+	It("Should refuse hub tenants v1 cannot represent", func() {
+		duplicate := &v2.Tenant{Spec: v2.TenantSpec{Components: []v2.ComponentSpec{
+			{Name: v2.ComponentRedis}, {Name: v2.ComponentRedis},
+		}}}
+		Expect((&Tenant{}).ConvertFrom(duplicate)).NotTo(Succeed())
+
+		misplaced := &v2.Tenant{Spec: v2.TenantSpec{Components: []v2.ComponentSpec{
+			{Name: v2.ComponentRegistry, Storage: "10Gi"},
+		}}}
+		Expect((&Tenant{}).ConvertFrom(misplaced)).NotTo(Succeed())
+	})
+})
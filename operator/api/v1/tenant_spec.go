@@ -16,6 +16,10 @@ limitations under the License.
 
 package v1
 
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
 // TenantSpec defines the desired state of a NeuralLog Tenant
 type TenantSpec struct {
 	// DisplayName is a user-friendly name for the tenant
@@ -26,40 +30,133 @@ type TenantSpec struct {
 	// +optional
 	Description string `json:"description,omitempty"`
 
+	// Plan is the name of the TenantPlan the tenant is on. Settings left unset
+	// on the tenant are taken from the plan.
+	// +optional
+	Plan string `json:"plan,omitempty"`
+
+	// Namespace defines the name and metadata of the tenant namespace
+	// +optional
+	Namespace *NamespaceSpec `json:"namespace,omitempty"`
+
 	// Resources defines the resource limits and requests for the tenant
 	// +optional
-	Resources ResourceRequirements `json:"resources,omitempty"`
+	Resources *ResourceRequirements `json:"resources,omitempty"`
 
 	// Server defines the configuration for the NeuralLog server
 	// +optional
-	Server ServerSpec `json:"server,omitempty"`
+	Server *ServerSpec `json:"server,omitempty"`
 
 	// Redis defines the configuration for the Redis instance
 	// +optional
-	Redis RedisSpec `json:"redis,omitempty"`
+	Redis *RedisSpec `json:"redis,omitempty"`
 
 	// Registry defines the configuration for the Endpoint Registry service
 	// +optional
-	Registry RegistrySpec `json:"registry,omitempty"`
+	Registry *RegistrySpec `json:"registry,omitempty"`
 
 	// NetworkPolicy defines the network policy configuration for the tenant
 	// +optional
-	NetworkPolicy NetworkPolicySpec `json:"networkPolicy,omitempty"`
+	NetworkPolicy *NetworkPolicySpec `json:"networkPolicy,omitempty"`
+
+	// Exposure publishes the tenant's server outside the cluster at
+	// <tenant>.<registry.baseDomain>
+	// +optional
+	Exposure *ExposureSpec `json:"exposure,omitempty"`
+
+	// Auth defines how the tenant is registered with the Auth service
+	// +optional
+	Auth *AuthSpec `json:"auth,omitempty"`
+
+	// Authorization defines the tenant's OpenFGA store and authorization model
+	// +optional
+	Authorization *AuthorizationSpec `json:"authorization,omitempty"`
+
+	// Suspended scales the tenant's workloads to zero while keeping its data
+	// and Auth registration
+	// +optional
+	Suspended bool `json:"suspended,omitempty"`
+
+	// IdleTimeout suspends the tenant when no activity has been recorded in the
+	// LastActivityAnnotation for this long. Recording new activity resumes it.
+	// +optional
+	IdleTimeout *metav1.Duration `json:"idleTimeout,omitempty"`
+
+	// DeletionPolicy decides what happens to the tenant's data when the tenant
+	// is deleted
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+}
+
+// DeletionPolicy decides what happens to a tenant's data when it is deleted
+// +kubebuilder:validation:Enum=Delete;Retain;Snapshot
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete deletes the tenant namespace with all its volumes
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+
+	// DeletionPolicyRetain keeps the persistent volumes of the tenant namespace
+	// after the namespace is deleted
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+
+	// DeletionPolicySnapshot takes a final Redis backup to the tenant's backup
+	// destination before the namespace is deleted
+	DeletionPolicySnapshot DeletionPolicy = "Snapshot"
+)
+
+// LastActivityAnnotation is set on a Tenant to the RFC 3339 time the tenant was
+// last used, by the server or a gateway in front of it
+const LastActivityAnnotation = "neurallog.io/last-activity"
+
+// SkipAuthCleanupAnnotation is set to "true" on a Tenant to delete it without
+// removing it from the Auth service, for example when the Auth service is gone
+const SkipAuthCleanupAnnotation = "neurallog.io/skip-auth-cleanup"
+
+// NamespaceSpec defines the name and metadata of the tenant namespace. The name
+// can't be changed once the namespace is created.
+type NamespaceSpec struct {
+	// Name is the name of the namespace. It can't be combined with Prefix or Suffix.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Prefix is prepended to the tenant name to form the namespace name.
+	// Defaults to "tenant-".
+	// +optional
+	Prefix *string `json:"prefix,omitempty"`
+
+	// Suffix is appended to the tenant name to form the namespace name
+	// +optional
+	Suffix string `json:"suffix,omitempty"`
+
+	// Adopt lets the operator take over a namespace that already exists and
+	// isn't managed by it. An adopted namespace isn't deleted with the tenant,
+	// it is released by removing the labels and annotations the operator set.
+	// +optional
+	Adopt bool `json:"adopt,omitempty"`
+
+	// Labels are added to the namespace, e.g. pod-security.kubernetes.io/enforce
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations are added to the namespace
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
 }
 
 // ResourceRequirements defines the resource limits and requests for the tenant
 type ResourceRequirements struct {
 	// CPU defines the CPU limits and requests
 	// +optional
-	CPU ResourceLimit `json:"cpu,omitempty"`
+	CPU *ResourceLimit `json:"cpu,omitempty"`
 
 	// Memory defines the memory limits and requests
 	// +optional
-	Memory ResourceLimit `json:"memory,omitempty"`
+	Memory *ResourceLimit `json:"memory,omitempty"`
 
 	// Storage defines the storage limits and requests
 	// +optional
-	Storage ResourceLimit `json:"storage,omitempty"`
+	Storage *ResourceLimit `json:"storage,omitempty"`
 }
 
 // ResourceLimit defines a resource limit and request
@@ -85,16 +182,58 @@ type ServerSpec struct {
 
 	// Resources defines the resource limits and requests for the server
 	// +optional
-	Resources ResourceRequirements `json:"resources,omitempty"`
+	Resources *ResourceRequirements `json:"resources,omitempty"`
 
 	// Env defines additional environment variables for the server
 	// +optional
 	Env []EnvVar `json:"env,omitempty"`
+
+	// Autoscaling scales the server with a HorizontalPodAutoscaler. Replicas
+	// is ignored while it is set.
+	// +optional
+	Autoscaling *AutoscalingSpec `json:"autoscaling,omitempty"`
+}
+
+// AutoscalingSpec defines horizontal autoscaling of the server
+type AutoscalingSpec struct {
+	// MinReplicas is the lower limit for the number of server instances
+	// +optional
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+
+	// MaxReplicas is the upper limit for the number of server instances
+	MaxReplicas int32 `json:"maxReplicas"`
+
+	// TargetCPUUtilization is the target average CPU utilization of the
+	// server, as a percentage of its CPU request. Defaults to 80 if no other
+	// target is set.
+	// +optional
+	TargetCPUUtilization *int32 `json:"targetCPUUtilization,omitempty"`
+
+	// TargetMemoryUtilization is the target average memory utilization of the
+	// server, as a percentage of its memory request
+	// +optional
+	TargetMemoryUtilization *int32 `json:"targetMemoryUtilization,omitempty"`
+
+	// Metrics are custom per-pod metrics to scale on, such as the log ingest rate
+	// +optional
+	Metrics []AutoscalingMetric `json:"metrics,omitempty"`
+}
+
+// AutoscalingMetric defines a target for a custom metric of the server pods,
+// served by a custom metrics API adapter
+type AutoscalingMetric struct {
+	// Name is the name of the metric, e.g. neurallog_log_ingest_rate
+	Name string `json:"name"`
+
+	// TargetAverageValue is the target value of the metric averaged over the
+	// server pods, e.g. "500"
+	TargetAverageValue string `json:"targetAverageValue"`
 }
 
 // RedisSpec defines the configuration for the Redis instance
 type RedisSpec struct {
-	// Replicas is the number of Redis instances (for Redis Sentinel)
+	// Replicas is the number of Redis instances. With Sentinel enabled, one
+	// instance is the primary and the others replicate from it.
 	// +optional
 	Replicas *int32 `json:"replicas,omitempty"`
 
@@ -104,7 +243,7 @@ type RedisSpec struct {
 
 	// Resources defines the resource limits and requests for Redis
 	// +optional
-	Resources ResourceRequirements `json:"resources,omitempty"`
+	Resources *ResourceRequirements `json:"resources,omitempty"`
 
 	// Storage defines the storage configuration for Redis
 	// +optional
@@ -113,6 +252,88 @@ type RedisSpec struct {
 	// Config defines additional Redis configuration
 	// +optional
 	Config map[string]string `json:"config,omitempty"`
+
+	// Sentinel enables high availability with Redis Sentinel
+	// +optional
+	Sentinel *SentinelSpec `json:"sentinel,omitempty"`
+
+	// Backup defines scheduled backups of the Redis data
+	// +optional
+	Backup *RedisBackupSpec `json:"backup,omitempty"`
+}
+
+// SentinelSpec defines the Redis Sentinel configuration for high availability
+type SentinelSpec struct {
+	// Replicas is the number of Sentinel instances
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Quorum is the number of Sentinels that must agree the primary is down
+	// before a failover starts. Defaults to a majority of the Sentinels.
+	// +optional
+	Quorum *int32 `json:"quorum,omitempty"`
+}
+
+// RedisBackupSpec defines scheduled backups of the Redis data
+type RedisBackupSpec struct {
+	// Schedule is the cron schedule for backups, e.g. "0 2 * * *"
+	Schedule string `json:"schedule"`
+
+	// Retention is the number of backups to keep
+	// +optional
+	Retention *int32 `json:"retention,omitempty"`
+
+	// Destination is where backups are stored
+	Destination BackupDestination `json:"destination"`
+
+	// Image is the Docker image for the backup job. Defaults to the Redis image
+	// for PVC destinations and to the MinIO client for S3 destinations.
+	// +optional
+	Image string `json:"image,omitempty"`
+}
+
+// BackupDestination defines where backups are stored. Exactly one of PVC or S3 must be set.
+type BackupDestination struct {
+	// PVC stores backups in a PersistentVolumeClaim in the tenant namespace
+	// +optional
+	PVC *PVCBackupDestination `json:"pvc,omitempty"`
+
+	// S3 stores backups in an S3-compatible bucket
+	// +optional
+	S3 *S3BackupDestination `json:"s3,omitempty"`
+}
+
+// PVCBackupDestination stores backups in a PersistentVolumeClaim
+type PVCBackupDestination struct {
+	// ClaimName is the name of an existing PersistentVolumeClaim in the tenant
+	// namespace. If empty, the operator creates one.
+	// +optional
+	ClaimName string `json:"claimName,omitempty"`
+
+	// Size is the size of the PersistentVolumeClaim created by the operator
+	// +optional
+	Size string `json:"size,omitempty"`
+
+	// StorageClassName is the storage class of the PersistentVolumeClaim created by the operator
+	// +optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+}
+
+// S3BackupDestination stores backups in an S3-compatible bucket
+type S3BackupDestination struct {
+	// Endpoint is the URL of the S3-compatible service, e.g. https://s3.amazonaws.com
+	Endpoint string `json:"endpoint"`
+
+	// Bucket is the bucket backups are stored in
+	Bucket string `json:"bucket"`
+
+	// Prefix is the key prefix for backups. Defaults to the tenant name.
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// CredentialsSecretRef references a Secret with "accessKeyId" and
+	// "secretAccessKey" keys used to access the bucket
+	CredentialsSecretRef SecretReference `json:"credentialsSecretRef"`
 }
 
 // RegistrySpec defines the configuration for the Endpoint Registry service
@@ -127,13 +348,141 @@ type RegistrySpec struct {
 
 	// Resources defines the resource limits and requests for the Registry
 	// +optional
-	Resources ResourceRequirements `json:"resources,omitempty"`
+	Resources *ResourceRequirements `json:"resources,omitempty"`
 
 	// BaseDomain is the base domain for endpoint URLs
 	// +optional
 	BaseDomain string `json:"baseDomain,omitempty"`
 }
 
+// AuthSpec defines how the tenant is registered with the Auth service
+type AuthSpec struct {
+	// AdminUserID is the ID of the tenant's initial admin user
+	// +optional
+	AdminUserID string `json:"adminUserId,omitempty"`
+
+	// AdminEmail is the email address of the tenant's initial admin user
+	// +optional
+	AdminEmail string `json:"adminEmail,omitempty"`
+
+	// BootstrapSecretRef references a Secret whose "password" key is used as
+	// the initial admin password. If unset, the operator generates one.
+	// +optional
+	BootstrapSecretRef *SecretReference `json:"bootstrapSecretRef,omitempty"`
+}
+
+// SecretReference references a Secret in a specific namespace
+type SecretReference struct {
+	// Name is the name of the Secret
+	Name string `json:"name"`
+
+	// Namespace is the namespace of the Secret
+	Namespace string `json:"namespace"`
+}
+
+// AuthorizationSpec defines the OpenFGA store and authorization model of a tenant
+type AuthorizationSpec struct {
+	// StoreID selects an existing OpenFGA store. If unset, the operator creates
+	// a store for the tenant.
+	// +optional
+	StoreID string `json:"storeId,omitempty"`
+
+	// ModelRef references the ConfigMap key holding the authorization model in
+	// OpenFGA's JSON format. A new model version is written whenever it changes.
+	ModelRef ConfigMapKeyReference `json:"modelRef"`
+}
+
+// ConfigMapKeyReference references a key of a ConfigMap in a specific namespace
+type ConfigMapKeyReference struct {
+	// Name is the name of the ConfigMap
+	Name string `json:"name"`
+
+	// Namespace is the namespace of the ConfigMap
+	Namespace string `json:"namespace"`
+
+	// Key is the key in the ConfigMap. Defaults to "model.json".
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// ExposureType is how the tenant's server is exposed
+// +kubebuilder:validation:Enum=Ingress;HTTPRoute
+type ExposureType string
+
+const (
+	// ExposureIngress exposes the server with an Ingress
+	ExposureIngress ExposureType = "Ingress"
+
+	// ExposureHTTPRoute exposes the server with a Gateway API HTTPRoute
+	ExposureHTTPRoute ExposureType = "HTTPRoute"
+)
+
+// ExposureSpec defines how the tenant's server is exposed outside the cluster
+type ExposureSpec struct {
+	// Type is the kind of route created for the server. Defaults to Ingress.
+	// +optional
+	Type ExposureType `json:"type,omitempty"`
+
+	// Hostname overrides the default hostname <tenant>.<registry.baseDomain>
+	// +optional
+	Hostname string `json:"hostname,omitempty"`
+
+	// IngressClassName is the class of the Ingress
+	// +optional
+	IngressClassName *string `json:"ingressClassName,omitempty"`
+
+	// Annotations are added to the Ingress or HTTPRoute
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// GatewayRef is the Gateway the HTTPRoute attaches to. Required for the
+	// HTTPRoute type.
+	// +optional
+	GatewayRef *GatewayReference `json:"gatewayRef,omitempty"`
+
+	// TLS serves the Ingress over HTTPS. With an HTTPRoute, TLS is terminated
+	// by the Gateway listener.
+	// +optional
+	TLS *ExposureTLS `json:"tls,omitempty"`
+}
+
+// GatewayReference references a listener of a Gateway API Gateway
+type GatewayReference struct {
+	// Name is the name of the Gateway
+	Name string `json:"name"`
+
+	// Namespace is the namespace of the Gateway
+	Namespace string `json:"namespace"`
+
+	// SectionName is the name of the Gateway listener to attach to
+	// +optional
+	SectionName string `json:"sectionName,omitempty"`
+}
+
+// ExposureTLS defines where the certificate of an exposed tenant comes from.
+// Exactly one of IssuerRef or SecretRef must be set.
+type ExposureTLS struct {
+	// IssuerRef requests a certificate from a cert-manager Issuer or ClusterIssuer
+	// +optional
+	IssuerRef *IssuerReference `json:"issuerRef,omitempty"`
+
+	// SecretRef references a kubernetes.io/tls Secret, which is copied into
+	// the tenant namespace
+	// +optional
+	SecretRef *SecretReference `json:"secretRef,omitempty"`
+}
+
+// IssuerReference references a cert-manager issuer
+type IssuerReference struct {
+	// Name is the name of the issuer
+	Name string `json:"name"`
+
+	// Kind is Issuer, in the tenant namespace, or ClusterIssuer. Defaults to ClusterIssuer.
+	// +kubebuilder:validation:Enum=Issuer;ClusterIssuer
+	// +optional
+	Kind string `json:"kind,omitempty"`
+}
+
 // NetworkPolicySpec defines the network policy configuration for the tenant
 type NetworkPolicySpec struct {
 	// Enabled indicates whether network policies should be created
@@ -17,6 +17,7 @@ limitations under the License.
 package v1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -24,7 +25,11 @@ import (
 type TenantStatus struct {
 	// Conditions represent the latest available observations of the tenant's state
 	// +optional
-	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
 
 	// Phase represents the current phase of the tenant
 	// +optional
@@ -34,19 +39,93 @@ type TenantStatus struct {
 	// +optional
 	Namespace string `json:"namespace,omitempty"`
 
+	// NamespaceAdopted is true when the namespace existed before the tenant and
+	// was adopted. It is released rather than deleted with the tenant.
+	// +optional
+	NamespaceAdopted bool `json:"namespaceAdopted,omitempty"`
+
 	// ServerStatus represents the status of the server deployment
 	// +optional
 	ServerStatus ComponentStatus `json:"serverStatus,omitempty"`
 
 	// RedisStatus represents the status of the Redis deployment
 	// +optional
-	RedisStatus ComponentStatus `json:"redisStatus,omitempty"`
+	RedisStatus RedisStatus `json:"redisStatus,omitempty"`
 
 	// RegistryStatus represents the status of the registry deployment
 	// +optional
 	RegistryStatus ComponentStatus `json:"registryStatus,omitempty"`
+
+	// AdminCredentialsSecret is the name of the Secret in the tenant namespace
+	// holding the initial admin credentials
+	// +optional
+	AdminCredentialsSecret string `json:"adminCredentialsSecret,omitempty"`
+
+	// Suspension describes why the tenant is suspended. It is unset while the
+	// tenant is active.
+	// +optional
+	Suspension *SuspensionStatus `json:"suspension,omitempty"`
+
+	// StoppedServerReplicas is the number of autoscaled server instances when
+	// the server was stopped for a suspension or restore. The server starts
+	// again with as many.
+	// +optional
+	StoppedServerReplicas *int32 `json:"stoppedServerReplicas,omitempty"`
+
+	// Authorization records the tenant's OpenFGA store and authorization model
+	// +optional
+	Authorization *AuthorizationStatus `json:"authorization,omitempty"`
+
+	// URL is the address the tenant's server is exposed at
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// Quota reports the hard limits and usage of the tenant namespace's
+	// ResourceQuota. It is unset when the tenant has no resources set.
+	// +optional
+	Quota *corev1.ResourceQuotaStatus `json:"quota,omitempty"`
 }
 
+// AuthorizationStatus records the OpenFGA store and authorization model of a tenant
+type AuthorizationStatus struct {
+	// StoreID is the ID of the tenant's OpenFGA store
+	// +optional
+	StoreID string `json:"storeId,omitempty"`
+
+	// StoreName is the name of the tenant's OpenFGA store
+	// +optional
+	StoreName string `json:"storeName,omitempty"`
+
+	// ModelID is the ID of the authorization model last written to the store
+	// +optional
+	ModelID string `json:"modelId,omitempty"`
+
+	// ModelHash is the SHA-256 hash of the model last written to the store
+	// +optional
+	ModelHash string `json:"modelHash,omitempty"`
+}
+
+// SuspensionStatus describes a suspended tenant
+type SuspensionStatus struct {
+	// Reason is why the tenant is suspended
+	Reason SuspensionReason `json:"reason"`
+
+	// Since is when the tenant was suspended
+	// +optional
+	Since *metav1.Time `json:"since,omitempty"`
+}
+
+// SuspensionReason is why a tenant is suspended
+type SuspensionReason string
+
+const (
+	// SuspensionRequested means spec.suspended is set
+	SuspensionRequested SuspensionReason = "Requested"
+
+	// SuspensionIdle means no activity was recorded within spec.idleTimeout
+	SuspensionIdle SuspensionReason = "Idle"
+)
+
 // TenantPhase represents the phase of a tenant
 type TenantPhase string
 
@@ -63,10 +142,92 @@ const (
 	// TenantFailed means the tenant creation failed
 	TenantFailed TenantPhase = "Failed"
 
+	// TenantSuspended means the tenant's workloads are scaled to zero
+	TenantSuspended TenantPhase = "Suspended"
+
 	// TenantTerminating means the tenant is being deleted
 	TenantTerminating TenantPhase = "Terminating"
 )
 
+// Condition types reported on a Tenant. Each reconcile step owns one condition;
+// Ready summarizes all of them.
+const (
+	// ConditionPlanApplied indicates whether the tenant's plan is merged into its spec
+	ConditionPlanApplied = "PlanApplied"
+
+	// ConditionNamespaceReady indicates whether the tenant namespace exists
+	ConditionNamespaceReady = "NamespaceReady"
+
+	// ConditionRedisReady indicates whether Redis is provisioned and ready
+	ConditionRedisReady = "RedisReady"
+
+	// ConditionServerReady indicates whether the server is provisioned and ready
+	ConditionServerReady = "ServerReady"
+
+	// ConditionRegistryReady indicates whether the Endpoint Registry is provisioned and ready
+	ConditionRegistryReady = "RegistryReady"
+
+	// ConditionNetworkPoliciesReady indicates whether the network policies are applied
+	ConditionNetworkPoliciesReady = "NetworkPoliciesReady"
+
+	// ConditionExposureReady indicates whether the tenant's server is exposed
+	// outside the cluster
+	ConditionExposureReady = "ExposureReady"
+
+	// ConditionAuthSynced indicates whether the tenant is registered with the Auth service
+	ConditionAuthSynced = "AuthSynced"
+
+	// ConditionAuthorizationReady indicates whether the tenant's OpenFGA store
+	// holds its current authorization model
+	ConditionAuthorizationReady = "AuthorizationReady"
+
+	// ConditionReady indicates whether all tenant components are ready
+	ConditionReady = "Ready"
+
+	// ConditionAuthCleanupFailed indicates that a deleted tenant could not be
+	// removed from the Auth service
+	ConditionAuthCleanupFailed = "AuthCleanupFailed"
+)
+
+// Condition reasons reported on a Tenant
+const (
+	// ReasonReconciled means the step completed and its resources are ready
+	ReasonReconciled = "Reconciled"
+
+	// ReasonProvisioning means the step's resources exist but are not ready yet
+	ReasonProvisioning = "Provisioning"
+
+	// ReasonDisabled means the step is disabled in the tenant spec
+	ReasonDisabled = "Disabled"
+
+	// ReasonReconcileFailed means the step returned an error
+	ReasonReconcileFailed = "ReconcileFailed"
+
+	// ReasonNamespaceNotManaged means the tenant namespace already exists and
+	// the operator may not take it over
+	ReasonNamespaceNotManaged = "NamespaceNotManaged"
+
+	// ReasonAllComponentsReady means every step condition is true
+	ReasonAllComponentsReady = "AllComponentsReady"
+
+	// ReasonComponentsNotReady means at least one step condition is not true
+	ReasonComponentsNotReady = "ComponentsNotReady"
+
+	// ReasonTerminating means the tenant is being deleted
+	ReasonTerminating = "Terminating"
+
+	// ReasonSuspended means the tenant is suspended
+	ReasonSuspended = "Suspended"
+
+	// ReasonAuthCleanupRetrying means removing the tenant from the Auth service
+	// failed and is being retried
+	ReasonAuthCleanupRetrying = "Retrying"
+
+	// ReasonAuthCleanupDeadlineExceeded means removing the tenant from the Auth
+	// service failed until the cleanup timeout passed
+	ReasonAuthCleanupDeadlineExceeded = "DeadlineExceeded"
+)
+
 // ComponentStatus represents the status of a component
 type ComponentStatus struct {
 	// Phase represents the current phase of the component
@@ -86,6 +247,39 @@ type ComponentStatus struct {
 	TotalReplicas int32 `json:"totalReplicas,omitempty"`
 }
 
+// RedisStatus represents the status of Redis
+type RedisStatus struct {
+	ComponentStatus `json:",inline"`
+
+	// Primary is the name of the pod currently serving as the Redis primary.
+	// Only set when Sentinel is enabled.
+	// +optional
+	Primary string `json:"primary,omitempty"`
+
+	// LastFailoverTime is when Sentinel last promoted a new primary
+	// +optional
+	LastFailoverTime *metav1.Time `json:"lastFailoverTime,omitempty"`
+
+	// LastBackup describes the most recent successful backup
+	// +optional
+	LastBackup *BackupStatus `json:"lastBackup,omitempty"`
+}
+
+// BackupStatus describes a completed backup
+type BackupStatus struct {
+	// Name is the file name of the backup at its destination
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Time is when the backup completed
+	// +optional
+	Time *metav1.Time `json:"time,omitempty"`
+
+	// SizeBytes is the size of the backup in bytes
+	// +optional
+	SizeBytes int64 `json:"sizeBytes,omitempty"`
+}
+
 // ComponentPhase represents the phase of a component
 type ComponentPhase string
 
@@ -0,0 +1,743 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var tenantlog = logf.Log.WithName("tenant-resource")
+
+// Default images and sizes for tenant components
+const (
+	DefaultServerImage   = "neurallog/server:latest"
+	DefaultRedisImage    = "redis:7-alpine"
+	DefaultRegistryImage = "neurallog/registry:latest"
+	DefaultRedisStorage  = "1Gi"
+	DefaultReplicas      = int32(1)
+
+	// DefaultRedisHAReplicas is the default number of Redis instances with
+	// Sentinel enabled: one primary and two replicas
+	DefaultRedisHAReplicas = int32(3)
+
+	// DefaultSentinelReplicas is the default number of Sentinel instances
+	DefaultSentinelReplicas = int32(3)
+
+	// DefaultRedisBackupRetention is the default number of Redis backups to keep
+	DefaultRedisBackupRetention = int32(7)
+
+	// DefaultRedisBackupSize is the default size of the backup volume the operator creates
+	DefaultRedisBackupSize = "5Gi"
+
+	// DefaultBackupUploaderImage is the default image for uploading backups to S3
+	DefaultBackupUploaderImage = "minio/mc:latest"
+
+	// DefaultAuthorizationModelKey is the default ConfigMap key of the authorization model
+	DefaultAuthorizationModelKey = "model.json"
+
+	// DefaultTargetCPUUtilization is the default target CPU utilization of an
+	// autoscaled server, in percent of its CPU request
+	DefaultTargetCPUUtilization = int32(80)
+)
+
+// Default resource requirements for tenant components
+var (
+	DefaultServerResources = ResourceRequirements{
+		CPU:    &ResourceLimit{Request: "100m", Limit: "500m"},
+		Memory: &ResourceLimit{Request: "128Mi", Limit: "512Mi"},
+	}
+	DefaultRedisResources = ResourceRequirements{
+		CPU:    &ResourceLimit{Request: "100m", Limit: "300m"},
+		Memory: &ResourceLimit{Request: "128Mi", Limit: "256Mi"},
+	}
+	DefaultRegistryResources = ResourceRequirements{
+		CPU:    &ResourceLimit{Request: "50m", Limit: "200m"},
+		Memory: &ResourceLimit{Request: "64Mi", Limit: "256Mi"},
+	}
+	DefaultSentinelResources = ResourceRequirements{
+		CPU:    &ResourceLimit{Request: "50m", Limit: "100m"},
+		Memory: &ResourceLimit{Request: "32Mi", Limit: "64Mi"},
+	}
+
+	// DefaultContainerResources is given to containers without resources in
+	// a tenant namespace with a quota
+	DefaultContainerResources = ResourceRequirements{
+		CPU:    &ResourceLimit{Request: "50m", Limit: "200m"},
+		Memory: &ResourceLimit{Request: "64Mi", Limit: "256Mi"},
+	}
+)
+
+// DefaultSentinelQuorum returns the default quorum for the given number of
+// Sentinels, a majority of them
+func DefaultSentinelQuorum(sentinels int32) int32 {
+	return sentinels/2 + 1
+}
+
+// NamespacePrefix is prepended to the tenant name to form the tenant namespace
+const NamespacePrefix = "tenant-"
+
+// NamespaceName returns the name of the namespace the tenant asks for. Once the
+// namespace is created, its name is recorded in status.namespace.
+func (r *Tenant) NamespaceName() string {
+	namespace := r.Spec.Namespace
+	if namespace == nil {
+		return NamespacePrefix + r.Name
+	}
+	if namespace.Name != "" {
+		return namespace.Name
+	}
+	prefix := NamespacePrefix
+	if namespace.Prefix != nil {
+		prefix = *namespace.Prefix
+	}
+	return prefix + r.Name + namespace.Suffix
+}
+
+// OperatorNamespace is the namespace the operator runs in. The manager sets it
+// at startup.
+var OperatorNamespace string
+
+// SecretNamespaceAllowed reports whether the tenant may reference a Secret in
+// the given namespace. The operator copies referenced Secrets into the tenant
+// namespace with its own cluster-wide access, so a tenant may only reference
+// Secrets in its own namespace or the operator namespace.
+func (r *Tenant) SecretNamespaceAllowed(namespace string) bool {
+	if namespace == "" {
+		return false
+	}
+	tenantNamespace := r.Status.Namespace
+	if tenantNamespace == "" {
+		tenantNamespace = r.NamespaceName()
+	}
+	return namespace == tenantNamespace || namespace == OperatorNamespace
+}
+
+// SetupWebhookWithManager registers the Tenant webhooks with the manager
+func (r *Tenant) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-neurallog-io-v1-tenant,mutating=true,failurePolicy=fail,sideEffects=None,groups=neurallog.io,resources=tenants,verbs=create;update,versions=v1,name=mtenant.neurallog.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &Tenant{}
+
+// Default fills in the images, replicas and resources the operator would
+// otherwise assume, so the effective configuration is visible on the object
+func (r *Tenant) Default() {
+	tenantlog.Info("default", "name", r.Name)
+
+	// Defaults would hide the plan's settings, the operator applies them after
+	// merging the plan instead
+	if r.Spec.Plan == "" {
+		r.Spec.defaultPlanSettings()
+	}
+
+	if r.Spec.DeletionPolicy == "" {
+		r.Spec.DeletionPolicy = DeletionPolicyDelete
+	}
+
+	if authorization := r.Spec.Authorization; authorization != nil {
+		defaultString(&authorization.ModelRef.Key, DefaultAuthorizationModelKey)
+	}
+
+	if exposure := r.Spec.Exposure; exposure != nil {
+		if exposure.Type == "" {
+			exposure.Type = ExposureIngress
+		}
+		if tls := exposure.TLS; tls != nil && tls.IssuerRef != nil {
+			defaultString(&tls.IssuerRef.Kind, "ClusterIssuer")
+		}
+	}
+}
+
+// defaultPlanSettings fills in the images, replicas and resources a plan could set
+func (s *TenantSpec) defaultPlanSettings() {
+	if s.Server == nil {
+		s.Server = &ServerSpec{}
+	}
+	defaultReplicas(&s.Server.Replicas)
+	defaultString(&s.Server.Image, DefaultServerImage)
+	defaultResources(&s.Server.Resources, DefaultServerResources)
+	if autoscaling := s.Server.Autoscaling; autoscaling != nil {
+		defaultReplicas(&autoscaling.MinReplicas)
+		if autoscaling.TargetCPUUtilization == nil && autoscaling.TargetMemoryUtilization == nil && len(autoscaling.Metrics) == 0 {
+			target := DefaultTargetCPUUtilization
+			autoscaling.TargetCPUUtilization = &target
+		}
+	}
+
+	if s.Redis == nil {
+		s.Redis = &RedisSpec{}
+	}
+	if sentinel := s.Redis.Sentinel; sentinel != nil {
+		if s.Redis.Replicas == nil {
+			replicas := DefaultRedisHAReplicas
+			s.Redis.Replicas = &replicas
+		}
+		if sentinel.Replicas == nil {
+			replicas := DefaultSentinelReplicas
+			sentinel.Replicas = &replicas
+		}
+		if sentinel.Quorum == nil {
+			quorum := DefaultSentinelQuorum(*sentinel.Replicas)
+			sentinel.Quorum = &quorum
+		}
+	}
+	defaultReplicas(&s.Redis.Replicas)
+	defaultString(&s.Redis.Image, DefaultRedisImage)
+	defaultResources(&s.Redis.Resources, DefaultRedisResources)
+	defaultString(&s.Redis.Storage, DefaultRedisStorage)
+	if backup := s.Redis.Backup; backup != nil {
+		if backup.Retention == nil {
+			retention := DefaultRedisBackupRetention
+			backup.Retention = &retention
+		}
+		if pvc := backup.Destination.PVC; pvc != nil && pvc.ClaimName == "" {
+			defaultString(&pvc.Size, DefaultRedisBackupSize)
+		}
+	}
+
+	if s.Registry == nil {
+		s.Registry = &RegistrySpec{}
+	}
+	defaultReplicas(&s.Registry.Replicas)
+	defaultString(&s.Registry.Image, DefaultRegistryImage)
+	defaultResources(&s.Registry.Resources, DefaultRegistryResources)
+
+	if s.NetworkPolicy == nil {
+		s.NetworkPolicy = &NetworkPolicySpec{}
+	}
+	if s.NetworkPolicy.Enabled == nil {
+		enabled := true
+		s.NetworkPolicy.Enabled = &enabled
+	}
+}
+
+// defaultReplicas sets replicas to DefaultReplicas if unset
+func defaultReplicas(replicas **int32) {
+	if *replicas == nil {
+		value := DefaultReplicas
+		*replicas = &value
+	}
+}
+
+// defaultString sets value to def if empty
+func defaultString(value *string, def string) {
+	if *value == "" {
+		*value = def
+	}
+}
+
+// defaultResources fills the unset requests and limits from defaults
+func defaultResources(resources **ResourceRequirements, defaults ResourceRequirements) {
+	mergeResources(resources, defaults.DeepCopy())
+}
+
+//+kubebuilder:webhook:path=/validate-neurallog-io-v1-tenant,mutating=false,failurePolicy=fail,sideEffects=None,groups=neurallog.io,resources=tenants,verbs=create;update,versions=v1,name=vtenant.neurallog.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &Tenant{}
+
+// ValidateCreate implements webhook.Validator
+func (r *Tenant) ValidateCreate() (admission.Warnings, error) {
+	tenantlog.Info("validate create", "name", r.Name)
+	return nil, r.validateTenant(nil)
+}
+
+// ValidateUpdate implements webhook.Validator
+func (r *Tenant) ValidateUpdate(old runtime.Object) (admission.Warnings, error) {
+	tenantlog.Info("validate update", "name", r.Name)
+	oldTenant, _ := old.(*Tenant)
+	return nil, r.validateTenant(oldTenant)
+}
+
+// ValidateDelete implements webhook.Validator
+func (r *Tenant) ValidateDelete() (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateTenant returns an Invalid error listing every problem with the
+// tenant. old is the tenant being updated, or nil on create.
+func (r *Tenant) validateTenant(old *Tenant) error {
+	var allErrs field.ErrorList
+
+	specPath := field.NewPath("spec")
+	allErrs = append(allErrs, validateNamespace(specPath.Child("namespace"), r)...)
+	if old != nil && old.Status.Namespace != "" && r.NamespaceName() != old.Status.Namespace {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("namespace"),
+			fmt.Sprintf("the tenant namespace %s can't be renamed", old.Status.Namespace)))
+	}
+	allErrs = append(allErrs, validatePlanSettings(specPath, r.Spec.planSettings(), r.validateSecretNamespace)...)
+
+	// A plan can provide the backup configuration
+	if r.Spec.DeletionPolicy == DeletionPolicySnapshot && (r.Spec.Redis == nil || r.Spec.Redis.Backup == nil) && r.Spec.Plan == "" {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("deletionPolicy"), r.Spec.DeletionPolicy,
+			"requires redis.backup to be configured"))
+	}
+	if authorization := r.Spec.Authorization; authorization != nil {
+		modelRefPath := specPath.Child("authorization", "modelRef")
+		if authorization.ModelRef.Name == "" {
+			allErrs = append(allErrs, field.Required(modelRefPath.Child("name"), "the ConfigMap holding the authorization model is required"))
+		}
+		if authorization.ModelRef.Namespace == "" {
+			allErrs = append(allErrs, field.Required(modelRefPath.Child("namespace"), "the namespace of the ConfigMap is required"))
+		}
+	}
+	if auth := r.Spec.Auth; auth != nil && auth.BootstrapSecretRef != nil {
+		allErrs = append(allErrs, r.validateSecretReference(specPath.Child("auth", "bootstrapSecretRef"), *auth.BootstrapSecretRef)...)
+	}
+	allErrs = append(allErrs, validateExposure(specPath.Child("exposure"), &r.Spec, r.validateSecretNamespace)...)
+	if r.Spec.IdleTimeout != nil && r.Spec.IdleTimeout.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("idleTimeout"), r.Spec.IdleTimeout.Duration.String(), "must be greater than 0"))
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("Tenant").GroupKind(), r.Name, allErrs)
+}
+
+// planSettings returns the settings of the tenant that a plan can also hold
+func (s *TenantSpec) planSettings() TenantPlanSpec {
+	return TenantPlanSpec{
+		Resources:     s.Resources,
+		Server:        s.Server,
+		Redis:         s.Redis,
+		Registry:      s.Registry,
+		NetworkPolicy: s.NetworkPolicy,
+	}
+}
+
+// secretNamespaceValidator checks the namespace of a referenced Secret
+type secretNamespaceValidator func(path *field.Path, namespace string) field.ErrorList
+
+// validatePlanSettings validates the settings shared by tenants and plans
+func validatePlanSettings(specPath *field.Path, spec TenantPlanSpec, validateSecretNamespace secretNamespaceValidator) field.ErrorList {
+	var allErrs field.ErrorList
+	allErrs = append(allErrs, validateResources(specPath.Child("resources"), spec.Resources)...)
+
+	if server := spec.Server; server != nil {
+		serverPath := specPath.Child("server")
+		allErrs = append(allErrs, validateReplicas(serverPath.Child("replicas"), server.Replicas)...)
+		allErrs = append(allErrs, validateResources(serverPath.Child("resources"), server.Resources)...)
+		allErrs = append(allErrs, validateEnv(serverPath.Child("env"), server.Env)...)
+		allErrs = append(allErrs, validateAutoscaling(serverPath.Child("autoscaling"), server.Autoscaling)...)
+	}
+
+	if redis := spec.Redis; redis != nil {
+		redisPath := specPath.Child("redis")
+		allErrs = append(allErrs, validateReplicas(redisPath.Child("replicas"), redis.Replicas)...)
+		allErrs = append(allErrs, validateResources(redisPath.Child("resources"), redis.Resources)...)
+		allErrs = append(allErrs, validateQuantity(redisPath.Child("storage"), redis.Storage)...)
+		allErrs = append(allErrs, validateSentinel(redisPath, *redis)...)
+		allErrs = append(allErrs, validateRedisBackup(redisPath.Child("backup"), redis.Backup, validateSecretNamespace)...)
+	}
+
+	if registry := spec.Registry; registry != nil {
+		registryPath := specPath.Child("registry")
+		allErrs = append(allErrs, validateReplicas(registryPath.Child("replicas"), registry.Replicas)...)
+		allErrs = append(allErrs, validateResources(registryPath.Child("resources"), registry.Resources)...)
+	}
+
+	if networkPolicy := spec.NetworkPolicy; networkPolicy != nil {
+		networkPolicyPath := specPath.Child("networkPolicy")
+		for i, rule := range networkPolicy.IngressRules {
+			allErrs = append(allErrs, validateNetworkPolicyPorts(networkPolicyPath.Child("ingressRules").Index(i).Child("ports"), rule.Ports)...)
+		}
+		for i, rule := range networkPolicy.EgressRules {
+			allErrs = append(allErrs, validateNetworkPolicyPorts(networkPolicyPath.Child("egressRules").Index(i).Child("ports"), rule.Ports)...)
+		}
+	}
+	return allErrs
+}
+
+// validateReplicas rejects negative replica counts
+func validateReplicas(path *field.Path, replicas *int32) field.ErrorList {
+	if replicas != nil && *replicas < 0 {
+		return field.ErrorList{field.Invalid(path, *replicas, "must be greater than or equal to 0")}
+	}
+	return nil
+}
+
+// validateNamespace checks the name of the tenant namespace and the labels and
+// annotations added to it
+func validateNamespace(path *field.Path, tenant *Tenant) field.ErrorList {
+	var allErrs field.ErrorList
+	namespace := tenant.Spec.Namespace
+	if namespace == nil {
+		namespace = &NamespaceSpec{}
+	}
+
+	// Unless it's set explicitly, the tenant name becomes part of the namespace name
+	namePath, name := field.NewPath("metadata", "name"), tenant.Name
+	if namespace.Name != "" {
+		namePath, name = path.Child("name"), namespace.Name
+		if namespace.Prefix != nil || namespace.Suffix != "" {
+			allErrs = append(allErrs, field.Forbidden(namePath, "can't be combined with prefix or suffix"))
+		}
+	}
+	namespaceName := tenant.NamespaceName()
+	for _, msg := range validation.IsDNS1123Label(namespaceName) {
+		allErrs = append(allErrs, field.Invalid(namePath, name,
+			fmt.Sprintf("namespace %q is invalid: %s", namespaceName, msg)))
+	}
+	if ReservedNamespace(namespaceName) {
+		allErrs = append(allErrs, field.Forbidden(namePath,
+			fmt.Sprintf("namespace %q is reserved for Kubernetes or the operator", namespaceName)))
+	}
+
+	// The operator finds its namespaces by their neurallog.io labels
+	labelsPath := path.Child("labels")
+	allErrs = append(allErrs, metav1validation.ValidateLabels(namespace.Labels, labelsPath)...)
+	for key := range namespace.Labels {
+		if strings.HasPrefix(key, GroupVersion.Group+"/") {
+			allErrs = append(allErrs, field.Forbidden(labelsPath.Key(key), "labels in the neurallog.io domain are set by the operator"))
+		}
+	}
+	allErrs = append(allErrs, apivalidation.ValidateAnnotations(namespace.Annotations, path.Child("annotations"))...)
+
+	return allErrs
+}
+
+// ReservedNamespace reports whether a namespace belongs to Kubernetes or the
+// operator, and can't be taken over by a tenant
+func ReservedNamespace(name string) bool {
+	return name == "default" || strings.HasPrefix(name, "kube-") || (OperatorNamespace != "" && name == OperatorNamespace)
+}
+
+// validateSecretReference checks that a referenced Secret is named and in a
+// namespace the tenant may reference Secrets in
+func (r *Tenant) validateSecretReference(path *field.Path, ref SecretReference) field.ErrorList {
+	var allErrs field.ErrorList
+	if ref.Name == "" {
+		allErrs = append(allErrs, field.Required(path.Child("name"), ""))
+	}
+	if ref.Namespace == "" {
+		allErrs = append(allErrs, field.Required(path.Child("namespace"), ""))
+	} else {
+		allErrs = append(allErrs, r.validateSecretNamespace(path.Child("namespace"), ref.Namespace)...)
+	}
+	return allErrs
+}
+
+// validateSecretNamespace rejects Secrets outside the namespaces the tenant
+// may reference Secrets in
+func (r *Tenant) validateSecretNamespace(path *field.Path, namespace string) field.ErrorList {
+	if r.SecretNamespaceAllowed(namespace) {
+		return nil
+	}
+	tenantNamespace := r.Status.Namespace
+	if tenantNamespace == "" {
+		tenantNamespace = r.NamespaceName()
+	}
+	msg := fmt.Sprintf("Secrets can only be referenced in the tenant namespace %s", tenantNamespace)
+	if OperatorNamespace != "" {
+		msg += fmt.Sprintf(" or the operator namespace %s", OperatorNamespace)
+	}
+	return field.ErrorList{field.Forbidden(path, msg)}
+}
+
+// validateExposure checks that the exposed server has a hostname, a Gateway
+// for an HTTPRoute and one source of certificates for TLS
+func validateExposure(path *field.Path, spec *TenantSpec, validateSecretNamespace secretNamespaceValidator) field.ErrorList {
+	exposure := spec.Exposure
+	if exposure == nil {
+		return nil
+	}
+	var allErrs field.ErrorList
+
+	// A plan can provide the base domain
+	if exposure.Hostname != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(exposure.Hostname) {
+			allErrs = append(allErrs, field.Invalid(path.Child("hostname"), exposure.Hostname, msg))
+		}
+	} else if (spec.Registry == nil || spec.Registry.BaseDomain == "") && spec.Plan == "" {
+		allErrs = append(allErrs, field.Required(path.Child("hostname"), "a hostname or registry.baseDomain is required"))
+	}
+
+	if exposure.Type == ExposureHTTPRoute {
+		gatewayPath := path.Child("gatewayRef")
+		switch {
+		case exposure.GatewayRef == nil:
+			allErrs = append(allErrs, field.Required(gatewayPath, "an HTTPRoute requires a Gateway"))
+		case exposure.GatewayRef.Name == "" || exposure.GatewayRef.Namespace == "":
+			allErrs = append(allErrs, field.Required(gatewayPath, "the name and namespace of the Gateway are required"))
+		}
+		if exposure.TLS != nil {
+			allErrs = append(allErrs, field.Forbidden(path.Child("tls"), "TLS for an HTTPRoute is configured on the Gateway listener"))
+		}
+	}
+
+	if tls := exposure.TLS; tls != nil {
+		tlsPath := path.Child("tls")
+		switch {
+		case tls.IssuerRef == nil && tls.SecretRef == nil:
+			allErrs = append(allErrs, field.Required(tlsPath, "one of issuerRef or secretRef must be set"))
+		case tls.IssuerRef != nil && tls.SecretRef != nil:
+			allErrs = append(allErrs, field.Forbidden(tlsPath, "only one of issuerRef or secretRef may be set"))
+		case tls.IssuerRef != nil && tls.IssuerRef.Name == "":
+			allErrs = append(allErrs, field.Required(tlsPath.Child("issuerRef", "name"), ""))
+		case tls.SecretRef != nil && (tls.SecretRef.Name == "" || tls.SecretRef.Namespace == ""):
+			allErrs = append(allErrs, field.Required(tlsPath.Child("secretRef"), "the name and namespace of the Secret are required"))
+		case tls.SecretRef != nil:
+			allErrs = append(allErrs, validateSecretNamespace(tlsPath.Child("secretRef", "namespace"), tls.SecretRef.Namespace)...)
+		}
+	}
+	return allErrs
+}
+
+// validateAutoscaling checks the replica range and the targets of autoscaling
+func validateAutoscaling(path *field.Path, autoscaling *AutoscalingSpec) field.ErrorList {
+	if autoscaling == nil {
+		return nil
+	}
+	var allErrs field.ErrorList
+	minReplicas := DefaultReplicas
+	if autoscaling.MinReplicas != nil {
+		minReplicas = *autoscaling.MinReplicas
+		if minReplicas < 1 {
+			allErrs = append(allErrs, field.Invalid(path.Child("minReplicas"), minReplicas, "must be at least 1"))
+		}
+	}
+	if autoscaling.MaxReplicas < minReplicas {
+		allErrs = append(allErrs, field.Invalid(path.Child("maxReplicas"), autoscaling.MaxReplicas,
+			fmt.Sprintf("must be at least minReplicas (%d)", minReplicas)))
+	}
+	for _, t := range []struct {
+		name   string
+		target *int32
+	}{
+		{"targetCPUUtilization", autoscaling.TargetCPUUtilization},
+		{"targetMemoryUtilization", autoscaling.TargetMemoryUtilization},
+	} {
+		if t.target != nil && *t.target < 1 {
+			allErrs = append(allErrs, field.Invalid(path.Child(t.name), *t.target, "must be at least 1"))
+		}
+	}
+	for i, metric := range autoscaling.Metrics {
+		metricPath := path.Child("metrics").Index(i)
+		if metric.Name == "" {
+			allErrs = append(allErrs, field.Required(metricPath.Child("name"), ""))
+		}
+		if metric.TargetAverageValue == "" {
+			allErrs = append(allErrs, field.Required(metricPath.Child("targetAverageValue"), ""))
+		}
+		allErrs = append(allErrs, validateQuantity(metricPath.Child("targetAverageValue"), metric.TargetAverageValue)...)
+	}
+	return allErrs
+}
+
+// validateSentinel checks that Sentinel has replicas to fail over to and a reachable quorum
+func validateSentinel(path *field.Path, redis RedisSpec) field.ErrorList {
+	if redis.Sentinel == nil {
+		return nil
+	}
+	var allErrs field.ErrorList
+	if redis.Replicas != nil && *redis.Replicas < 2 {
+		allErrs = append(allErrs, field.Invalid(path.Child("replicas"), *redis.Replicas,
+			"must be at least 2 when Sentinel is enabled"))
+	}
+
+	sentinelPath := path.Child("sentinel")
+	sentinels := DefaultSentinelReplicas
+	if redis.Sentinel.Replicas != nil {
+		sentinels = *redis.Sentinel.Replicas
+		if sentinels < 1 {
+			allErrs = append(allErrs, field.Invalid(sentinelPath.Child("replicas"), sentinels, "must be at least 1"))
+		}
+	}
+	if quorum := redis.Sentinel.Quorum; quorum != nil && (*quorum < 1 || *quorum > sentinels) {
+		allErrs = append(allErrs, field.Invalid(sentinelPath.Child("quorum"), *quorum,
+			fmt.Sprintf("must be between 1 and the number of Sentinels (%d)", sentinels)))
+	}
+	return allErrs
+}
+
+// cronMacros are the predefined schedules a CronJob accepts
+var cronMacros = []string{"@yearly", "@annually", "@monthly", "@weekly", "@daily", "@midnight", "@hourly"}
+
+// validateRedisBackup checks the backup schedule, retention and destination
+func validateRedisBackup(path *field.Path, backup *RedisBackupSpec, validateSecretNamespace secretNamespaceValidator) field.ErrorList {
+	if backup == nil {
+		return nil
+	}
+	var allErrs field.ErrorList
+	allErrs = append(allErrs, validateSchedule(path.Child("schedule"), backup.Schedule)...)
+	if backup.Retention != nil && *backup.Retention < 1 {
+		allErrs = append(allErrs, field.Invalid(path.Child("retention"), *backup.Retention, "must be at least 1"))
+	}
+
+	destinationPath := path.Child("destination")
+	destination := backup.Destination
+	switch {
+	case destination.PVC == nil && destination.S3 == nil:
+		allErrs = append(allErrs, field.Required(destinationPath, "one of pvc or s3 must be set"))
+	case destination.PVC != nil && destination.S3 != nil:
+		allErrs = append(allErrs, field.Forbidden(destinationPath, "only one of pvc or s3 may be set"))
+	case destination.PVC != nil:
+		allErrs = append(allErrs, validateQuantity(destinationPath.Child("pvc", "size"), destination.PVC.Size)...)
+	case destination.S3 != nil:
+		s3Path := destinationPath.Child("s3")
+		s3 := destination.S3
+		if s3.Endpoint == "" {
+			allErrs = append(allErrs, field.Required(s3Path.Child("endpoint"), ""))
+		} else if endpoint, err := url.Parse(s3.Endpoint); err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+			allErrs = append(allErrs, field.Invalid(s3Path.Child("endpoint"), s3.Endpoint, "must be an http or https URL"))
+		}
+		if s3.Bucket == "" {
+			allErrs = append(allErrs, field.Required(s3Path.Child("bucket"), ""))
+		}
+		if s3.CredentialsSecretRef.Name == "" {
+			allErrs = append(allErrs, field.Required(s3Path.Child("credentialsSecretRef", "name"), ""))
+		}
+		if s3.CredentialsSecretRef.Namespace == "" {
+			allErrs = append(allErrs, field.Required(s3Path.Child("credentialsSecretRef", "namespace"), ""))
+		} else {
+			allErrs = append(allErrs, validateSecretNamespace(s3Path.Child("credentialsSecretRef", "namespace"), s3.CredentialsSecretRef.Namespace)...)
+		}
+	}
+	return allErrs
+}
+
+// validateSchedule accepts a standard five-field cron schedule or a predefined macro
+func validateSchedule(path *field.Path, schedule string) field.ErrorList {
+	if schedule == "" {
+		return field.ErrorList{field.Required(path, "")}
+	}
+	if strings.HasPrefix(schedule, "@") {
+		if !containsString(cronMacros, schedule) {
+			return field.ErrorList{field.NotSupported(path, schedule, cronMacros)}
+		}
+		return nil
+	}
+	if len(strings.Fields(schedule)) != 5 {
+		return field.ErrorList{field.Invalid(path, schedule, "must have five fields: minute, hour, day of month, month and day of week")}
+	}
+	return nil
+}
+
+// validateQuantity rejects values that are not valid resource quantities
+func validateQuantity(path *field.Path, value string) field.ErrorList {
+	if value == "" {
+		return nil
+	}
+	quantity, err := resource.ParseQuantity(value)
+	if err != nil {
+		return field.ErrorList{field.Invalid(path, value, err.Error())}
+	}
+	if quantity.Sign() < 0 {
+		return field.ErrorList{field.Invalid(path, value, "must be greater than or equal to 0")}
+	}
+	return nil
+}
+
+// validateResources validates every quantity and that no request exceeds its limit
+func validateResources(path *field.Path, resources *ResourceRequirements) field.ErrorList {
+	if resources == nil {
+		return nil
+	}
+	var allErrs field.ErrorList
+	for _, r := range []struct {
+		name  string
+		limit *ResourceLimit
+	}{
+		{"cpu", resources.CPU},
+		{"memory", resources.Memory},
+		{"storage", resources.Storage},
+	} {
+		limit := r.limit
+		if limit == nil {
+			continue
+		}
+		limitPath := path.Child(r.name)
+		errs := append(validateQuantity(limitPath.Child("request"), limit.Request),
+			validateQuantity(limitPath.Child("limit"), limit.Limit)...)
+		allErrs = append(allErrs, errs...)
+		if len(errs) > 0 || limit.Request == "" || limit.Limit == "" {
+			continue
+		}
+		request, max := resource.MustParse(limit.Request), resource.MustParse(limit.Limit)
+		if request.Cmp(max) > 0 {
+			allErrs = append(allErrs, field.Invalid(limitPath.Child("request"), limit.Request,
+				fmt.Sprintf("must be less than or equal to the limit %s", limit.Limit)))
+		}
+	}
+	return allErrs
+}
+
+// validateEnv rejects invalid and duplicate environment variable names
+func validateEnv(path *field.Path, env []EnvVar) field.ErrorList {
+	var allErrs field.ErrorList
+	names := map[string]bool{}
+	for i, envVar := range env {
+		namePath := path.Index(i).Child("name")
+		for _, msg := range validation.IsEnvVarName(envVar.Name) {
+			allErrs = append(allErrs, field.Invalid(namePath, envVar.Name, msg))
+		}
+		if names[envVar.Name] {
+			allErrs = append(allErrs, field.Duplicate(namePath, envVar.Name))
+		}
+		names[envVar.Name] = true
+	}
+	return allErrs
+}
+
+// supportedProtocols are the protocols a network policy port can use
+var supportedProtocols = []string{"TCP", "UDP", "SCTP"}
+
+// validateNetworkPolicyPorts validates the protocol and number of each port
+func validateNetworkPolicyPorts(path *field.Path, ports []NetworkPolicyPort) field.ErrorList {
+	var allErrs field.ErrorList
+	for i, port := range ports {
+		portPath := path.Index(i)
+		if port.Protocol != "" && !containsString(supportedProtocols, port.Protocol) {
+			allErrs = append(allErrs, field.NotSupported(portPath.Child("protocol"), port.Protocol, supportedProtocols))
+		}
+		if port.Port != 0 {
+			for _, msg := range validation.IsValidPortNum(int(port.Port)) {
+				allErrs = append(allErrs, field.Invalid(portPath.Child("port"), port.Port, msg))
+			}
+		}
+	}
+	return allErrs
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
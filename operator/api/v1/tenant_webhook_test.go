@@ -0,0 +1,438 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Tenant Webhook", func() {
+	var tenant *Tenant
+
+	BeforeEach(func() {
+		tenant = &Tenant{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-tenant"},
+			Spec: TenantSpec{
+				Server:        &ServerSpec{},
+				Redis:         &RedisSpec{},
+				Registry:      &RegistrySpec{},
+				NetworkPolicy: &NetworkPolicySpec{},
+			},
+		}
+	})
+
+	Context("When defaulting a Tenant", func() {
+		It("Should fill in images, replicas and resources", func() {
+			tenant.Spec.Server.Resources = &ResourceRequirements{CPU: &ResourceLimit{Limit: "1"}}
+			tenant.Default()
+
+			Expect(*tenant.Spec.Server.Replicas).To(Equal(DefaultReplicas))
+			Expect(tenant.Spec.Server.Image).To(Equal(DefaultServerImage))
+			Expect(tenant.Spec.Server.Resources.CPU.Request).To(Equal(DefaultServerResources.CPU.Request))
+			Expect(tenant.Spec.Server.Resources.CPU.Limit).To(Equal("1"))
+			Expect(tenant.Spec.Redis.Image).To(Equal(DefaultRedisImage))
+			Expect(tenant.Spec.Redis.Storage).To(Equal(DefaultRedisStorage))
+			Expect(tenant.Spec.Registry.Image).To(Equal(DefaultRegistryImage))
+			Expect(*tenant.Spec.NetworkPolicy.Enabled).To(BeTrue())
+			Expect(tenant.Spec.DeletionPolicy).To(Equal(DeletionPolicyDelete))
+		})
+
+		It("Should fill in the sub-specs a tenant leaves unset", func() {
+			tenant.Spec = TenantSpec{}
+			tenant.Default()
+
+			Expect(tenant.Spec.Server).NotTo(BeNil())
+			Expect(tenant.Spec.Server.Resources.Memory.Limit).To(Equal(DefaultServerResources.Memory.Limit))
+			Expect(tenant.Spec.Redis).NotTo(BeNil())
+			Expect(tenant.Spec.Registry).NotTo(BeNil())
+			Expect(*tenant.Spec.NetworkPolicy.Enabled).To(BeTrue())
+			Expect(tenant.Spec.Auth).To(BeNil())
+		})
+
+		It("Should not share the default resources between tenants", func() {
+			tenant.Default()
+			tenant.Spec.Server.Resources.CPU.Limit = "4"
+
+			Expect(DefaultServerResources.CPU.Limit).To(Equal("500m"))
+		})
+
+		It("Should default Sentinel to three Redis instances and a majority quorum", func() {
+			tenant.Spec.Redis.Sentinel = &SentinelSpec{}
+			tenant.Default()
+
+			Expect(*tenant.Spec.Redis.Replicas).To(Equal(DefaultRedisHAReplicas))
+			Expect(*tenant.Spec.Redis.Sentinel.Replicas).To(Equal(DefaultSentinelReplicas))
+			Expect(*tenant.Spec.Redis.Sentinel.Quorum).To(Equal(int32(2)))
+		})
+
+		It("Should default backup retention and the backup volume size", func() {
+			tenant.Spec.Redis.Backup = &RedisBackupSpec{
+				Schedule:    "0 2 * * *",
+				Destination: BackupDestination{PVC: &PVCBackupDestination{}},
+			}
+			tenant.Default()
+
+			Expect(*tenant.Spec.Redis.Backup.Retention).To(Equal(DefaultRedisBackupRetention))
+			Expect(tenant.Spec.Redis.Backup.Destination.PVC.Size).To(Equal(DefaultRedisBackupSize))
+		})
+
+		It("Should default the authorization model key", func() {
+			tenant.Spec.Authorization = &AuthorizationSpec{
+				ModelRef: ConfigMapKeyReference{Name: "authorization-model", Namespace: "neurallog"},
+			}
+			tenant.Default()
+
+			Expect(tenant.Spec.Authorization.ModelRef.Key).To(Equal(DefaultAuthorizationModelKey))
+		})
+
+		It("Should default autoscaling to a minimum of one replica on CPU", func() {
+			tenant.Spec.Server.Autoscaling = &AutoscalingSpec{MaxReplicas: 5}
+			tenant.Default()
+
+			Expect(*tenant.Spec.Server.Autoscaling.MinReplicas).To(Equal(DefaultReplicas))
+			Expect(*tenant.Spec.Server.Autoscaling.TargetCPUUtilization).To(Equal(DefaultTargetCPUUtilization))
+		})
+
+		It("Should default exposure to an Ingress with a ClusterIssuer", func() {
+			tenant.Spec.Exposure = &ExposureSpec{
+				TLS: &ExposureTLS{IssuerRef: &IssuerReference{Name: "letsencrypt"}},
+			}
+			tenant.Default()
+
+			Expect(tenant.Spec.Exposure.Type).To(Equal(ExposureIngress))
+			Expect(tenant.Spec.Exposure.TLS.IssuerRef.Kind).To(Equal("ClusterIssuer"))
+		})
+
+		It("Should leave the settings of a plan unset", func() {
+			tenant.Spec = TenantSpec{Plan: "team"}
+			tenant.Default()
+
+			Expect(tenant.Spec.Server).To(BeNil())
+			Expect(tenant.Spec.Redis).To(BeNil())
+			Expect(tenant.Spec.Registry).To(BeNil())
+			Expect(tenant.Spec.NetworkPolicy).To(BeNil())
+			Expect(tenant.Spec.DeletionPolicy).To(Equal(DeletionPolicyDelete))
+		})
+
+		It("Should produce a valid Tenant", func() {
+			tenant.Default()
+			_, err := tenant.ValidateCreate()
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Context("When validating a Tenant", func() {
+		expectInvalid := func(field string) {
+			_, err := tenant.ValidateCreate()
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring(field))
+		}
+
+		It("Should reject malformed quantities", func() {
+			tenant.Spec.Server.Resources = &ResourceRequirements{CPU: &ResourceLimit{Request: "500mm"}}
+			expectInvalid("spec.server.resources.cpu.request")
+		})
+
+		It("Should reject requests above their limit", func() {
+			tenant.Spec.Redis.Resources = &ResourceRequirements{Memory: &ResourceLimit{Request: "1Gi", Limit: "512Mi"}}
+			expectInvalid("spec.redis.resources.memory.request")
+		})
+
+		It("Should reject negative replicas", func() {
+			replicas := int32(-1)
+			tenant.Spec.Registry.Replicas = &replicas
+			expectInvalid("spec.registry.replicas")
+		})
+
+		It("Should reject invalid environment variable names", func() {
+			tenant.Spec.Server.Env = []EnvVar{{Name: "1INVALID"}}
+			expectInvalid("spec.server.env[0].name")
+		})
+
+		It("Should reject unsupported network policy protocols", func() {
+			tenant.Spec.NetworkPolicy.IngressRules = []NetworkPolicyRule{
+				{Ports: []NetworkPolicyPort{{Protocol: "tcp", Port: 80}}},
+			}
+			expectInvalid("spec.networkPolicy.ingressRules[0].ports[0].protocol")
+		})
+
+		It("Should reject Sentinel without replicas to fail over to", func() {
+			replicas := int32(1)
+			tenant.Spec.Redis.Replicas = &replicas
+			tenant.Spec.Redis.Sentinel = &SentinelSpec{}
+			expectInvalid("spec.redis.replicas")
+		})
+
+		It("Should reject a Sentinel quorum larger than the number of Sentinels", func() {
+			quorum := int32(4)
+			tenant.Spec.Redis.Sentinel = &SentinelSpec{Quorum: &quorum}
+			expectInvalid("spec.redis.sentinel.quorum")
+		})
+
+		It("Should reject malformed backup schedules", func() {
+			tenant.Spec.Redis.Backup = &RedisBackupSpec{
+				Schedule:    "0 2 * *",
+				Destination: BackupDestination{PVC: &PVCBackupDestination{}},
+			}
+			expectInvalid("spec.redis.backup.schedule")
+		})
+
+		It("Should require exactly one backup destination", func() {
+			tenant.Spec.Redis.Backup = &RedisBackupSpec{Schedule: "@daily"}
+			expectInvalid("spec.redis.backup.destination")
+
+			tenant.Spec.Redis.Backup.Destination = BackupDestination{
+				PVC: &PVCBackupDestination{},
+				S3:  &S3BackupDestination{},
+			}
+			expectInvalid("spec.redis.backup.destination")
+		})
+
+		It("Should require an endpoint, bucket and credentials for S3 backups", func() {
+			tenant.Spec.Redis.Backup = &RedisBackupSpec{
+				Schedule: "@daily",
+				Destination: BackupDestination{S3: &S3BackupDestination{
+					Endpoint: "minio:9000",
+				}},
+			}
+			expectInvalid("spec.redis.backup.destination.s3.endpoint")
+			expectInvalid("spec.redis.backup.destination.s3.bucket")
+			expectInvalid("spec.redis.backup.destination.s3.credentialsSecretRef.name")
+		})
+
+		It("Should accept S3 backups to MinIO", func() {
+			tenant.Spec.Redis.Backup = &RedisBackupSpec{
+				Schedule: "*/30 * * * *",
+				Destination: BackupDestination{S3: &S3BackupDestination{
+					Endpoint:             "http://minio.minio.svc:9000",
+					Bucket:               "redis-backups",
+					CredentialsSecretRef: SecretReference{Name: "minio-credentials", Namespace: "tenant-test-tenant"},
+				}},
+			}
+			_, err := tenant.ValidateCreate()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should reject S3 credentials outside the tenant and operator namespaces", func() {
+			tenant.Spec.Redis.Backup = &RedisBackupSpec{
+				Schedule: "@daily",
+				Destination: BackupDestination{S3: &S3BackupDestination{
+					Endpoint:             "http://minio.minio.svc:9000",
+					Bucket:               "redis-backups",
+					CredentialsSecretRef: SecretReference{Name: "minio-credentials", Namespace: "minio"},
+				}},
+			}
+			expectInvalid("spec.redis.backup.destination.s3.credentialsSecretRef.namespace")
+		})
+
+		It("Should reject autoscaling with a maximum below the minimum", func() {
+			minReplicas := int32(3)
+			tenant.Spec.Server.Autoscaling = &AutoscalingSpec{MinReplicas: &minReplicas, MaxReplicas: 2}
+			expectInvalid("spec.server.autoscaling.maxReplicas")
+		})
+
+		It("Should reject custom metrics without a target", func() {
+			tenant.Spec.Server.Autoscaling = &AutoscalingSpec{
+				MaxReplicas: 5,
+				Metrics:     []AutoscalingMetric{{Name: "neurallog_log_ingest_rate"}},
+			}
+			expectInvalid("spec.server.autoscaling.metrics[0].targetAverageValue")
+		})
+
+		It("Should require a hostname or base domain for exposure", func() {
+			tenant.Spec.Exposure = &ExposureSpec{Type: ExposureIngress}
+			expectInvalid("spec.exposure.hostname")
+		})
+
+		It("Should require a Gateway for an HTTPRoute", func() {
+			tenant.Spec.Exposure = &ExposureSpec{Type: ExposureHTTPRoute, Hostname: "logs.example.com"}
+			expectInvalid("spec.exposure.gatewayRef")
+		})
+
+		It("Should require exactly one certificate source", func() {
+			tenant.Spec.Exposure = &ExposureSpec{
+				Type:     ExposureIngress,
+				Hostname: "logs.example.com",
+				TLS: &ExposureTLS{
+					IssuerRef: &IssuerReference{Name: "letsencrypt", Kind: "ClusterIssuer"},
+					SecretRef: &SecretReference{Name: "logs-tls", Namespace: "certificates"},
+				},
+			}
+			expectInvalid("spec.exposure.tls")
+		})
+
+		It("Should only accept a TLS Secret in the tenant or operator namespace", func() {
+			defer func(namespace string) { OperatorNamespace = namespace }(OperatorNamespace)
+			OperatorNamespace = "neurallog-system"
+
+			tenant.Spec.Exposure = &ExposureSpec{
+				Type:     ExposureIngress,
+				Hostname: "logs.example.com",
+				TLS:      &ExposureTLS{SecretRef: &SecretReference{Name: "logs-tls", Namespace: "certificates"}},
+			}
+			expectInvalid("spec.exposure.tls.secretRef.namespace")
+
+			for _, namespace := range []string{"tenant-test-tenant", "neurallog-system"} {
+				tenant.Spec.Exposure.TLS.SecretRef.Namespace = namespace
+				_, err := tenant.ValidateCreate()
+				Expect(err).NotTo(HaveOccurred())
+			}
+		})
+
+		It("Should reject the Snapshot deletion policy without backups", func() {
+			tenant.Spec.DeletionPolicy = DeletionPolicySnapshot
+			expectInvalid("spec.deletionPolicy")
+		})
+
+		It("Should accept the Snapshot deletion policy with backups from a plan", func() {
+			tenant.Spec.Plan = "team"
+			tenant.Spec.DeletionPolicy = DeletionPolicySnapshot
+			_, err := tenant.ValidateCreate()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should reject an authorization model without a ConfigMap", func() {
+			tenant.Spec.Authorization = &AuthorizationSpec{}
+			expectInvalid("spec.authorization.modelRef.name")
+		})
+
+		It("Should only accept a bootstrap Secret in the tenant or operator namespace", func() {
+			defer func(namespace string) { OperatorNamespace = namespace }(OperatorNamespace)
+			OperatorNamespace = "neurallog-system"
+
+			tenant.Spec.Auth = &AuthSpec{BootstrapSecretRef: &SecretReference{Name: "admin", Namespace: "kube-system"}}
+			expectInvalid("spec.auth.bootstrapSecretRef.namespace")
+
+			for _, namespace := range []string{"tenant-test-tenant", "neurallog-system"} {
+				tenant.Spec.Auth.BootstrapSecretRef.Namespace = namespace
+				_, err := tenant.ValidateCreate()
+				Expect(err).NotTo(HaveOccurred())
+			}
+		})
+
+		It("Should reject a non-positive idle timeout", func() {
+			tenant.Spec.IdleTimeout = &metav1.Duration{}
+			expectInvalid("spec.idleTimeout")
+		})
+
+		It("Should reject names that produce an invalid namespace", func() {
+			tenant.Name = "a-very-long-tenant-name-that-does-not-fit-into-a-namespace-name"
+			expectInvalid("metadata.name")
+		})
+
+		It("Should name the namespace from its prefix and suffix", func() {
+			prefix := "team-"
+			tenant.Spec.Namespace = &NamespaceSpec{Prefix: &prefix, Suffix: "-prod"}
+			Expect(tenant.NamespaceName()).To(Equal("team-test-tenant-prod"))
+
+			tenant.Spec.Namespace = &NamespaceSpec{Name: "logging"}
+			Expect(tenant.NamespaceName()).To(Equal("logging"))
+		})
+
+		It("Should reject a namespace name combined with a prefix", func() {
+			prefix := ""
+			tenant.Spec.Namespace = &NamespaceSpec{Name: "logging", Prefix: &prefix}
+			expectInvalid("spec.namespace.name")
+		})
+
+		It("Should reject namespaces reserved for Kubernetes or the operator", func() {
+			defer func(namespace string) { OperatorNamespace = namespace }(OperatorNamespace)
+			OperatorNamespace = "neurallog-system"
+
+			for _, name := range []string{"default", "kube-system", "kube-public", "kube-node-lease", "neurallog-system"} {
+				tenant.Spec.Namespace = &NamespaceSpec{Name: name, Adopt: true}
+				expectInvalid("spec.namespace.name")
+			}
+
+			prefix := "kube-"
+			tenant.Spec.Namespace = &NamespaceSpec{Prefix: &prefix}
+			expectInvalid("metadata.name")
+
+			tenant.Spec.Namespace = &NamespaceSpec{Name: "logging", Adopt: true}
+			_, err := tenant.ValidateCreate()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should reject namespace labels in the operator's domain", func() {
+			tenant.Spec.Namespace = &NamespaceSpec{Labels: map[string]string{
+				"pod-security.kubernetes.io/enforce": "restricted",
+				"neurallog.io/tenant":                "other-tenant",
+			}}
+			expectInvalid("spec.namespace.labels[neurallog.io/tenant]")
+		})
+
+		It("Should reject renaming the namespace of an existing tenant", func() {
+			old := tenant.DeepCopy()
+			old.Status.Namespace = "tenant-test-tenant"
+			tenant.Spec.Namespace = &NamespaceSpec{Suffix: "-prod"}
+
+			_, err := tenant.ValidateUpdate(old)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.namespace"))
+
+			tenant.Spec.Namespace = &NamespaceSpec{Labels: map[string]string{"cost-center": "logging"}}
+			_, err = tenant.ValidateUpdate(old)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+})
+
+var _ = Describe("TenantPlan Webhook", func() {
+	It("Should validate the plan's settings like a tenant's", func() {
+		replicas := int32(-1)
+		plan := &TenantPlan{
+			ObjectMeta: metav1.ObjectMeta{Name: "team"},
+			Spec: TenantPlanSpec{
+				Server: &ServerSpec{Replicas: &replicas},
+				Redis:  &RedisSpec{Storage: "lots"},
+			},
+		}
+		_, err := plan.ValidateCreate()
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.server.replicas"))
+		Expect(err.Error()).To(ContainSubstring("spec.redis.storage"))
+	})
+
+	It("Should only accept S3 credentials in the operator namespace", func() {
+		defer func(namespace string) { OperatorNamespace = namespace }(OperatorNamespace)
+		OperatorNamespace = "neurallog-system"
+
+		plan := &TenantPlan{
+			ObjectMeta: metav1.ObjectMeta{Name: "team"},
+			Spec: TenantPlanSpec{
+				Redis: &RedisSpec{Backup: &RedisBackupSpec{
+					Schedule: "@daily",
+					Destination: BackupDestination{S3: &S3BackupDestination{
+						Endpoint:             "http://minio.minio.svc:9000",
+						Bucket:               "redis-backups",
+						CredentialsSecretRef: SecretReference{Name: "minio-credentials", Namespace: "minio"},
+					}},
+				}},
+			},
+		}
+		_, err := plan.ValidateCreate()
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.redis.backup.destination.s3.credentialsSecretRef.namespace"))
+
+		plan.Spec.Redis.Backup.Destination.S3.CredentialsSecretRef.Namespace = "neurallog-system"
+		_, err = plan.ValidateCreate()
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
@@ -0,0 +1,152 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+// MergePlan fills the settings the tenant spec leaves unset from its plan.
+// Scalars, pointers and lists set on the tenant replace the plan's, while
+// server environment variables and Redis configuration are merged by name,
+// with the tenant's entries taking precedence. The plan is not modified.
+func MergePlan(spec *TenantSpec, plan *TenantPlanSpec) {
+	plan = plan.DeepCopy()
+
+	mergeResources(&spec.Resources, plan.Resources)
+
+	if server := plan.Server; server != nil {
+		if spec.Server == nil {
+			spec.Server = &ServerSpec{}
+		}
+		mergeInt32(&spec.Server.Replicas, server.Replicas)
+		defaultString(&spec.Server.Image, server.Image)
+		mergeResources(&spec.Server.Resources, server.Resources)
+		spec.Server.Env = mergeEnv(server.Env, spec.Server.Env)
+		if spec.Server.Autoscaling == nil {
+			spec.Server.Autoscaling = server.Autoscaling
+		}
+	}
+
+	if redis := plan.Redis; redis != nil {
+		if spec.Redis == nil {
+			spec.Redis = &RedisSpec{}
+		}
+		mergeInt32(&spec.Redis.Replicas, redis.Replicas)
+		defaultString(&spec.Redis.Image, redis.Image)
+		mergeResources(&spec.Redis.Resources, redis.Resources)
+		defaultString(&spec.Redis.Storage, redis.Storage)
+		for key, value := range redis.Config {
+			if _, ok := spec.Redis.Config[key]; !ok {
+				if spec.Redis.Config == nil {
+					spec.Redis.Config = map[string]string{}
+				}
+				spec.Redis.Config[key] = value
+			}
+		}
+		if sentinel := redis.Sentinel; sentinel != nil {
+			if spec.Redis.Sentinel == nil {
+				spec.Redis.Sentinel = sentinel
+			} else {
+				mergeInt32(&spec.Redis.Sentinel.Replicas, sentinel.Replicas)
+				mergeInt32(&spec.Redis.Sentinel.Quorum, sentinel.Quorum)
+			}
+		}
+		if spec.Redis.Backup == nil {
+			spec.Redis.Backup = redis.Backup
+		}
+	}
+
+	if registry := plan.Registry; registry != nil {
+		if spec.Registry == nil {
+			spec.Registry = &RegistrySpec{}
+		}
+		mergeInt32(&spec.Registry.Replicas, registry.Replicas)
+		defaultString(&spec.Registry.Image, registry.Image)
+		mergeResources(&spec.Registry.Resources, registry.Resources)
+		defaultString(&spec.Registry.BaseDomain, registry.BaseDomain)
+	}
+
+	if networkPolicy := plan.NetworkPolicy; networkPolicy != nil {
+		if spec.NetworkPolicy == nil {
+			spec.NetworkPolicy = &NetworkPolicySpec{}
+		}
+		if spec.NetworkPolicy.Enabled == nil {
+			spec.NetworkPolicy.Enabled = networkPolicy.Enabled
+		}
+		if spec.NetworkPolicy.AllowedNamespaces == nil {
+			spec.NetworkPolicy.AllowedNamespaces = networkPolicy.AllowedNamespaces
+		}
+		if spec.NetworkPolicy.IngressRules == nil {
+			spec.NetworkPolicy.IngressRules = networkPolicy.IngressRules
+		}
+		if spec.NetworkPolicy.EgressRules == nil {
+			spec.NetworkPolicy.EgressRules = networkPolicy.EgressRules
+		}
+	}
+}
+
+// mergeInt32 sets value to the plan's if unset
+func mergeInt32(value **int32, plan *int32) {
+	if *value == nil {
+		*value = plan
+	}
+}
+
+// mergeResources fills the unset requests and limits from the plan's
+func mergeResources(resources **ResourceRequirements, plan *ResourceRequirements) {
+	if plan == nil {
+		return
+	}
+	if *resources == nil {
+		*resources = plan
+		return
+	}
+	mergeResourceLimit(&(*resources).CPU, plan.CPU)
+	mergeResourceLimit(&(*resources).Memory, plan.Memory)
+	mergeResourceLimit(&(*resources).Storage, plan.Storage)
+}
+
+// mergeResourceLimit fills the unset request and limit from the plan's
+func mergeResourceLimit(limit **ResourceLimit, plan *ResourceLimit) {
+	if plan == nil {
+		return
+	}
+	if *limit == nil {
+		*limit = plan
+		return
+	}
+	defaultString(&(*limit).Request, plan.Request)
+	defaultString(&(*limit).Limit, plan.Limit)
+}
+
+// mergeEnv returns the plan's environment variables the tenant doesn't set,
+// followed by the tenant's
+func mergeEnv(plan, tenant []EnvVar) []EnvVar {
+	if len(plan) == 0 {
+		return tenant
+	}
+
+	names := make(map[string]bool, len(tenant))
+	for _, env := range tenant {
+		names[env.Name] = true
+	}
+
+	merged := make([]EnvVar, 0, len(plan)+len(tenant))
+	for _, env := range plan {
+		if !names[env.Name] {
+			merged = append(merged, env)
+		}
+	}
+	return append(merged, tenant...)
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MergePlan", func() {
+	var plan *TenantPlanSpec
+
+	BeforeEach(func() {
+		serverReplicas, redisReplicas := int32(3), int32(2)
+		enabled := false
+		plan = &TenantPlanSpec{
+			Resources: &ResourceRequirements{CPU: &ResourceLimit{Limit: "4"}},
+			Server: &ServerSpec{
+				Replicas:  &serverReplicas,
+				Image:     "neurallog/server:team",
+				Resources: &ResourceRequirements{CPU: &ResourceLimit{Request: "200m", Limit: "1"}},
+				Env:       []EnvVar{{Name: "LOG_LEVEL", Value: "info"}, {Name: "RETENTION_DAYS", Value: "30"}},
+			},
+			Redis: &RedisSpec{
+				Replicas: &redisReplicas,
+				Storage:  "10Gi",
+				Config:   map[string]string{"maxmemory-policy": "allkeys-lru", "appendonly": "yes"},
+				Sentinel: &SentinelSpec{},
+			},
+			NetworkPolicy: &NetworkPolicySpec{Enabled: &enabled, AllowedNamespaces: []string{"monitoring"}},
+		}
+	})
+
+	It("Should fill the settings the tenant leaves unset", func() {
+		spec := &TenantSpec{}
+		MergePlan(spec, plan)
+
+		Expect(spec.Resources.CPU.Limit).To(Equal("4"))
+		Expect(*spec.Server.Replicas).To(Equal(int32(3)))
+		Expect(spec.Server.Image).To(Equal("neurallog/server:team"))
+		Expect(spec.Redis.Storage).To(Equal("10Gi"))
+		Expect(spec.Redis.Sentinel).NotTo(BeNil())
+		Expect(spec.Registry).To(BeNil())
+		Expect(*spec.NetworkPolicy.Enabled).To(BeFalse())
+		Expect(spec.NetworkPolicy.AllowedNamespaces).To(ConsistOf("monitoring"))
+	})
+
+	It("Should keep the tenant's overrides", func() {
+		replicas := int32(5)
+		spec := &TenantSpec{
+			Server: &ServerSpec{
+				Replicas:  &replicas,
+				Resources: &ResourceRequirements{CPU: &ResourceLimit{Limit: "2"}},
+				Env:       []EnvVar{{Name: "RETENTION_DAYS", Value: "90"}},
+			},
+			Redis: &RedisSpec{Config: map[string]string{"appendonly": "no"}},
+		}
+		MergePlan(spec, plan)
+
+		Expect(*spec.Server.Replicas).To(Equal(int32(5)))
+		Expect(*spec.Server.Resources.CPU).To(Equal(ResourceLimit{Request: "200m", Limit: "2"}))
+		Expect(spec.Server.Env).To(Equal([]EnvVar{{Name: "LOG_LEVEL", Value: "info"}, {Name: "RETENTION_DAYS", Value: "90"}}))
+		Expect(spec.Redis.Config).To(Equal(map[string]string{"maxmemory-policy": "allkeys-lru", "appendonly": "no"}))
+	})
+
+	It("Should not share state with the plan", func() {
+		spec := &TenantSpec{}
+		MergePlan(spec, plan)
+		*spec.Server.Replicas = 7
+		spec.Redis.Config["appendonly"] = "no"
+
+		spec.Server.Resources.CPU.Limit = "8"
+
+		Expect(*plan.Server.Replicas).To(Equal(int32(3)))
+		Expect(plan.Server.Resources.CPU.Limit).To(Equal("1"))
+		Expect(plan.Redis.Config["appendonly"]).To(Equal("yes"))
+	})
+})
@@ -0,0 +1,77 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TenantPlanSpec defines the configuration shared by the tenants on a plan.
+// A tenant's own settings take precedence over its plan's.
+type TenantPlanSpec struct {
+	// Description describes the plan
+	// +optional
+	Description string `json:"description,omitempty"`
+
+	// Resources defines the resource quota of each tenant namespace
+	// +optional
+	Resources *ResourceRequirements `json:"resources,omitempty"`
+
+	// Server defines the default configuration for the NeuralLog server
+	// +optional
+	Server *ServerSpec `json:"server,omitempty"`
+
+	// Redis defines the default configuration for the Redis instance
+	// +optional
+	Redis *RedisSpec `json:"redis,omitempty"`
+
+	// Registry defines the default configuration for the Endpoint Registry service
+	// +optional
+	Registry *RegistrySpec `json:"registry,omitempty"`
+
+	// NetworkPolicy defines the default network policy configuration
+	// +optional
+	NetworkPolicy *NetworkPolicySpec `json:"networkPolicy,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Description",type="string",JSONPath=".spec.description"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// TenantPlan is the Schema for the tenantplans API. It holds the defaults of
+// a tier of tenants, such as free, team or enterprise. Tenants reference it
+// by name in spec.plan, and changes to it roll out to all of them.
+type TenantPlan struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec TenantPlanSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// TenantPlanList contains a list of TenantPlan
+type TenantPlanList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []TenantPlan `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&TenantPlan{}, &TenantPlanList{})
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var tenantplanlog = logf.Log.WithName("tenantplan-resource")
+
+// SetupWebhookWithManager registers the TenantPlan webhook with the manager
+func (r *TenantPlan) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-neurallog-io-v1-tenantplan,mutating=false,failurePolicy=fail,sideEffects=None,groups=neurallog.io,resources=tenantplans,verbs=create;update,versions=v1,name=vtenantplan.neurallog.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &TenantPlan{}
+
+// ValidateCreate implements webhook.Validator
+func (r *TenantPlan) ValidateCreate() (admission.Warnings, error) {
+	tenantplanlog.Info("validate create", "name", r.Name)
+	return nil, r.validateTenantPlan()
+}
+
+// ValidateUpdate implements webhook.Validator
+func (r *TenantPlan) ValidateUpdate(old runtime.Object) (admission.Warnings, error) {
+	tenantplanlog.Info("validate update", "name", r.Name)
+	return nil, r.validateTenantPlan()
+}
+
+// ValidateDelete implements webhook.Validator. Tenants still on a deleted plan
+// fail to reconcile until they are moved to another plan.
+func (r *TenantPlan) ValidateDelete() (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateTenantPlan validates the plan's settings as the Tenant webhook
+// validates a tenant's own
+func (r *TenantPlan) validateTenantPlan() error {
+	allErrs := validatePlanSettings(field.NewPath("spec"), r.Spec, validatePlanSecretNamespace)
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("TenantPlan").GroupKind(), r.Name, allErrs)
+}
+
+// validatePlanSecretNamespace rejects Secrets outside the operator namespace.
+// A plan is shared by many tenants, so it can't reference a tenant namespace.
+func validatePlanSecretNamespace(path *field.Path, namespace string) field.ErrorList {
+	if OperatorNamespace != "" && namespace == OperatorNamespace {
+		return nil
+	}
+	return field.ErrorList{field.Forbidden(path, "a plan can only reference Secrets in the operator namespace")}
+}
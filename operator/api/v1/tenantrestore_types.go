@@ -0,0 +1,154 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RestoreAnnotation is set on a Tenant to the name of the TenantRestore in
+// progress. While it is set, the tenant's server and Redis are scaled to zero.
+const RestoreAnnotation = "neurallog.io/restore"
+
+// TenantRestoreSpec defines a restore of a tenant's Redis data from a backup
+// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="spec is immutable"
+type TenantRestoreSpec struct {
+	// TenantName is the name of the Tenant to restore
+	// +kubebuilder:validation:MinLength=1
+	TenantName string `json:"tenantName"`
+
+	// Backup is the file name of the backup to restore from the tenant's
+	// backup destination. Defaults to the tenant's most recent backup.
+	// +optional
+	Backup string `json:"backup,omitempty"`
+}
+
+// TenantRestoreStatus defines the observed state of a TenantRestore
+type TenantRestoreStatus struct {
+	// Conditions represent the progress and outcome of the restore
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+
+	// Phase is the current phase of the restore
+	// +optional
+	Phase RestorePhase `json:"phase,omitempty"`
+
+	// Backup is the file name of the backup being restored
+	// +optional
+	Backup string `json:"backup,omitempty"`
+
+	// RestoredVolumes is the number of Redis volumes the backup has been loaded into
+	// +optional
+	RestoredVolumes int32 `json:"restoredVolumes,omitempty"`
+
+	// StartTime is when the restore started
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// CompletionTime is when the restore completed or failed
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// RestorePhase represents the phase of a restore
+type RestorePhase string
+
+const (
+	// RestorePending means the restore is waiting to start
+	RestorePending RestorePhase = "Pending"
+
+	// RestoreScalingDown means the tenant's server and Redis are being stopped
+	RestoreScalingDown RestorePhase = "ScalingDown"
+
+	// RestoreLoading means the backup is being loaded into the Redis volumes
+	RestoreLoading RestorePhase = "Loading"
+
+	// RestoreStarting means the tenant's server and Redis are being restarted
+	RestoreStarting RestorePhase = "Starting"
+
+	// RestoreCompleted means the tenant is running with the restored data
+	RestoreCompleted RestorePhase = "Completed"
+
+	// RestoreFailed means the restore failed
+	RestoreFailed RestorePhase = "Failed"
+)
+
+// Condition types reported on a TenantRestore
+const (
+	// RestoreConditionScaledDown indicates whether the tenant's server and Redis are stopped
+	RestoreConditionScaledDown = "ScaledDown"
+
+	// RestoreConditionDataLoaded indicates whether the backup is loaded into every Redis volume
+	RestoreConditionDataLoaded = "DataLoaded"
+
+	// RestoreConditionTenantReady indicates whether the tenant is running again
+	RestoreConditionTenantReady = "TenantReady"
+
+	// RestoreConditionComplete indicates whether the restore has finished, and
+	// its reason whether it succeeded
+	RestoreConditionComplete = "Complete"
+)
+
+// Condition reasons reported on a TenantRestore
+const (
+	// ReasonRestoreWaiting means the restore is waiting for another restore or for the tenant
+	ReasonRestoreWaiting = "Waiting"
+
+	// ReasonRestoreInProgress means the step is in progress
+	ReasonRestoreInProgress = "InProgress"
+
+	// ReasonRestoreSucceeded means the step or the restore succeeded
+	ReasonRestoreSucceeded = "Succeeded"
+
+	// ReasonRestoreFailed means the step or the restore failed
+	ReasonRestoreFailed = "Failed"
+)
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Tenant",type="string",JSONPath=".spec.tenantName"
+//+kubebuilder:printcolumn:name="Backup",type="string",JSONPath=".status.backup"
+//+kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.phase"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// TenantRestore is the Schema for the tenantrestores API. It restores a
+// tenant's Redis data from one of its backups.
+type TenantRestore struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   TenantRestoreSpec   `json:"spec,omitempty"`
+	Status TenantRestoreStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// TenantRestoreList contains a list of TenantRestore
+type TenantRestoreList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []TenantRestore `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&TenantRestore{}, &TenantRestoreList{})
+}
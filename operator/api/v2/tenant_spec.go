@@ -0,0 +1,573 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TenantSpec defines the desired state of a NeuralLog Tenant
+type TenantSpec struct {
+	// DisplayName is a user-friendly name for the tenant
+	// +optional
+	DisplayName string `json:"displayName,omitempty"`
+
+	// Description provides additional information about the tenant
+	// +optional
+	Description string `json:"description,omitempty"`
+
+	// Plan is the name of the TenantPlan the tenant is on. Settings left unset
+	// on the tenant are taken from the plan.
+	// +optional
+	Plan string `json:"plan,omitempty"`
+
+	// Namespace defines the name and metadata of the tenant namespace
+	// +optional
+	Namespace *NamespaceSpec `json:"namespace,omitempty"`
+
+	// Resources defines the resource limits and requests for the tenant
+	// +optional
+	Resources *ResourceRequirements `json:"resources,omitempty"`
+
+	// Components configures the tenant's workloads. A component left out of
+	// the list runs with the defaults of its kind.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=3
+	Components []ComponentSpec `json:"components,omitempty"`
+
+	// NetworkPolicy defines the network policy configuration for the tenant
+	// +optional
+	NetworkPolicy *NetworkPolicySpec `json:"networkPolicy,omitempty"`
+
+	// Exposure publishes the tenant's server outside the cluster at
+	// <tenant>.<baseDomain of the Registry component>
+	// +optional
+	Exposure *ExposureSpec `json:"exposure,omitempty"`
+
+	// Auth defines how the tenant is registered with the Auth service
+	// +optional
+	Auth *AuthSpec `json:"auth,omitempty"`
+
+	// Authorization defines the tenant's OpenFGA store and authorization model
+	// +optional
+	Authorization *AuthorizationSpec `json:"authorization,omitempty"`
+
+	// Suspended scales the tenant's workloads to zero while keeping its data
+	// and Auth registration
+	// +optional
+	Suspended bool `json:"suspended,omitempty"`
+
+	// IdleTimeout suspends the tenant when no activity has been recorded in the
+	// LastActivityAnnotation for this long. Recording new activity resumes it.
+	// +optional
+	IdleTimeout *metav1.Duration `json:"idleTimeout,omitempty"`
+
+	// DeletionPolicy decides what happens to the tenant's data when the tenant
+	// is deleted
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+}
+
+// DeletionPolicy decides what happens to a tenant's data when it is deleted
+// +kubebuilder:validation:Enum=Delete;Retain;Snapshot
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete deletes the tenant namespace with all its volumes
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+
+	// DeletionPolicyRetain keeps the persistent volumes of the tenant namespace
+	// after the namespace is deleted
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+
+	// DeletionPolicySnapshot takes a final Redis backup to the tenant's backup
+	// destination before the namespace is deleted
+	DeletionPolicySnapshot DeletionPolicy = "Snapshot"
+)
+
+// LastActivityAnnotation is set on a Tenant to the RFC 3339 time the tenant was
+// last used, by the server or a gateway in front of it
+const LastActivityAnnotation = "neurallog.io/last-activity"
+
+// SkipAuthCleanupAnnotation is set to "true" on a Tenant to delete it without
+// removing it from the Auth service, for example when the Auth service is gone
+const SkipAuthCleanupAnnotation = "neurallog.io/skip-auth-cleanup"
+
+// NamespaceSpec defines the name and metadata of the tenant namespace. The name
+// can't be changed once the namespace is created.
+type NamespaceSpec struct {
+	// Name is the name of the namespace. It can't be combined with Prefix or Suffix.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Prefix is prepended to the tenant name to form the namespace name.
+	// Defaults to "tenant-".
+	// +optional
+	Prefix *string `json:"prefix,omitempty"`
+
+	// Suffix is appended to the tenant name to form the namespace name
+	// +optional
+	Suffix string `json:"suffix,omitempty"`
+
+	// Adopt lets the operator take over a namespace that already exists and
+	// isn't managed by it. An adopted namespace isn't deleted with the tenant,
+	// it is released by removing the labels and annotations the operator set.
+	// +optional
+	Adopt bool `json:"adopt,omitempty"`
+
+	// Labels are added to the namespace, e.g. pod-security.kubernetes.io/enforce
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations are added to the namespace
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// ResourceRequirements defines the resource limits and requests for the tenant
+type ResourceRequirements struct {
+	// CPU defines the CPU limits and requests
+	// +optional
+	CPU *ResourceLimit `json:"cpu,omitempty"`
+
+	// Memory defines the memory limits and requests
+	// +optional
+	Memory *ResourceLimit `json:"memory,omitempty"`
+
+	// Storage defines the storage limits and requests
+	// +optional
+	Storage *ResourceLimit `json:"storage,omitempty"`
+}
+
+// ResourceLimit defines a resource limit and request
+type ResourceLimit struct {
+	// Limit is the maximum amount of the resource
+	// +optional
+	Limit string `json:"limit,omitempty"`
+
+	// Request is the minimum amount of the resource
+	// +optional
+	Request string `json:"request,omitempty"`
+}
+
+// ComponentName names a tenant workload
+// +kubebuilder:validation:Enum=Server;Redis;Registry
+type ComponentName string
+
+const (
+	// ComponentServer is the NeuralLog server
+	ComponentServer ComponentName = "Server"
+
+	// ComponentRedis is the tenant's Redis instance
+	ComponentRedis ComponentName = "Redis"
+
+	// ComponentRegistry is the Endpoint Registry service
+	ComponentRegistry ComponentName = "Registry"
+)
+
+// ComponentSpec configures one of the tenant's workloads. Settings that only
+// apply to one kind of component are rejected on the others.
+// +kubebuilder:validation:XValidation:rule="self.name == 'Server' || (!has(self.env) && !has(self.autoscaling))",message="env and autoscaling are only supported on the Server component"
+// +kubebuilder:validation:XValidation:rule="self.name == 'Redis' || (!has(self.storage) && !has(self.config) && !has(self.sentinel) && !has(self.backup))",message="storage, config, sentinel and backup are only supported on the Redis component"
+// +kubebuilder:validation:XValidation:rule="self.name == 'Registry' || !has(self.baseDomain)",message="baseDomain is only supported on the Registry component"
+type ComponentSpec struct {
+	// Name is the component being configured
+	Name ComponentName `json:"name"`
+
+	// Replicas is the number of instances of the component. With Sentinel
+	// enabled on Redis, one instance is the primary and the others replicate
+	// from it.
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Image is the Docker image for the component
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Resources defines the resource limits and requests for the component
+	// +optional
+	Resources *ResourceRequirements `json:"resources,omitempty"`
+
+	// Env defines additional environment variables for the Server
+	// +optional
+	Env []EnvVar `json:"env,omitempty"`
+
+	// Autoscaling scales the Server with a HorizontalPodAutoscaler. Replicas
+	// is ignored while it is set.
+	// +optional
+	Autoscaling *AutoscalingSpec `json:"autoscaling,omitempty"`
+
+	// Storage defines the storage configuration for Redis
+	// +optional
+	Storage string `json:"storage,omitempty"`
+
+	// Config defines additional Redis configuration
+	// +optional
+	Config map[string]string `json:"config,omitempty"`
+
+	// Sentinel enables high availability with Redis Sentinel
+	// +optional
+	Sentinel *SentinelSpec `json:"sentinel,omitempty"`
+
+	// Backup defines scheduled backups of the Redis data
+	// +optional
+	Backup *RedisBackupSpec `json:"backup,omitempty"`
+
+	// BaseDomain is the base domain for Registry endpoint URLs
+	// +optional
+	BaseDomain string `json:"baseDomain,omitempty"`
+}
+
+// AutoscalingSpec defines horizontal autoscaling of the server
+type AutoscalingSpec struct {
+	// MinReplicas is the lower limit for the number of server instances
+	// +optional
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+
+	// MaxReplicas is the upper limit for the number of server instances
+	MaxReplicas int32 `json:"maxReplicas"`
+
+	// TargetCPUUtilization is the target average CPU utilization of the
+	// server, as a percentage of its CPU request. Defaults to 80 if no other
+	// target is set.
+	// +optional
+	TargetCPUUtilization *int32 `json:"targetCPUUtilization,omitempty"`
+
+	// TargetMemoryUtilization is the target average memory utilization of the
+	// server, as a percentage of its memory request
+	// +optional
+	TargetMemoryUtilization *int32 `json:"targetMemoryUtilization,omitempty"`
+
+	// Metrics are custom per-pod metrics to scale on, such as the log ingest rate
+	// +optional
+	Metrics []AutoscalingMetric `json:"metrics,omitempty"`
+}
+
+// AutoscalingMetric defines a target for a custom metric of the server pods,
+// served by a custom metrics API adapter
+type AutoscalingMetric struct {
+	// Name is the name of the metric, e.g. neurallog_log_ingest_rate
+	Name string `json:"name"`
+
+	// TargetAverageValue is the target value of the metric averaged over the
+	// server pods, e.g. "500"
+	TargetAverageValue string `json:"targetAverageValue"`
+}
+
+// SentinelSpec defines the Redis Sentinel configuration for high availability
+type SentinelSpec struct {
+	// Replicas is the number of Sentinel instances
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Quorum is the number of Sentinels that must agree the primary is down
+	// before a failover starts. Defaults to a majority of the Sentinels.
+	// +optional
+	Quorum *int32 `json:"quorum,omitempty"`
+}
+
+// RedisBackupSpec defines scheduled backups of the Redis data
+type RedisBackupSpec struct {
+	// Schedule is the cron schedule for backups, e.g. "0 2 * * *"
+	Schedule string `json:"schedule"`
+
+	// Retention is the number of backups to keep
+	// +optional
+	Retention *int32 `json:"retention,omitempty"`
+
+	// Destination is where backups are stored
+	Destination BackupDestination `json:"destination"`
+
+	// Image is the Docker image for the backup job. Defaults to the Redis image
+	// for PVC destinations and to the MinIO client for S3 destinations.
+	// +optional
+	Image string `json:"image,omitempty"`
+}
+
+// BackupDestination defines where backups are stored. Exactly one of PVC or S3 must be set.
+type BackupDestination struct {
+	// PVC stores backups in a PersistentVolumeClaim in the tenant namespace
+	// +optional
+	PVC *PVCBackupDestination `json:"pvc,omitempty"`
+
+	// S3 stores backups in an S3-compatible bucket
+	// +optional
+	S3 *S3BackupDestination `json:"s3,omitempty"`
+}
+
+// PVCBackupDestination stores backups in a PersistentVolumeClaim
+type PVCBackupDestination struct {
+	// ClaimName is the name of an existing PersistentVolumeClaim in the tenant
+	// namespace. If empty, the operator creates one.
+	// +optional
+	ClaimName string `json:"claimName,omitempty"`
+
+	// Size is the size of the PersistentVolumeClaim created by the operator
+	// +optional
+	Size string `json:"size,omitempty"`
+
+	// StorageClassName is the storage class of the PersistentVolumeClaim created by the operator
+	// +optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+}
+
+// S3BackupDestination stores backups in an S3-compatible bucket
+type S3BackupDestination struct {
+	// Endpoint is the URL of the S3-compatible service, e.g. https://s3.amazonaws.com
+	Endpoint string `json:"endpoint"`
+
+	// Bucket is the bucket backups are stored in
+	Bucket string `json:"bucket"`
+
+	// Prefix is the key prefix for backups. Defaults to the tenant name.
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// CredentialsSecretRef references a Secret with "accessKeyId" and
+	// "secretAccessKey" keys used to access the bucket
+	CredentialsSecretRef SecretReference `json:"credentialsSecretRef"`
+}
+
+// AuthSpec defines how the tenant is registered with the Auth service
+type AuthSpec struct {
+	// AdminUserID is the ID of the tenant's initial admin user
+	// +optional
+	AdminUserID string `json:"adminUserId,omitempty"`
+
+	// AdminEmail is the email address of the tenant's initial admin user
+	// +optional
+	AdminEmail string `json:"adminEmail,omitempty"`
+
+	// BootstrapSecretRef references a Secret whose "password" key is used as
+	// the initial admin password. If unset, the operator generates one.
+	// +optional
+	BootstrapSecretRef *SecretReference `json:"bootstrapSecretRef,omitempty"`
+}
+
+// SecretReference references a Secret in a specific namespace
+type SecretReference struct {
+	// Name is the name of the Secret
+	Name string `json:"name"`
+
+	// Namespace is the namespace of the Secret
+	Namespace string `json:"namespace"`
+}
+
+// AuthorizationSpec defines the OpenFGA store and authorization model of a tenant
+type AuthorizationSpec struct {
+	// StoreID selects an existing OpenFGA store. If unset, the operator creates
+	// a store for the tenant.
+	// +optional
+	StoreID string `json:"storeId,omitempty"`
+
+	// ModelRef references the ConfigMap key holding the authorization model in
+	// OpenFGA's JSON format. A new model version is written whenever it changes.
+	ModelRef ConfigMapKeyReference `json:"modelRef"`
+}
+
+// ConfigMapKeyReference references a key of a ConfigMap in a specific namespace
+type ConfigMapKeyReference struct {
+	// Name is the name of the ConfigMap
+	Name string `json:"name"`
+
+	// Namespace is the namespace of the ConfigMap
+	Namespace string `json:"namespace"`
+
+	// Key is the key in the ConfigMap. Defaults to "model.json".
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// ExposureType is how the tenant's server is exposed
+// +kubebuilder:validation:Enum=Ingress;HTTPRoute
+type ExposureType string
+
+const (
+	// ExposureIngress exposes the server with an Ingress
+	ExposureIngress ExposureType = "Ingress"
+
+	// ExposureHTTPRoute exposes the server with a Gateway API HTTPRoute
+	ExposureHTTPRoute ExposureType = "HTTPRoute"
+)
+
+// ExposureSpec defines how the tenant's server is exposed outside the cluster
+type ExposureSpec struct {
+	// Type is the kind of route created for the server. Defaults to Ingress.
+	// +optional
+	Type ExposureType `json:"type,omitempty"`
+
+	// Hostname overrides the default hostname <tenant>.<baseDomain of the Registry component>
+	// +optional
+	Hostname string `json:"hostname,omitempty"`
+
+	// IngressClassName is the class of the Ingress
+	// +optional
+	IngressClassName *string `json:"ingressClassName,omitempty"`
+
+	// Annotations are added to the Ingress or HTTPRoute
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// GatewayRef is the Gateway the HTTPRoute attaches to. Required for the
+	// HTTPRoute type.
+	// +optional
+	GatewayRef *GatewayReference `json:"gatewayRef,omitempty"`
+
+	// TLS serves the Ingress over HTTPS. With an HTTPRoute, TLS is terminated
+	// by the Gateway listener.
+	// +optional
+	TLS *ExposureTLS `json:"tls,omitempty"`
+}
+
+// GatewayReference references a listener of a Gateway API Gateway
+type GatewayReference struct {
+	// Name is the name of the Gateway
+	Name string `json:"name"`
+
+	// Namespace is the namespace of the Gateway
+	Namespace string `json:"namespace"`
+
+	// SectionName is the name of the Gateway listener to attach to
+	// +optional
+	SectionName string `json:"sectionName,omitempty"`
+}
+
+// ExposureTLS defines where the certificate of an exposed tenant comes from.
+// Exactly one of IssuerRef or SecretRef must be set.
+type ExposureTLS struct {
+	// IssuerRef requests a certificate from a cert-manager Issuer or ClusterIssuer
+	// +optional
+	IssuerRef *IssuerReference `json:"issuerRef,omitempty"`
+
+	// SecretRef references a kubernetes.io/tls Secret, which is copied into
+	// the tenant namespace
+	// +optional
+	SecretRef *SecretReference `json:"secretRef,omitempty"`
+}
+
+// IssuerReference references a cert-manager issuer
+type IssuerReference struct {
+	// Name is the name of the issuer
+	Name string `json:"name"`
+
+	// Kind is Issuer, in the tenant namespace, or ClusterIssuer. Defaults to ClusterIssuer.
+	// +kubebuilder:validation:Enum=Issuer;ClusterIssuer
+	// +optional
+	Kind string `json:"kind,omitempty"`
+}
+
+// NetworkPolicySpec defines the network policy configuration for the tenant
+type NetworkPolicySpec struct {
+	// Enabled indicates whether network policies should be created
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// AllowedNamespaces is a list of namespaces that can access the tenant
+	// +optional
+	AllowedNamespaces []string `json:"allowedNamespaces,omitempty"`
+
+	// IngressRules defines additional ingress rules
+	// +optional
+	IngressRules []NetworkPolicyRule `json:"ingressRules,omitempty"`
+
+	// EgressRules defines additional egress rules
+	// +optional
+	EgressRules []NetworkPolicyRule `json:"egressRules,omitempty"`
+}
+
+// NetworkPolicyRule defines a network policy rule
+type NetworkPolicyRule struct {
+	// Description provides information about the rule
+	// +optional
+	Description string `json:"description,omitempty"`
+
+	// From defines the source selector for ingress rules
+	// +optional
+	From map[string]string `json:"from,omitempty"`
+
+	// To defines the destination selector for egress rules
+	// +optional
+	To map[string]string `json:"to,omitempty"`
+
+	// Ports defines the ports for the rule
+	// +optional
+	Ports []NetworkPolicyPort `json:"ports,omitempty"`
+}
+
+// NetworkPolicyPort defines a port for a network policy rule
+type NetworkPolicyPort struct {
+	// Protocol is the protocol for the port
+	// +optional
+	Protocol string `json:"protocol,omitempty"`
+
+	// Port is the port number
+	// +optional
+	Port int32 `json:"port,omitempty"`
+}
+
+// EnvVar defines an environment variable
+type EnvVar struct {
+	// Name is the name of the environment variable
+	Name string `json:"name"`
+
+	// Value is the value of the environment variable
+	// +optional
+	Value string `json:"value,omitempty"`
+
+	// ValueFrom defines a source for the environment variable value
+	// +optional
+	ValueFrom *EnvVarSource `json:"valueFrom,omitempty"`
+}
+
+// EnvVarSource defines a source for an environment variable
+type EnvVarSource struct {
+	// ConfigMapKeyRef references a key in a ConfigMap
+	// +optional
+	ConfigMapKeyRef *ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+
+	// SecretKeyRef references a key in a Secret
+	// +optional
+	SecretKeyRef *SecretKeySelector `json:"secretKeyRef,omitempty"`
+}
+
+// ConfigMapKeySelector references a key in a ConfigMap
+type ConfigMapKeySelector struct {
+	// Name is the name of the ConfigMap
+	Name string `json:"name"`
+
+	// Key is the key in the ConfigMap
+	Key string `json:"key"`
+
+	// Optional indicates whether the ConfigMap or key must exist
+	// +optional
+	Optional *bool `json:"optional,omitempty"`
+}
+
+// SecretKeySelector references a key in a Secret
+type SecretKeySelector struct {
+	// Name is the name of the Secret
+	Name string `json:"name"`
+
+	// Key is the key in the Secret
+	Key string `json:"key"`
+
+	// Optional indicates whether the Secret or key must exist
+	// +optional
+	Optional *bool `json:"optional,omitempty"`
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+)
+
+const (
+	// DefaultAuthCleanupTimeout is how long removing a deleted tenant from the
+	// Auth service is retried before the operator stops and waits for a person
+	DefaultAuthCleanupTimeout = 15 * time.Minute
+
+	// authCleanupMinBackoff and authCleanupMaxBackoff bound the delay between
+	// attempts to remove a deleted tenant from the Auth service
+	authCleanupMinBackoff = 2 * time.Second
+	authCleanupMaxBackoff = 2 * time.Minute
+)
+
+// Event reasons recorded while removing a deleted tenant from the Auth service
+const (
+	eventAuthCleanupFailed  = "AuthCleanupFailed"
+	eventAuthCleanupSkipped = "AuthCleanupSkipped"
+)
+
+// deregisterTenant removes the deleted tenant from the Auth service. It returns
+// whether the tenant is gone from the Auth service or the step was skipped, and
+// otherwise how long to wait before the next attempt. After the cleanup
+// timeout it stops retrying, and the tenant keeps its finalizer until the
+// Auth service recovers or the step is skipped with an annotation.
+func (r *TenantReconciler) deregisterTenant(ctx context.Context, tenant *neurallogv1.Tenant, now time.Time) (bool, time.Duration, error) {
+	logger := log.FromContext(ctx)
+
+	if tenant.Annotations[neurallogv1.SkipAuthCleanupAnnotation] == "true" {
+		logger.Info("Skipping removal of tenant from Auth service", "tenant", tenant.Name)
+		r.Recorder.Eventf(tenant, corev1.EventTypeWarning, eventAuthCleanupSkipped,
+			"Skipped removing the tenant from the Auth service because of the %s annotation; it may still be registered there",
+			neurallogv1.SkipAuthCleanupAnnotation)
+		return true, 0, nil
+	}
+
+	// Deleting a tenant the Auth service doesn't know succeeds
+	err := r.AuthClient.DeleteTenant(ctx, tenant.Name)
+	if err == nil {
+		logger.Info("Deleted tenant from Auth service", "tenant", tenant.Name)
+		r.AuthTenants.Remove(tenant.Name)
+		r.Recorder.Event(tenant, corev1.EventTypeNormal, eventDeregisteredFromAuth, "Removed the tenant from the Auth service")
+		return true, 0, nil
+	}
+	logger.Error(err, "Failed to delete tenant from Auth service")
+
+	timeout := r.AuthCleanupTimeout
+	if timeout == 0 {
+		timeout = DefaultAuthCleanupTimeout
+	}
+	deletedAt := tenant.DeletionTimestamp.Time
+	deadline := deletedAt.Add(timeout)
+
+	reason := neurallogv1.ReasonAuthCleanupRetrying
+	message := fmt.Sprintf("Failed to remove the tenant from the Auth service, retrying until %s: %v",
+		deadline.UTC().Format(time.RFC3339), err)
+	retryIn := authCleanupBackoff(now.Sub(deletedAt))
+	if remaining := deadline.Sub(now); remaining < retryIn {
+		retryIn = remaining
+	}
+	if retryIn <= 0 {
+		reason = neurallogv1.ReasonAuthCleanupDeadlineExceeded
+		message = fmt.Sprintf("Failed to remove the tenant from the Auth service within %s: %v; set the %s annotation to \"true\" to delete the tenant anyway",
+			timeout, err, neurallogv1.SkipAuthCleanupAnnotation)
+		retryIn = 0
+	}
+
+	// Record the failure once, so retries don't trigger reconciles of their own
+	condition := meta.FindStatusCondition(tenant.Status.Conditions, neurallogv1.ConditionAuthCleanupFailed)
+	if condition != nil && condition.Reason == reason && condition.Message == message {
+		return false, retryIn, nil
+	}
+	if reason == neurallogv1.ReasonAuthCleanupDeadlineExceeded {
+		r.Recorder.Event(tenant, corev1.EventTypeWarning, eventAuthCleanupFailed, message)
+	}
+	setCondition(tenant, neurallogv1.ConditionAuthCleanupFailed, metav1.ConditionTrue, reason, message)
+	if err := r.updateStatus(ctx, tenant); err != nil {
+		logger.Error(err, "Failed to update Tenant status")
+		return false, 0, err
+	}
+	return false, retryIn, nil
+}
+
+// authCleanupBackoff returns the delay before the next attempt to remove a
+// tenant from the Auth service. Waiting as long as the attempts have already
+// taken doubles the delay each time.
+func authCleanupBackoff(elapsed time.Duration) time.Duration {
+	if elapsed < authCleanupMinBackoff {
+		return authCleanupMinBackoff
+	}
+	if elapsed > authCleanupMaxBackoff {
+		return authCleanupMaxBackoff
+	}
+	return elapsed
+}
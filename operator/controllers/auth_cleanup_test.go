@@ -0,0 +1,134 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+)
+
+// failingAuthClient is an AuthClient whose deletes fail with err
+type failingAuthClient struct {
+	AuthClient
+	err     error
+	deletes int
+}
+
+func (c *failingAuthClient) DeleteTenant(ctx context.Context, tenantID string) error {
+	c.deletes++
+	return c.err
+}
+
+var _ = Describe("Auth service cleanup", func() {
+	var (
+		tenant     *neurallogv1.Tenant
+		deletedAt  time.Time
+		authClient *failingAuthClient
+		recorder   *record.FakeRecorder
+		r          *TenantReconciler
+	)
+
+	BeforeEach(func() {
+		deletedAt = time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+		deletionTimestamp := metav1.NewTime(deletedAt)
+		tenant = &neurallogv1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "test-tenant",
+				DeletionTimestamp: &deletionTimestamp,
+				Finalizers:        []string{"neurallog.io/finalizer"},
+			},
+		}
+
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(neurallogv1.AddToScheme(scheme)).To(Succeed())
+		authClient = &failingAuthClient{err: errors.New("connection refused")}
+		recorder = record.NewFakeRecorder(10)
+		r = &TenantReconciler{
+			Client:             fake.NewClientBuilder().WithScheme(scheme).WithObjects(tenant).WithStatusSubresource(tenant).Build(),
+			AuthClient:         authClient,
+			AuthTenants:        NewAuthTenantCache(authClient, 0),
+			Recorder:           recorder,
+			AuthCleanupTimeout: 10 * time.Minute,
+		}
+		Expect(r.Get(context.Background(), client.ObjectKeyFromObject(tenant), tenant)).To(Succeed())
+	})
+
+	It("Should retry with backoff until the deadline", func() {
+		done, retryIn, err := r.deregisterTenant(context.Background(), tenant, deletedAt.Add(time.Second))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(done).To(BeFalse())
+		Expect(retryIn).To(Equal(authCleanupMinBackoff))
+
+		condition := meta.FindStatusCondition(tenant.Status.Conditions, neurallogv1.ConditionAuthCleanupFailed)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Reason).To(Equal(neurallogv1.ReasonAuthCleanupRetrying))
+
+		_, retryIn, err = r.deregisterTenant(context.Background(), tenant, deletedAt.Add(30*time.Second))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(retryIn).To(Equal(30 * time.Second))
+
+		_, retryIn, err = r.deregisterTenant(context.Background(), tenant, deletedAt.Add(9*time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(retryIn).To(Equal(time.Minute))
+	})
+
+	It("Should stop retrying after the deadline", func() {
+		done, retryIn, err := r.deregisterTenant(context.Background(), tenant, deletedAt.Add(10*time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(done).To(BeFalse())
+		Expect(retryIn).To(BeZero())
+
+		condition := meta.FindStatusCondition(tenant.Status.Conditions, neurallogv1.ConditionAuthCleanupFailed)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Reason).To(Equal(neurallogv1.ReasonAuthCleanupDeadlineExceeded))
+		Expect(recorder.Events).To(Receive(ContainSubstring(eventAuthCleanupFailed)))
+	})
+
+	It("Should succeed once the Auth service deletes the tenant", func() {
+		authClient.err = nil
+
+		done, _, err := r.deregisterTenant(context.Background(), tenant, deletedAt.Add(time.Hour))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(done).To(BeTrue())
+		Expect(recorder.Events).To(Receive(ContainSubstring(eventDeregisteredFromAuth)))
+	})
+
+	It("Should skip the Auth service when annotated", func() {
+		tenant.Annotations = map[string]string{neurallogv1.SkipAuthCleanupAnnotation: "true"}
+
+		done, _, err := r.deregisterTenant(context.Background(), tenant, deletedAt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(done).To(BeTrue())
+		Expect(authClient.deletes).To(BeZero())
+		Expect(recorder.Events).To(Receive(ContainSubstring(eventAuthCleanupSkipped)))
+	})
+})
@@ -0,0 +1,345 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// AuthClient manages tenants in the NeuralLog Auth service
+type AuthClient interface {
+	// ListTenants returns the IDs of all tenants in the Auth service
+	ListTenants(ctx context.Context) ([]string, error)
+
+	// TenantExists checks if a tenant exists in the Auth service
+	TenantExists(ctx context.Context, tenantID string) (bool, error)
+
+	// CreateTenant creates a tenant in the Auth service and returns the
+	// credentials of its initial admin user
+	CreateTenant(ctx context.Context, req CreateTenantRequest) (*TenantAdmin, error)
+
+	// DeleteTenant deletes a tenant from the Auth service. Deleting a tenant
+	// the Auth service doesn't know succeeds.
+	DeleteTenant(ctx context.Context, tenantID string) error
+}
+
+// CreateTenantRequest is the request body for creating a tenant in the Auth service
+type CreateTenantRequest struct {
+	TenantID      string `json:"tenantId"`
+	AdminUserID   string `json:"adminUserId"`
+	AdminEmail    string `json:"adminEmail,omitempty"`
+	AdminPassword string `json:"adminPassword,omitempty"`
+}
+
+// TenantAdmin holds the credentials of a tenant's initial admin user
+type TenantAdmin struct {
+	UserID   string `json:"userId"`
+	Email    string `json:"email,omitempty"`
+	Password string `json:"password,omitempty"`
+	APIKey   string `json:"apiKey,omitempty"`
+}
+
+// AuthClientConfig defines how the operator connects to the Auth service
+type AuthClientConfig struct {
+	// URL is the base URL of the Auth service
+	URL string
+
+	// Timeout is the timeout for a single request to the Auth service
+	Timeout time.Duration
+
+	// TokenFile is the path to a file containing a bearer token. The file is
+	// read for every request, so a rotated token is picked up.
+	TokenFile string
+
+	// CAFile is the path to a PEM bundle used to verify the Auth service certificate
+	CAFile string
+
+	// CertFile and KeyFile are the client certificate and key used for mTLS
+	CertFile string
+	KeyFile  string
+}
+
+// DefaultAuthServiceURL is the in-cluster address of the Auth service
+const DefaultAuthServiceURL = "http://auth:3000"
+
+// httpAuthClient is the HTTP implementation of AuthClient
+type httpAuthClient struct {
+	baseURL    string
+	tokenFile  string
+	httpClient *http.Client
+}
+
+// NewAuthClient creates an AuthClient from the given configuration
+func NewAuthClient(cfg AuthClientConfig) (AuthClient, error) {
+	baseURL := cfg.URL
+	if baseURL == "" {
+		baseURL = DefaultAuthServiceURL
+	}
+	if _, err := url.Parse(baseURL); err != nil {
+		return nil, fmt.Errorf("invalid Auth service URL %q: %w", baseURL, err)
+	}
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+
+	// Check the bearer token can be read, so a bad path fails at startup
+	if cfg.TokenFile != "" {
+		if _, err := readTokenFile(cfg.TokenFile); err != nil {
+			return nil, err
+		}
+	}
+
+	// Configure TLS if a CA or client certificate is provided
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.CAFile != "" || cfg.CertFile != "" || cfg.KeyFile != "" {
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+		if cfg.CAFile != "" {
+			caData, err := os.ReadFile(cfg.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read Auth service CA file: %w", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(caData) {
+				return nil, fmt.Errorf("no certificates found in Auth service CA file %q", cfg.CAFile)
+			}
+			tlsConfig.RootCAs = pool
+		}
+
+		if cfg.CertFile != "" || cfg.KeyFile != "" {
+			cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load Auth service client certificate: %w", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	return &httpAuthClient{
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		tokenFile: cfg.TokenFile,
+		httpClient: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+		},
+	}, nil
+}
+
+// do sends a request to the Auth service and returns the response
+func (c *httpAuthClient) do(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set headers
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.tokenFile != "" {
+		// Projected service account tokens are rotated, so the file is read
+		// for every request
+		token, err := readTokenFile(c.tokenFile)
+		if err != nil {
+			return nil, err
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+
+	return c.httpClient.Do(req)
+}
+
+// readTokenFile returns the bearer token stored in the given file
+func readTokenFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read Auth service token file: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// ListTenants returns the IDs of all tenants in the Auth service
+func (c *httpAuthClient) ListTenants(ctx context.Context) ([]string, error) {
+	logger := log.FromContext(ctx)
+
+	// Make a request to the Auth service to list tenants
+	resp, err := c.do(ctx, http.MethodGet, "/api/tenants", nil)
+	if err != nil {
+		logger.Error(err, "Failed to connect to Auth service")
+		countAuthError("list", 0)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Read the response body
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		logger.Error(err, "Failed to read response from Auth service")
+		return nil, err
+	}
+
+	// Check the response status
+	if resp.StatusCode != http.StatusOK {
+		logger.Error(nil, "Failed to list tenants in Auth service", "statusCode", resp.StatusCode, "response", string(body))
+		countAuthError("list", resp.StatusCode)
+		return nil, fmt.Errorf("failed to list tenants in Auth service: %d", resp.StatusCode)
+	}
+
+	// Parse the response
+	var response struct {
+		Status  string   `json:"status"`
+		Tenants []string `json:"tenants"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		logger.Error(err, "Failed to parse response from Auth service")
+		return nil, err
+	}
+
+	return response.Tenants, nil
+}
+
+// TenantExists checks if a tenant exists in the Auth service
+func (c *httpAuthClient) TenantExists(ctx context.Context, tenantID string) (bool, error) {
+	tenants, err := c.ListTenants(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	// Check if the tenant exists
+	for _, t := range tenants {
+		if t == tenantID {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// CreateTenant creates a tenant in the Auth service
+func (c *httpAuthClient) CreateTenant(ctx context.Context, req CreateTenantRequest) (*TenantAdmin, error) {
+	logger := log.FromContext(ctx)
+
+	// Make a request to the Auth service to create a tenant
+	resp, err := c.do(ctx, http.MethodPost, "/api/tenants", req)
+	if err != nil {
+		logger.Error(err, "Failed to connect to Auth service")
+		countAuthError("create", 0)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Read the response body
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		logger.Error(err, "Failed to read response from Auth service")
+		return nil, err
+	}
+
+	// Check the response status
+	if resp.StatusCode != http.StatusCreated {
+		logger.Error(nil, "Failed to create tenant in Auth service", "statusCode", resp.StatusCode, "response", string(body))
+		countAuthError("create", resp.StatusCode)
+		return nil, fmt.Errorf("failed to create tenant in Auth service: %d", resp.StatusCode)
+	}
+
+	// Parse the response; older Auth service versions don't return the admin
+	var response struct {
+		Status string       `json:"status"`
+		Admin  *TenantAdmin `json:"admin"`
+	}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &response); err != nil {
+			logger.Error(err, "Failed to parse response from Auth service")
+			return nil, err
+		}
+	}
+
+	// Fall back to the requested admin for anything the Auth service omitted
+	admin := &TenantAdmin{}
+	if response.Admin != nil {
+		admin = response.Admin
+	}
+	if admin.UserID == "" {
+		admin.UserID = req.AdminUserID
+	}
+	if admin.Email == "" {
+		admin.Email = req.AdminEmail
+	}
+	if admin.Password == "" {
+		admin.Password = req.AdminPassword
+	}
+
+	return admin, nil
+}
+
+// DeleteTenant deletes a tenant from the Auth service
+func (c *httpAuthClient) DeleteTenant(ctx context.Context, tenantID string) error {
+	logger := log.FromContext(ctx)
+
+	// Make a request to the Auth service to delete the tenant
+	resp, err := c.do(ctx, http.MethodDelete, "/api/tenants/"+url.PathEscape(tenantID), nil)
+	if err != nil {
+		logger.Error(err, "Failed to connect to Auth service")
+		countAuthError("delete", 0)
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Check the response status; a missing tenant is already deleted
+	if resp.StatusCode == http.StatusNotFound {
+		logger.Info("Tenant not found in Auth service", "tenant", tenantID)
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		// Read the response body for error details
+		body, _ := io.ReadAll(resp.Body)
+		logger.Error(nil, "Failed to delete tenant from Auth service", "statusCode", resp.StatusCode, "response", string(body))
+		countAuthError("delete", resp.StatusCode)
+		return fmt.Errorf("failed to delete tenant from Auth service: %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+var _ = Describe("Auth Client", func() {
+	var (
+		server        *httptest.Server
+		authorization string
+		statusCode    int
+	)
+
+	BeforeEach(func() {
+		statusCode = http.StatusOK
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorization = r.Header.Get("Authorization")
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(statusCode)
+			fmt.Fprintln(w, `{"status":"success","tenants":["existing-tenant"]}`)
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("Should send the bearer token from the token file", func() {
+		tokenFile := filepath.Join(GinkgoT().TempDir(), "token")
+		Expect(os.WriteFile(tokenFile, []byte("secret-token\n"), 0o600)).To(Succeed())
+
+		authClient, err := NewAuthClient(AuthClientConfig{URL: server.URL, TokenFile: tokenFile})
+		Expect(err).NotTo(HaveOccurred())
+
+		exists, err := authClient.TenantExists(context.Background(), "existing-tenant")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(exists).To(BeTrue())
+		Expect(authorization).To(Equal("Bearer secret-token"))
+	})
+
+	It("Should pick up a rotated token", func() {
+		tokenFile := filepath.Join(GinkgoT().TempDir(), "token")
+		Expect(os.WriteFile(tokenFile, []byte("old-token"), 0o600)).To(Succeed())
+
+		authClient, err := NewAuthClient(AuthClientConfig{URL: server.URL, TokenFile: tokenFile})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = authClient.ListTenants(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(authorization).To(Equal("Bearer old-token"))
+
+		Expect(os.WriteFile(tokenFile, []byte("new-token"), 0o600)).To(Succeed())
+		_, err = authClient.ListTenants(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(authorization).To(Equal("Bearer new-token"))
+	})
+
+	It("Should return an error for unexpected status codes", func() {
+		statusCode = http.StatusUnauthorized
+		errors := testutil.ToFloat64(authRequestErrors.WithLabelValues("list", "401"))
+
+		authClient, err := NewAuthClient(AuthClientConfig{URL: server.URL})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = authClient.TenantExists(context.Background(), "existing-tenant")
+		Expect(err).To(HaveOccurred())
+		Expect(testutil.ToFloat64(authRequestErrors.WithLabelValues("list", "401"))).To(Equal(errors + 1))
+	})
+
+	It("Should treat deleting an unknown tenant as success", func() {
+		statusCode = http.StatusNotFound
+
+		authClient, err := NewAuthClient(AuthClientConfig{URL: server.URL})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(authClient.DeleteTenant(context.Background(), "missing-tenant")).To(Succeed())
+	})
+
+	It("Should honour context cancellation", func() {
+		authClient, err := NewAuthClient(AuthClientConfig{URL: server.URL, Timeout: time.Second})
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		Expect(authClient.DeleteTenant(ctx, "existing-tenant")).NotTo(Succeed())
+	})
+})
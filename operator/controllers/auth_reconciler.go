@@ -0,0 +1,240 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// defaultAdminUserID is the admin user registered when the tenant doesn't name one
+const defaultAdminUserID = "system"
+
+// reconcileAuthService integrates with the Auth service to manage tenant authentication
+func (r *TenantReconciler) reconcileAuthService(ctx context.Context, tenant *neurallogv1.Tenant) error {
+	logger := log.FromContext(ctx)
+	logger.Info("Reconciling Auth Service integration", "tenant", tenant.Name)
+
+	// Skip if the tenant is being deleted
+	if !tenant.ObjectMeta.DeletionTimestamp.IsZero() {
+		return nil
+	}
+
+	// Check if the tenant exists in the Auth service
+	exists, err := r.AuthTenants.Exists(ctx, tenant.Name)
+	if err != nil {
+		logger.Error(err, "Failed to check if tenant exists in Auth service")
+		return err
+	}
+
+	// Until the credentials are recorded in the status, a previous attempt may
+	// have failed after registering the tenant
+	if exists && tenant.Status.AdminCredentialsSecret != "" {
+		return nil
+	}
+
+	// Publish the admin credentials into the tenant namespace before the tenant
+	// is registered, so they aren't lost if a later step fails
+	admin, published, err := r.adminCredentials(ctx, tenant)
+	if err != nil {
+		logger.Error(err, "Failed to build Auth service registration")
+		return err
+	}
+	if exists && !published {
+		// Made-up credentials would not match the registered admin
+		logger.Info("Tenant is registered with the Auth service without published admin credentials", "tenant", tenant.Name)
+		return nil
+	}
+	secret, err := r.reconcileAdminCredentialsSecret(ctx, tenant, admin)
+	if err != nil {
+		logger.Error(err, "Failed to publish admin credentials")
+		return err
+	}
+
+	if !exists {
+		// The tenant doesn't exist in the Auth service, create it
+		created, err := r.AuthClient.CreateTenant(ctx, CreateTenantRequest{
+			TenantID:      tenant.Name,
+			AdminUserID:   admin.UserID,
+			AdminEmail:    admin.Email,
+			AdminPassword: admin.Password,
+		})
+		if err != nil {
+			logger.Error(err, "Failed to create tenant in Auth service")
+			return err
+		}
+		logger.Info("Created tenant in Auth service", "tenant", tenant.Name, "adminUserId", created.UserID)
+		r.AuthTenants.Add(tenant.Name)
+		r.Recorder.Eventf(tenant, corev1.EventTypeNormal, eventRegisteredWithAuth, "Registered the tenant with the Auth service with admin user %s", created.UserID)
+
+		// The Auth service may return more than it was sent, such as an API key
+		if *created != *admin {
+			if secret, err = r.reconcileAdminCredentialsSecret(ctx, tenant, created); err != nil {
+				logger.Error(err, "Failed to publish admin credentials")
+				return err
+			}
+		}
+	}
+
+	tenant.Status.AdminCredentialsSecret = secret.Name
+	if err := r.updateStatus(ctx, tenant); err != nil {
+		logger.Error(err, "Failed to update tenant status with admin credentials Secret")
+		return err
+	}
+
+	return nil
+}
+
+// adminCredentials returns the credentials of the tenant's initial admin, and
+// whether they were already published. A credentials Secret published by an
+// earlier attempt is reused; otherwise they are built from the tenant's auth
+// configuration, with a generated password unless a bootstrap Secret provides one.
+func (r *TenantReconciler) adminCredentials(ctx context.Context, tenant *neurallogv1.Tenant) (*TenantAdmin, bool, error) {
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, client.ObjectKey{Name: adminCredentialsSecretName(tenant), Namespace: tenant.Status.Namespace}, secret)
+	switch {
+	case err == nil && len(secret.Data["userId"]) > 0 && len(secret.Data["password"]) > 0:
+		return &TenantAdmin{
+			UserID:   string(secret.Data["userId"]),
+			Email:    string(secret.Data["email"]),
+			Password: string(secret.Data["password"]),
+			APIKey:   string(secret.Data["apiKey"]),
+		}, true, nil
+	case err != nil && !errors.IsNotFound(err):
+		return nil, false, fmt.Errorf("failed to get admin credentials Secret: %w", err)
+	}
+
+	req, err := r.createTenantRequest(ctx, tenant)
+	if err != nil {
+		return nil, false, err
+	}
+	if req.AdminPassword == "" {
+		if req.AdminPassword, err = generateAdminPassword(); err != nil {
+			return nil, false, err
+		}
+	}
+	return &TenantAdmin{UserID: req.AdminUserID, Email: req.AdminEmail, Password: req.AdminPassword}, false, nil
+}
+
+// generateAdminPassword returns a random password for a tenant's initial admin
+func generateAdminPassword() (string, error) {
+	password := make([]byte, 24)
+	if _, err := rand.Read(password); err != nil {
+		return "", fmt.Errorf("failed to generate admin password: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(password), nil
+}
+
+// authSpec returns the tenant's Auth service configuration, empty if unset
+func authSpec(tenant *neurallogv1.Tenant) neurallogv1.AuthSpec {
+	if tenant.Spec.Auth == nil {
+		return neurallogv1.AuthSpec{}
+	}
+	return *tenant.Spec.Auth
+}
+
+// createTenantRequest builds the Auth service registration for the tenant
+func (r *TenantReconciler) createTenantRequest(ctx context.Context, tenant *neurallogv1.Tenant) (CreateTenantRequest, error) {
+	auth := authSpec(tenant)
+	req := CreateTenantRequest{
+		TenantID:    tenant.Name,
+		AdminUserID: auth.AdminUserID,
+		AdminEmail:  auth.AdminEmail,
+	}
+	if req.AdminUserID == "" {
+		req.AdminUserID = defaultAdminUserID
+	}
+
+	// Read the initial admin password from the bootstrap Secret if provided
+	ref := auth.BootstrapSecretRef
+	if ref != nil {
+		bootstrapSecret, err := r.getReferencedSecret(ctx, tenant, "bootstrap", *ref)
+		if err != nil {
+			return req, err
+		}
+		password, ok := bootstrapSecret.Data["password"]
+		if !ok {
+			return req, fmt.Errorf("bootstrap Secret %s/%s has no \"password\" key", ref.Namespace, ref.Name)
+		}
+		req.AdminPassword = string(password)
+	}
+
+	return req, nil
+}
+
+// adminCredentialsSecretName returns the name of the Secret holding the tenant's admin credentials
+func adminCredentialsSecretName(tenant *neurallogv1.Tenant) string {
+	return fmt.Sprintf("%s-admin-credentials", tenant.Name)
+}
+
+// reconcileAdminCredentialsSecret creates or updates the Secret holding the tenant's admin credentials
+func (r *TenantReconciler) reconcileAdminCredentialsSecret(ctx context.Context, tenant *neurallogv1.Tenant, admin *TenantAdmin) (*corev1.Secret, error) {
+	logger := log.FromContext(ctx)
+
+	data := map[string][]byte{
+		"userId": []byte(admin.UserID),
+	}
+	if admin.Email != "" {
+		data["email"] = []byte(admin.Email)
+	}
+	if admin.Password != "" {
+		data["password"] = []byte(admin.Password)
+	}
+	if admin.APIKey != "" {
+		data["apiKey"] = []byte(admin.APIKey)
+	}
+
+	// Define Secret
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      adminCredentialsSecretName(tenant),
+			Namespace: tenant.Status.Namespace,
+			Labels: map[string]string{
+				"neurallog.io/tenant":     tenant.Name,
+				"neurallog.io/component":  "auth",
+				"neurallog.io/managed-by": "tenant-operator",
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: data,
+	}
+
+	// Set owner reference
+	if err := controllerutil.SetControllerReference(tenant, secret, r.Scheme); err != nil {
+		logger.Error(err, "Failed to set owner reference on admin credentials Secret")
+		return nil, err
+	}
+
+	// Apply the Secret
+	if err := r.apply(ctx, secret); err != nil {
+		logger.Error(err, "Failed to apply admin credentials Secret")
+		return nil, err
+	}
+	logger.Info("Applied admin credentials Secret", "secret", secret.Name)
+	return secret, nil
+}
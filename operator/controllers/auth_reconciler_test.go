@@ -0,0 +1,237 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+)
+
+// registeringAuthClient is an AuthClient that registers tenants in memory and
+// returns an API key with the admin credentials
+type registeringAuthClient struct {
+	AuthClient
+	tenants  []string
+	requests []CreateTenantRequest
+}
+
+func (c *registeringAuthClient) ListTenants(ctx context.Context) ([]string, error) {
+	return c.tenants, nil
+}
+
+func (c *registeringAuthClient) CreateTenant(ctx context.Context, req CreateTenantRequest) (*TenantAdmin, error) {
+	c.tenants = append(c.tenants, req.TenantID)
+	c.requests = append(c.requests, req)
+	return &TenantAdmin{UserID: req.AdminUserID, Email: req.AdminEmail, Password: req.AdminPassword, APIKey: "api-key"}, nil
+}
+
+// applyWithUpdate emulates server-side apply, which the fake client doesn't
+// support, by creating or updating the object
+func applyWithUpdate(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch.Type() != types.ApplyPatchType {
+		return c.Patch(ctx, obj, patch, opts...)
+	}
+	existing := obj.DeepCopyObject().(client.Object)
+	err := c.Get(ctx, client.ObjectKeyFromObject(obj), existing)
+	if apierrors.IsNotFound(err) {
+		return c.Create(ctx, obj)
+	}
+	if err != nil {
+		return err
+	}
+	obj.SetResourceVersion(existing.GetResourceVersion())
+	return c.Update(ctx, obj)
+}
+
+// newFakeTenantReconciler returns a reconciler on a fake client holding the
+// objects, for running full reconciles of a tenant that already has its
+// finalizer and namespace
+func newFakeTenantReconciler(objs ...client.Object) *TenantReconciler {
+	scheme := runtime.NewScheme()
+	Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	Expect(neurallogv1.AddToScheme(scheme)).To(Succeed())
+	authClient := &registeringAuthClient{}
+	return &TenantReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).
+			WithStatusSubresource(&neurallogv1.Tenant{}).
+			WithIndex(&neurallogv1.Tenant{}, tenantPlanIndex, tenantPlanName).
+			WithInterceptorFuncs(interceptor.Funcs{Patch: applyWithUpdate}).Build(),
+		Scheme:      scheme,
+		AuthClient:  authClient,
+		AuthTenants: NewAuthTenantCache(authClient, 0),
+		Recorder:    record.NewFakeRecorder(100),
+	}
+}
+
+var _ = Describe("Auth service reconciler", func() {
+	var (
+		tenant *neurallogv1.Tenant
+		r      *TenantReconciler
+	)
+
+	BeforeEach(func() {
+		tenant = &neurallogv1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-tenant"},
+			Status:     neurallogv1.TenantStatus{Namespace: "tenant-test-tenant"},
+		}
+		secrets := []client.Object{}
+		for _, namespace := range []string{"tenant-test-tenant", "kube-system"} {
+			secrets = append(secrets, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "admin", Namespace: namespace},
+				Data:       map[string][]byte{"password": []byte("secret-from-" + namespace)},
+			})
+		}
+
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(neurallogv1.AddToScheme(scheme)).To(Succeed())
+		r = &TenantReconciler{
+			Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(secrets...).Build(),
+			Scheme:   scheme,
+			Recorder: record.NewFakeRecorder(10),
+		}
+	})
+
+	It("Should read the bootstrap password from the tenant namespace", func() {
+		tenant.Spec.Auth = &neurallogv1.AuthSpec{
+			BootstrapSecretRef: &neurallogv1.SecretReference{Name: "admin", Namespace: "tenant-test-tenant"},
+		}
+		req, err := r.createTenantRequest(context.Background(), tenant)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(req.AdminPassword).To(Equal("secret-from-tenant-test-tenant"))
+	})
+
+	It("Should refuse a bootstrap Secret in another namespace", func() {
+		tenant.Spec.Auth = &neurallogv1.AuthSpec{
+			BootstrapSecretRef: &neurallogv1.SecretReference{Name: "admin", Namespace: "kube-system"},
+		}
+		req, err := r.createTenantRequest(context.Background(), tenant)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("not in the tenant namespace"))
+		Expect(req.AdminPassword).To(BeEmpty())
+	})
+
+	Context("When a write fails", func() {
+		var (
+			authClient    *registeringAuthClient
+			failApply     int
+			failStatus    int
+			secretKey     client.ObjectKey
+			publishedData func() map[string][]byte
+		)
+
+		BeforeEach(func() {
+			failApply, failStatus = 0, 0
+			authClient = &registeringAuthClient{}
+			r.Client = fake.NewClientBuilder().WithScheme(r.Scheme).WithObjects(tenant).WithStatusSubresource(tenant).
+				WithInterceptorFuncs(interceptor.Funcs{
+					Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+						if failApply > 0 {
+							failApply--
+							return errors.New("etcd unavailable")
+						}
+						return applyWithUpdate(ctx, c, obj, patch, opts...)
+					},
+					SubResourceUpdate: func(ctx context.Context, c client.Client, subResource string, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+						if failStatus > 0 {
+							failStatus--
+							return errors.New("etcd unavailable")
+						}
+						return c.Status().Update(ctx, obj, opts...)
+					},
+				}).Build()
+			r.AuthClient = authClient
+			r.AuthTenants = NewAuthTenantCache(authClient, time.Hour)
+			Expect(r.Get(context.Background(), client.ObjectKeyFromObject(tenant), tenant)).To(Succeed())
+
+			secretKey = client.ObjectKey{Name: "test-tenant-admin-credentials", Namespace: "tenant-test-tenant"}
+			publishedData = func() map[string][]byte {
+				secret := &corev1.Secret{}
+				Expect(r.Get(context.Background(), secretKey, secret)).To(Succeed())
+				return secret.Data
+			}
+		})
+
+		It("Should not register the tenant before its credentials are published", func() {
+			failApply = 1
+			Expect(r.reconcileAuthService(context.Background(), tenant)).NotTo(Succeed())
+			Expect(authClient.tenants).To(BeEmpty())
+			Expect(tenant.Status.AdminCredentialsSecret).To(BeEmpty())
+
+			Expect(r.reconcileAuthService(context.Background(), tenant)).To(Succeed())
+			Expect(authClient.requests).To(HaveLen(1))
+			Expect(tenant.Status.AdminCredentialsSecret).To(Equal(secretKey.Name))
+
+			data := publishedData()
+			Expect(string(data["userId"])).To(Equal(defaultAdminUserID))
+			Expect(string(data["password"])).To(Equal(authClient.requests[0].AdminPassword))
+			Expect(string(data["apiKey"])).To(Equal("api-key"))
+		})
+
+		It("Should publish the registered credentials after a failed status update", func() {
+			failStatus = 1
+			Expect(r.reconcileAuthService(context.Background(), tenant)).NotTo(Succeed())
+			Expect(authClient.tenants).To(ConsistOf("test-tenant"))
+			password := authClient.requests[0].AdminPassword
+			Expect(password).NotTo(BeEmpty())
+
+			// The tenant is registered now, and the credentials aren't generated again
+			Expect(r.reconcileAuthService(context.Background(), tenant)).To(Succeed())
+			Expect(authClient.requests).To(HaveLen(1))
+			Expect(tenant.Status.AdminCredentialsSecret).To(Equal(secretKey.Name))
+			Expect(string(publishedData()["password"])).To(Equal(password))
+		})
+
+		It("Should keep the credentials returned by the Auth service when publishing them fails", func() {
+			// The first write publishes the credentials, the second adds the API key
+			calls := 0
+			r.Client = interceptor.NewClient(r.Client.(client.WithWatch), interceptor.Funcs{
+				Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+					calls++
+					if calls == 2 {
+						return errors.New("etcd unavailable")
+					}
+					return c.Patch(ctx, obj, patch, opts...)
+				},
+			})
+			Expect(r.reconcileAuthService(context.Background(), tenant)).NotTo(Succeed())
+			password := authClient.requests[0].AdminPassword
+			Expect(string(publishedData()["password"])).To(Equal(password))
+
+			Expect(r.reconcileAuthService(context.Background(), tenant)).To(Succeed())
+			Expect(authClient.requests).To(HaveLen(1))
+			Expect(tenant.Status.AdminCredentialsSecret).To(Equal(secretKey.Name))
+			Expect(string(publishedData()["password"])).To(Equal(password))
+		})
+	})
+})
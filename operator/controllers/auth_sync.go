@@ -0,0 +1,235 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+)
+
+// DefaultAuthSyncInterval is how often the tenants in the Auth service are
+// listed and compared with the Tenant resources
+const DefaultAuthSyncInterval = 5 * time.Minute
+
+// Event reasons recorded by the Auth service sync
+const (
+	eventAuthOrphanFound   = "OrphanedInAuthService"
+	eventAuthOrphanDeleted = "OrphanDeletedFromAuthService"
+)
+
+// AuthTenantCache caches the tenants registered with the Auth service, so
+// reconciles don't each list every tenant. The list is refreshed when it is
+// older than MaxAge, and kept current with the operator's own changes.
+type AuthTenantCache struct {
+	AuthClient AuthClient
+
+	// MaxAge is how long a listing is used. Zero uses DefaultAuthSyncInterval.
+	MaxAge time.Duration
+
+	mu       sync.Mutex
+	tenants  map[string]bool
+	listedAt time.Time
+}
+
+// NewAuthTenantCache creates a cache of the tenants in the Auth service
+func NewAuthTenantCache(authClient AuthClient, maxAge time.Duration) *AuthTenantCache {
+	return &AuthTenantCache{AuthClient: authClient, MaxAge: maxAge}
+}
+
+// Exists checks if a tenant exists in the Auth service, listing the tenants
+// only if the cached listing is too old
+func (c *AuthTenantCache) Exists(ctx context.Context, tenantID string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	maxAge := c.MaxAge
+	if maxAge == 0 {
+		maxAge = DefaultAuthSyncInterval
+	}
+	if c.tenants == nil || time.Since(c.listedAt) > maxAge {
+		if err := c.refreshLocked(ctx); err != nil {
+			return false, err
+		}
+	}
+	return c.tenants[tenantID], nil
+}
+
+// Refresh lists the tenants in the Auth service and returns their IDs
+func (c *AuthTenantCache) Refresh(ctx context.Context) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.refreshLocked(ctx); err != nil {
+		return nil, err
+	}
+	tenants := make([]string, 0, len(c.tenants))
+	for tenantID := range c.tenants {
+		tenants = append(tenants, tenantID)
+	}
+	sort.Strings(tenants)
+	return tenants, nil
+}
+
+// refreshLocked replaces the cached listing; the caller holds the lock
+func (c *AuthTenantCache) refreshLocked(ctx context.Context) error {
+	tenants, err := c.AuthClient.ListTenants(ctx)
+	if err != nil {
+		return err
+	}
+	c.tenants = make(map[string]bool, len(tenants))
+	for _, tenantID := range tenants {
+		c.tenants[tenantID] = true
+	}
+	c.listedAt = time.Now()
+	return nil
+}
+
+// Add records that a tenant was created in the Auth service
+func (c *AuthTenantCache) Add(tenantID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.tenants != nil {
+		c.tenants[tenantID] = true
+	}
+}
+
+// Remove records that a tenant was deleted from the Auth service
+func (c *AuthTenantCache) Remove(tenantID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.tenants, tenantID)
+}
+
+// AuthSyncer periodically compares the tenants in the Auth service with the
+// Tenant resources. Tenants missing from the Auth service are queued for
+// reconciliation, which registers them again. Tenants only in the Auth
+// service are orphans: they are reported, and deleted if DeleteOrphans is set.
+type AuthSyncer struct {
+	client.Client
+	Cache    *AuthTenantCache
+	Recorder record.EventRecorder
+
+	// Interval is how often to sync. Zero uses DefaultAuthSyncInterval.
+	Interval time.Duration
+
+	// DeleteOrphans deletes tenants from the Auth service that have no Tenant resource
+	DeleteOrphans bool
+
+	// Events receives the Tenants that must be reconciled
+	Events chan<- event.GenericEvent
+}
+
+// Start runs the sync loop until the context is cancelled
+func (s *AuthSyncer) Start(ctx context.Context) error {
+	interval := s.Interval
+	if interval == 0 {
+		interval = DefaultAuthSyncInterval
+	}
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := s.Sync(ctx); err != nil {
+			log.FromContext(ctx).Error(err, "Failed to sync tenants with Auth service")
+		}
+	}, interval)
+	return nil
+}
+
+// NeedLeaderElection runs the sync loop only on the leader
+func (s *AuthSyncer) NeedLeaderElection() bool {
+	return true
+}
+
+// Sync compares the tenants in the Auth service with the Tenant resources once
+func (s *AuthSyncer) Sync(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("auth-sync")
+
+	authTenants, err := s.Cache.Refresh(ctx)
+	if err != nil {
+		return err
+	}
+	tenants := &neurallogv1.TenantList{}
+	if err := s.List(ctx, tenants); err != nil {
+		return err
+	}
+
+	// Queue tenants that are missing from the Auth service
+	registered := make(map[string]bool, len(authTenants))
+	for _, tenantID := range authTenants {
+		registered[tenantID] = true
+	}
+	known := make(map[string]bool, len(tenants.Items))
+	missing := 0
+	for i := range tenants.Items {
+		tenant := &tenants.Items[i]
+		known[tenant.Name] = true
+		if registered[tenant.Name] || !tenant.DeletionTimestamp.IsZero() {
+			continue
+		}
+		missing++
+		logger.Info("Tenant is missing from Auth service", "tenant", tenant.Name)
+		if s.Events != nil {
+			select {
+			case s.Events <- event.GenericEvent{Object: tenant}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	authMissingTenants.Set(float64(missing))
+
+	// Report, and optionally delete, tenants that only exist in the Auth service
+	orphans := 0
+	for _, tenantID := range authTenants {
+		if known[tenantID] {
+			continue
+		}
+		ref := &corev1.ObjectReference{
+			APIVersion: neurallogv1.GroupVersion.String(),
+			Kind:       "Tenant",
+			Name:       tenantID,
+		}
+		if !s.DeleteOrphans {
+			orphans++
+			logger.Info("Tenant in Auth service has no Tenant resource", "tenant", tenantID)
+			s.Recorder.Event(ref, corev1.EventTypeWarning, eventAuthOrphanFound,
+				"Tenant is registered with the Auth service but has no Tenant resource")
+			continue
+		}
+		if err := s.Cache.AuthClient.DeleteTenant(ctx, tenantID); err != nil {
+			orphans++
+			logger.Error(err, "Failed to delete orphaned tenant from Auth service", "tenant", tenantID)
+			continue
+		}
+		s.Cache.Remove(tenantID)
+		logger.Info("Deleted orphaned tenant from Auth service", "tenant", tenantID)
+		s.Recorder.Event(ref, corev1.EventTypeNormal, eventAuthOrphanDeleted,
+			"Deleted the tenant from the Auth service because it has no Tenant resource")
+	}
+	authOrphanedTenants.Set(float64(orphans))
+
+	return nil
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+)
+
+// listingAuthClient is an AuthClient backed by a list of tenant IDs
+type listingAuthClient struct {
+	AuthClient
+	tenants []string
+	lists   int
+	deleted []string
+}
+
+func (c *listingAuthClient) ListTenants(ctx context.Context) ([]string, error) {
+	c.lists++
+	return c.tenants, nil
+}
+
+func (c *listingAuthClient) DeleteTenant(ctx context.Context, tenantID string) error {
+	c.deleted = append(c.deleted, tenantID)
+	return nil
+}
+
+var _ = Describe("Auth service sync", func() {
+	var (
+		authClient *listingAuthClient
+		events     chan event.GenericEvent
+		recorder   *record.FakeRecorder
+		syncer     *AuthSyncer
+	)
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(neurallogv1.AddToScheme(scheme)).To(Succeed())
+		registered := &neurallogv1.Tenant{ObjectMeta: metav1.ObjectMeta{Name: "registered"}}
+		missing := &neurallogv1.Tenant{ObjectMeta: metav1.ObjectMeta{Name: "missing"}}
+
+		authClient = &listingAuthClient{tenants: []string{"registered", "orphan"}}
+		events = make(chan event.GenericEvent, 10)
+		recorder = record.NewFakeRecorder(10)
+		syncer = &AuthSyncer{
+			Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(registered, missing).Build(),
+			Cache:    NewAuthTenantCache(authClient, 0),
+			Recorder: recorder,
+			Events:   events,
+		}
+	})
+
+	It("Should queue tenants missing from the Auth service", func() {
+		Expect(syncer.Sync(context.Background())).To(Succeed())
+
+		Expect(events).To(HaveLen(1))
+		Expect((<-events).Object.GetName()).To(Equal("missing"))
+	})
+
+	It("Should report orphaned tenants without deleting them", func() {
+		Expect(syncer.Sync(context.Background())).To(Succeed())
+
+		Expect(recorder.Events).To(Receive(ContainSubstring(eventAuthOrphanFound)))
+		Expect(authClient.deleted).To(BeEmpty())
+	})
+
+	It("Should delete orphaned tenants when enabled", func() {
+		syncer.DeleteOrphans = true
+		Expect(syncer.Sync(context.Background())).To(Succeed())
+
+		Expect(authClient.deleted).To(ConsistOf("orphan"))
+		Expect(recorder.Events).To(Receive(ContainSubstring(eventAuthOrphanDeleted)))
+
+		exists, err := syncer.Cache.Exists(context.Background(), "orphan")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(exists).To(BeFalse())
+	})
+
+	It("Should answer reconciles from the cached listing", func() {
+		for i := 0; i < 3; i++ {
+			exists, err := syncer.Cache.Exists(context.Background(), "registered")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(exists).To(BeTrue())
+		}
+		Expect(authClient.lists).To(Equal(1))
+	})
+})
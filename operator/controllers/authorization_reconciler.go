@@ -0,0 +1,199 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+)
+
+// authorizationModelRefIndex indexes Tenants by the "namespace/name" of their
+// authorization model ConfigMap
+const authorizationModelRefIndex = "spec.authorization.modelRef"
+
+// eventAuthorizationModelWritten is recorded when a new model version is written
+const eventAuthorizationModelWritten = "AuthorizationModelWritten"
+
+// reconcileAuthorization selects or creates the tenant's OpenFGA store and
+// writes its authorization model whenever the model changes. The store and
+// model IDs are recorded in the status, from which the server is configured.
+func (r *TenantReconciler) reconcileAuthorization(ctx context.Context, tenant *neurallogv1.Tenant) error {
+	logger := log.FromContext(ctx)
+	authorization := tenant.Spec.Authorization
+
+	if authorization == nil {
+		tenant.Status.Authorization = nil
+		return nil
+	}
+	if r.OpenFGA == nil {
+		return fmt.Errorf("the operator is not configured with an OpenFGA URL")
+	}
+
+	// Read the model first, so a broken reference doesn't create a store
+	model, err := r.authorizationModel(ctx, authorization.ModelRef)
+	if err != nil {
+		logger.Error(err, "Failed to read authorization model")
+		return err
+	}
+	sum := sha256.Sum256(model)
+	modelHash := hex.EncodeToString(sum[:])
+
+	store, err := r.reconcileOpenFGAStore(ctx, tenant)
+	if err != nil {
+		logger.Error(err, "Failed to reconcile OpenFGA store")
+		return err
+	}
+
+	// A different store needs the model written again
+	status := tenant.Status.Authorization
+	if status == nil || status.StoreID != store.ID {
+		status = &neurallogv1.AuthorizationStatus{}
+	}
+	status.StoreID = store.ID
+	status.StoreName = store.Name
+	tenant.Status.Authorization = status
+	if status.ModelID != "" && status.ModelHash == modelHash {
+		return nil
+	}
+
+	modelID, err := r.OpenFGA.WriteAuthorizationModel(ctx, store.ID, model)
+	if err != nil {
+		logger.Error(err, "Failed to write authorization model", "store", store.ID)
+		return err
+	}
+	status.ModelID = modelID
+	status.ModelHash = modelHash
+	logger.Info("Wrote authorization model", "store", store.ID, "model", modelID)
+	r.Recorder.Eventf(tenant, corev1.EventTypeNormal, eventAuthorizationModelWritten,
+		"Wrote authorization model %s to OpenFGA store %s", modelID, store.ID)
+	return nil
+}
+
+// reconcileOpenFGAStore returns the store selected in the spec, or finds or
+// creates the tenant's own store
+func (r *TenantReconciler) reconcileOpenFGAStore(ctx context.Context, tenant *neurallogv1.Tenant) (*OpenFGAStore, error) {
+	logger := log.FromContext(ctx)
+
+	if storeID := tenant.Spec.Authorization.StoreID; storeID != "" {
+		if status := tenant.Status.Authorization; status != nil && status.StoreID == storeID {
+			return &OpenFGAStore{ID: status.StoreID, Name: status.StoreName}, nil
+		}
+		store, err := r.OpenFGA.GetStore(ctx, storeID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get OpenFGA store %s: %w", storeID, err)
+		}
+		return store, nil
+	}
+
+	// Reuse the tenant's own store once it is known
+	name := openFGAStoreName(tenant)
+	if status := tenant.Status.Authorization; status != nil && status.StoreID != "" && status.StoreName == name {
+		return &OpenFGAStore{ID: status.StoreID, Name: status.StoreName}, nil
+	}
+
+	store, err := r.OpenFGA.FindStore(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if store == nil {
+		store, err = r.OpenFGA.CreateStore(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("Created OpenFGA store", "store", store.ID, "name", name)
+	}
+	return store, nil
+}
+
+// authorizationModel reads the authorization model from its ConfigMap
+func (r *TenantReconciler) authorizationModel(ctx context.Context, ref neurallogv1.ConfigMapKeyReference) ([]byte, error) {
+	configMap := &corev1.ConfigMap{}
+	if err := r.Get(ctx, client.ObjectKey{Name: ref.Name, Namespace: ref.Namespace}, configMap); err != nil {
+		return nil, fmt.Errorf("failed to get authorization model ConfigMap %s/%s: %w", ref.Namespace, ref.Name, err)
+	}
+
+	key := authorizationModelKey(ref)
+	model, ok := configMap.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("authorization model ConfigMap %s/%s has no key %q", ref.Namespace, ref.Name, key)
+	}
+	if !json.Valid([]byte(model)) {
+		return nil, fmt.Errorf("authorization model in ConfigMap %s/%s key %q is not valid JSON", ref.Namespace, ref.Name, key)
+	}
+	return []byte(model), nil
+}
+
+// authorizationModelKey returns the ConfigMap key of the authorization model
+func authorizationModelKey(ref neurallogv1.ConfigMapKeyReference) string {
+	if ref.Key != "" {
+		return ref.Key
+	}
+	return neurallogv1.DefaultAuthorizationModelKey
+}
+
+// openFGAStoreName returns the name of the store the operator creates for the tenant
+func openFGAStoreName(tenant *neurallogv1.Tenant) string {
+	return neurallogv1.NamespacePrefix + tenant.Name
+}
+
+// openFGAEnv returns the environment that connects the server to the tenant's store
+func (r *TenantReconciler) openFGAEnv(tenant *neurallogv1.Tenant) []corev1.EnvVar {
+	status := tenant.Status.Authorization
+	if tenant.Spec.Authorization == nil || status == nil || status.StoreID == "" || r.OpenFGA == nil {
+		return nil
+	}
+	return []corev1.EnvVar{
+		{Name: "OPENFGA_API_URL", Value: r.OpenFGA.URL()},
+		{Name: "OPENFGA_STORE_ID", Value: status.StoreID},
+		{Name: "OPENFGA_AUTHORIZATION_MODEL_ID", Value: status.ModelID},
+	}
+}
+
+// authorizationModelRef returns the index key of the tenant's authorization model ConfigMap
+func authorizationModelRef(obj client.Object) []string {
+	tenant, ok := obj.(*neurallogv1.Tenant)
+	if !ok || tenant.Spec.Authorization == nil {
+		return nil
+	}
+	ref := tenant.Spec.Authorization.ModelRef
+	return []string{ref.Namespace + "/" + ref.Name}
+}
+
+// configMapToTenants maps an authorization model ConfigMap to the Tenants using it
+func (r *TenantReconciler) configMapToTenants(ctx context.Context, obj client.Object) []reconcile.Request {
+	tenants := &neurallogv1.TenantList{}
+	if err := r.List(ctx, tenants, client.MatchingFields{authorizationModelRefIndex: obj.GetNamespace() + "/" + obj.GetName()}); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list Tenants using authorization model", "configMap", obj.GetName())
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(tenants.Items))
+	for _, tenant := range tenants.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&tenant)})
+	}
+	return requests
+}
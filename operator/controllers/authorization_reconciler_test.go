@@ -0,0 +1,129 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+)
+
+var _ = Describe("Authorization reconciler", func() {
+	var (
+		server      *httptest.Server
+		storesMade  int
+		modelWrites int
+		configMap   *corev1.ConfigMap
+		tenant      *neurallogv1.Tenant
+		r           *TenantReconciler
+	)
+
+	BeforeEach(func() {
+		storesMade, modelWrites = 0, 0
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			switch {
+			case req.Method == http.MethodGet && req.URL.Path == "/stores":
+				fmt.Fprintln(w, `{"stores":[{"id":"other-store","name":"tenant-other"}],"continuation_token":""}`)
+			case req.Method == http.MethodPost && req.URL.Path == "/stores":
+				storesMade++
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprintln(w, `{"id":"tenant-store","name":"tenant-test-tenant"}`)
+			case req.Method == http.MethodPost && req.URL.Path == "/stores/tenant-store/authorization-models":
+				modelWrites++
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprintf(w, `{"authorization_model_id":"model-%d"}`+"\n", modelWrites)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprintln(w, `{"code":"not_found"}`)
+			}
+		}))
+
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "authorization-model", Namespace: "neurallog"},
+			Data:       map[string]string{"model.json": `{"schema_version":"1.1","type_definitions":[{"type":"user"}]}`},
+		}
+		tenant = &neurallogv1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-tenant"},
+			Spec: neurallogv1.TenantSpec{
+				Authorization: &neurallogv1.AuthorizationSpec{
+					ModelRef: neurallogv1.ConfigMapKeyReference{Name: "authorization-model", Namespace: "neurallog"},
+				},
+			},
+		}
+
+		openFGA, err := NewOpenFGAClient(OpenFGAClientConfig{URL: server.URL})
+		Expect(err).NotTo(HaveOccurred())
+		r = &TenantReconciler{
+			Client:   fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(configMap).Build(),
+			OpenFGA:  openFGA,
+			Recorder: record.NewFakeRecorder(10),
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("Should create a store and write the model once", func() {
+		Expect(r.reconcileAuthorization(context.Background(), tenant)).To(Succeed())
+		Expect(r.reconcileAuthorization(context.Background(), tenant)).To(Succeed())
+
+		Expect(storesMade).To(Equal(1))
+		Expect(modelWrites).To(Equal(1))
+		Expect(tenant.Status.Authorization.StoreID).To(Equal("tenant-store"))
+		Expect(tenant.Status.Authorization.ModelID).To(Equal("model-1"))
+
+		env := r.openFGAEnv(tenant)
+		Expect(env).To(ContainElement(corev1.EnvVar{Name: "OPENFGA_STORE_ID", Value: "tenant-store"}))
+		Expect(env).To(ContainElement(corev1.EnvVar{Name: "OPENFGA_AUTHORIZATION_MODEL_ID", Value: "model-1"}))
+	})
+
+	It("Should write a new model version when the model changes", func() {
+		Expect(r.reconcileAuthorization(context.Background(), tenant)).To(Succeed())
+
+		configMap.Data["model.json"] = `{"schema_version":"1.1","type_definitions":[{"type":"user"},{"type":"log"}]}`
+		Expect(r.Update(context.Background(), configMap)).To(Succeed())
+		Expect(r.reconcileAuthorization(context.Background(), tenant)).To(Succeed())
+
+		Expect(modelWrites).To(Equal(2))
+		Expect(tenant.Status.Authorization.ModelID).To(Equal("model-2"))
+	})
+
+	It("Should fail for a store that doesn't exist", func() {
+		tenant.Spec.Authorization.StoreID = "missing-store"
+		Expect(r.reconcileAuthorization(context.Background(), tenant)).NotTo(Succeed())
+	})
+
+	It("Should reject a model that isn't JSON", func() {
+		configMap.Data["model.json"] = "model\n  schema 1.1"
+		Expect(r.Update(context.Background(), configMap)).To(Succeed())
+		Expect(r.reconcileAuthorization(context.Background(), tenant)).NotTo(Succeed())
+		Expect(storesMade).To(BeZero())
+	})
+})
@@ -0,0 +1,130 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+)
+
+// stepConditions are the conditions owned by the individual reconcile steps,
+// in the order the steps run. The Ready condition summarizes them.
+var stepConditions = []string{
+	neurallogv1.ConditionPlanApplied,
+	neurallogv1.ConditionNamespaceReady,
+	neurallogv1.ConditionRedisReady,
+	neurallogv1.ConditionServerReady,
+	neurallogv1.ConditionRegistryReady,
+	neurallogv1.ConditionNetworkPoliciesReady,
+	neurallogv1.ConditionExposureReady,
+	neurallogv1.ConditionAuthSynced,
+	neurallogv1.ConditionAuthorizationReady,
+}
+
+// setCondition sets a condition on the tenant for its current generation
+func setCondition(tenant *neurallogv1.Tenant, conditionType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&tenant.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: tenant.Generation,
+	})
+}
+
+// setComponentCondition sets a step condition from the status of the component it manages
+func setComponentCondition(tenant *neurallogv1.Tenant, conditionType string, component neurallogv1.ComponentStatus) {
+	if component.Phase == neurallogv1.ComponentRunning {
+		setCondition(tenant, conditionType, metav1.ConditionTrue, neurallogv1.ReasonReconciled, component.Message)
+		return
+	}
+	setCondition(tenant, conditionType, metav1.ConditionFalse, neurallogv1.ReasonProvisioning, component.Message)
+}
+
+// stepFailed reports whether a step condition fails the tenant: the step
+// returned an error, or refused to take over an existing namespace
+func stepFailed(condition *metav1.Condition) bool {
+	return condition.Reason == neurallogv1.ReasonReconcileFailed || condition.Reason == neurallogv1.ReasonNamespaceNotManaged
+}
+
+// updatePhase derives the Ready condition and the tenant phase from the step conditions
+func updatePhase(tenant *neurallogv1.Tenant) {
+	// Any failed step fails the tenant with the error that caused it
+	for _, conditionType := range stepConditions {
+		condition := meta.FindStatusCondition(tenant.Status.Conditions, conditionType)
+		if condition != nil && stepFailed(condition) {
+			setCondition(tenant, neurallogv1.ConditionReady, metav1.ConditionFalse, condition.Reason,
+				fmt.Sprintf("%s: %s", conditionType, condition.Message))
+			tenant.Status.Phase = neurallogv1.TenantFailed
+			return
+		}
+	}
+
+	// A suspended tenant is intentionally not ready
+	if suspension := tenant.Status.Suspension; suspension != nil {
+		setCondition(tenant, neurallogv1.ConditionReady, metav1.ConditionFalse, neurallogv1.ReasonSuspended,
+			fmt.Sprintf("Tenant is suspended (%s)", suspension.Reason))
+		tenant.Status.Phase = neurallogv1.TenantSuspended
+		return
+	}
+
+	// Collect the steps that are not ready yet
+	var notReady []string
+	for _, conditionType := range stepConditions {
+		if !meta.IsStatusConditionTrue(tenant.Status.Conditions, conditionType) {
+			notReady = append(notReady, conditionType)
+		}
+	}
+
+	if len(notReady) == 0 {
+		setCondition(tenant, neurallogv1.ConditionReady, metav1.ConditionTrue, neurallogv1.ReasonAllComponentsReady, "All tenant components are ready")
+		tenant.Status.Phase = neurallogv1.TenantRunning
+		return
+	}
+
+	setCondition(tenant, neurallogv1.ConditionReady, metav1.ConditionFalse, neurallogv1.ReasonComponentsNotReady,
+		fmt.Sprintf("Waiting for %s", strings.Join(notReady, ", ")))
+	if meta.IsStatusConditionTrue(tenant.Status.Conditions, neurallogv1.ConditionNamespaceReady) {
+		tenant.Status.Phase = neurallogv1.TenantProvisioning
+	} else {
+		tenant.Status.Phase = neurallogv1.TenantPending
+	}
+}
+
+// failStep records the error returned by a reconcile step, moves the tenant to
+// the Failed phase and returns the error so the request is retried
+func (r *TenantReconciler) failStep(ctx context.Context, tenant *neurallogv1.Tenant, conditionType string, err error) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	setCondition(tenant, conditionType, metav1.ConditionFalse, neurallogv1.ReasonReconcileFailed, err.Error())
+	r.Recorder.Eventf(tenant, corev1.EventTypeWarning, eventReconcileFailed, "%s: %v", conditionType, err)
+	updatePhase(tenant)
+	if statusErr := r.updateStatus(ctx, tenant); statusErr != nil {
+		logger.Error(statusErr, "Failed to update Tenant status")
+	}
+
+	return ctrl.Result{}, err
+}
@@ -0,0 +1,256 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+)
+
+const (
+	// redisFinalBackupName is the job that takes the final backup of a deleted tenant
+	redisFinalBackupName = "redis-final-backup"
+
+	// redisFinalBackupDeadline is how long the final backup may run, in seconds
+	redisFinalBackupDeadline = 600
+
+	// deletionPollInterval is how often a deleted tenant checks on its final backup
+	deletionPollInterval = 5 * time.Second
+
+	// retainedTenantLabel and retainedClaimLabel identify the tenant and claim a
+	// retained PersistentVolume belonged to
+	retainedTenantLabel = "neurallog.io/tenant"
+	retainedClaimLabel  = "neurallog.io/claim"
+)
+
+// Event reasons recorded while applying the deletion policy
+const (
+	eventDeletionPolicy       = "DeletionPolicy"
+	eventVolumesRetained      = "VolumesRetained"
+	eventFinalBackupStarted   = "FinalBackupStarted"
+	eventFinalBackupCompleted = "FinalBackupCompleted"
+	eventFinalBackupFailed    = "FinalBackupFailed"
+	eventFinalBackupSkipped   = "FinalBackupSkipped"
+)
+
+// deletionPolicy returns the tenant's deletion policy, defaulting to Delete
+func deletionPolicy(tenant *neurallogv1.Tenant) neurallogv1.DeletionPolicy {
+	if tenant.Spec.DeletionPolicy == "" {
+		return neurallogv1.DeletionPolicyDelete
+	}
+	return tenant.Spec.DeletionPolicy
+}
+
+// applyDeletionPolicy prepares the tenant's data for the deletion of its
+// namespace. It returns false while a final backup is still running.
+func (r *TenantReconciler) applyDeletionPolicy(ctx context.Context, tenant *neurallogv1.Tenant) (bool, error) {
+	if tenant.Status.Namespace == "" {
+		return true, nil
+	}
+
+	switch deletionPolicy(tenant) {
+	case neurallogv1.DeletionPolicyRetain:
+		return true, r.retainVolumes(ctx, tenant, "")
+	case neurallogv1.DeletionPolicySnapshot:
+		return r.takeFinalBackup(ctx, tenant)
+	default:
+		return true, nil
+	}
+}
+
+// takeFinalBackup backs up the tenant's Redis data one last time. If the
+// backup can't be taken, the Redis volumes are retained instead.
+func (r *TenantReconciler) takeFinalBackup(ctx context.Context, tenant *neurallogv1.Tenant) (bool, error) {
+	logger := log.FromContext(ctx)
+	namespace := tenant.Status.Namespace
+
+	job := &batchv1.Job{}
+	err := r.Get(ctx, client.ObjectKey{Name: redisFinalBackupName, Namespace: namespace}, job)
+	if err != nil && !errors.IsNotFound(err) {
+		logger.Error(err, "Failed to get Redis final backup Job")
+		return false, err
+	}
+
+	// Start the final backup from the backup CronJob's template
+	if errors.IsNotFound(err) {
+		reason, err := r.finalBackupSkipReason(ctx, tenant)
+		if err != nil {
+			return false, err
+		}
+		if reason != "" {
+			r.Recorder.Eventf(tenant, corev1.EventTypeWarning, eventFinalBackupSkipped,
+				"Skipped the final backup because %s; retaining the Redis volumes instead", reason)
+			return true, r.retainVolumes(ctx, tenant, "")
+		}
+
+		cronJob := &batchv1.CronJob{}
+		if err := r.Get(ctx, client.ObjectKey{Name: redisBackupName, Namespace: namespace}, cronJob); err != nil {
+			logger.Error(err, "Failed to get Redis backup CronJob")
+			return false, err
+		}
+		deadline := int64(redisFinalBackupDeadline)
+		job = &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      redisFinalBackupName,
+				Namespace: namespace,
+				Labels:    cronJob.Spec.JobTemplate.Labels,
+			},
+			Spec: *cronJob.Spec.JobTemplate.Spec.DeepCopy(),
+		}
+		job.Spec.ActiveDeadlineSeconds = &deadline
+		if err := r.Create(ctx, job); err != nil && !errors.IsAlreadyExists(err) {
+			logger.Error(err, "Failed to create Redis final backup Job")
+			return false, err
+		}
+		logger.Info("Started Redis final backup", "job", job.Name)
+		r.Recorder.Event(tenant, corev1.EventTypeNormal, eventFinalBackupStarted, "Started the final backup of Redis")
+		return false, nil
+	}
+
+	// Wait for the job to finish
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			r.Recorder.Eventf(tenant, corev1.EventTypeWarning, eventFinalBackupFailed,
+				"The final backup failed: %s; retaining the Redis volumes instead", condition.Message)
+			return true, r.retainVolumes(ctx, tenant, "")
+		}
+	}
+	if job.Status.Succeeded == 0 {
+		return false, nil
+	}
+	result, err := r.redisBackupJobResult(ctx, job)
+	if err != nil {
+		return false, err
+	}
+	logger.Info("Completed Redis final backup", "name", result.Name, "sizeBytes", result.SizeBytes)
+	r.Recorder.Eventf(tenant, corev1.EventTypeNormal, eventFinalBackupCompleted,
+		"Completed the final backup %s (%d bytes)", result.Name, result.SizeBytes)
+
+	// Backups on a volume in the tenant namespace must outlive it
+	if redisSpec(tenant).Backup.Destination.PVC != nil {
+		return true, r.retainVolumes(ctx, tenant, redisBackupClaimName(tenant))
+	}
+	return true, nil
+}
+
+// finalBackupSkipReason returns why the final backup can't be taken, or an
+// empty string if it can
+func (r *TenantReconciler) finalBackupSkipReason(ctx context.Context, tenant *neurallogv1.Tenant) (string, error) {
+	logger := log.FromContext(ctx)
+	namespace := tenant.Status.Namespace
+
+	if redisSpec(tenant).Backup == nil {
+		return "backups are not configured", nil
+	}
+
+	cronJob := &batchv1.CronJob{}
+	err := r.Get(ctx, client.ObjectKey{Name: redisBackupName, Namespace: namespace}, cronJob)
+	if errors.IsNotFound(err) {
+		return "the backup CronJob does not exist", nil
+	}
+	if err != nil {
+		logger.Error(err, "Failed to get Redis backup CronJob")
+		return "", err
+	}
+
+	statefulSet := &appsv1.StatefulSet{}
+	err = r.Get(ctx, client.ObjectKey{Name: "redis", Namespace: namespace}, statefulSet)
+	if errors.IsNotFound(err) {
+		return "Redis does not exist", nil
+	}
+	if err != nil {
+		logger.Error(err, "Failed to get Redis StatefulSet")
+		return "", err
+	}
+	if statefulSet.Status.ReadyReplicas == 0 {
+		return "Redis is not running", nil
+	}
+	return "", nil
+}
+
+// retainVolumes sets the reclaim policy of the volumes bound to the tenant's
+// claims to Retain, so they survive the deletion of the namespace. If
+// claimName is set, only that claim's volume is retained.
+func (r *TenantReconciler) retainVolumes(ctx context.Context, tenant *neurallogv1.Tenant, claimName string) error {
+	logger := log.FromContext(ctx)
+
+	claims := &corev1.PersistentVolumeClaimList{}
+	if err := r.List(ctx, claims, client.InNamespace(tenant.Status.Namespace)); err != nil {
+		logger.Error(err, "Failed to list PersistentVolumeClaims")
+		return err
+	}
+
+	var retained []string
+	for _, claim := range claims.Items {
+		if claim.Spec.VolumeName == "" || (claimName != "" && claim.Name != claimName) {
+			continue
+		}
+
+		volume := &corev1.PersistentVolume{}
+		if err := r.Get(ctx, client.ObjectKey{Name: claim.Spec.VolumeName}, volume); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			logger.Error(err, "Failed to get PersistentVolume", "volume", claim.Spec.VolumeName)
+			return err
+		}
+
+		patch := client.MergeFrom(volume.DeepCopy())
+		volume.Spec.PersistentVolumeReclaimPolicy = corev1.PersistentVolumeReclaimRetain
+		if volume.Labels == nil {
+			volume.Labels = map[string]string{}
+		}
+		volume.Labels[retainedTenantLabel] = tenant.Name
+		volume.Labels[retainedClaimLabel] = claim.Name
+		if err := r.Patch(ctx, volume, patch); err != nil {
+			logger.Error(err, "Failed to retain PersistentVolume", "volume", volume.Name)
+			return err
+		}
+		retained = append(retained, volume.Name)
+	}
+
+	if len(retained) == 0 {
+		return nil
+	}
+	logger.Info("Retained PersistentVolumes", "volumes", retained)
+	r.Recorder.Eventf(tenant, corev1.EventTypeNormal, eventVolumesRetained,
+		"Retained PersistentVolumes %s", strings.Join(retained, ", "))
+	return nil
+}
+
+// deletionPolicyMessage describes what happens to the tenant's data on deletion
+func deletionPolicyMessage(tenant *neurallogv1.Tenant) string {
+	switch deletionPolicy(tenant) {
+	case neurallogv1.DeletionPolicyRetain:
+		return "Deleting the tenant and retaining its volumes"
+	case neurallogv1.DeletionPolicySnapshot:
+		return "Deleting the tenant after a final backup of its Redis data"
+	default:
+		return "Deleting the tenant and its data"
+	}
+}
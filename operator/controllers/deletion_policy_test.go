@@ -0,0 +1,99 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+)
+
+var _ = Describe("Tenant deletion policy", func() {
+	var (
+		tenant   *neurallogv1.Tenant
+		recorder *record.FakeRecorder
+		r        *TenantReconciler
+	)
+
+	BeforeEach(func() {
+		tenant = &neurallogv1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-tenant"},
+			Status:     neurallogv1.TenantStatus{Namespace: "tenant-test-tenant"},
+		}
+		claim := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "redis-data-redis-0", Namespace: "tenant-test-tenant"},
+			Spec:       corev1.PersistentVolumeClaimSpec{VolumeName: "pv-redis-0"},
+		}
+		volume := &corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pv-redis-0"},
+			Spec: corev1.PersistentVolumeSpec{
+				PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimDelete,
+			},
+		}
+		recorder = record.NewFakeRecorder(10)
+		r = &TenantReconciler{
+			Client:   fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(claim, volume).Build(),
+			Recorder: recorder,
+		}
+	})
+
+	It("Should default to deleting the tenant's data", func() {
+		Expect(deletionPolicy(tenant)).To(Equal(neurallogv1.DeletionPolicyDelete))
+
+		done, err := r.applyDeletionPolicy(context.Background(), tenant)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(done).To(BeTrue())
+
+		volume := &corev1.PersistentVolume{}
+		Expect(r.Get(context.Background(), client.ObjectKey{Name: "pv-redis-0"}, volume)).To(Succeed())
+		Expect(volume.Spec.PersistentVolumeReclaimPolicy).To(Equal(corev1.PersistentVolumeReclaimDelete))
+	})
+
+	It("Should retain the tenant's volumes", func() {
+		tenant.Spec.DeletionPolicy = neurallogv1.DeletionPolicyRetain
+
+		done, err := r.applyDeletionPolicy(context.Background(), tenant)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(done).To(BeTrue())
+
+		volume := &corev1.PersistentVolume{}
+		Expect(r.Get(context.Background(), client.ObjectKey{Name: "pv-redis-0"}, volume)).To(Succeed())
+		Expect(volume.Spec.PersistentVolumeReclaimPolicy).To(Equal(corev1.PersistentVolumeReclaimRetain))
+		Expect(volume.Labels).To(HaveKeyWithValue(retainedTenantLabel, "test-tenant"))
+		Expect(volume.Labels).To(HaveKeyWithValue(retainedClaimLabel, "redis-data-redis-0"))
+		Expect(recorder.Events).To(Receive(ContainSubstring(eventVolumesRetained)))
+	})
+
+	It("Should retain the volumes when the final backup can't be taken", func() {
+		tenant.Spec.DeletionPolicy = neurallogv1.DeletionPolicySnapshot
+
+		done, err := r.applyDeletionPolicy(context.Background(), tenant)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(done).To(BeTrue())
+		Expect(recorder.Events).To(Receive(ContainSubstring(eventFinalBackupSkipped)))
+		Expect(recorder.Events).To(Receive(ContainSubstring(eventVolumesRetained)))
+	})
+})
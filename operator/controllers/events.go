@@ -0,0 +1,82 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+)
+
+// Event reasons recorded over the lifecycle of a tenant. Changes of the step
+// conditions are recorded with the reason of the condition.
+const (
+	eventNamespaceCreated     = "NamespaceCreated"
+	eventNamespaceAdopted     = "NamespaceAdopted"
+	eventRegisteredWithAuth   = "RegisteredWithAuthService"
+	eventDeregisteredFromAuth = "DeregisteredFromAuthService"
+	eventPhaseChanged         = "PhaseChanged"
+	eventReconcileFailed      = "ReconcileFailed"
+	eventDeletionFailed       = "DeletionFailed"
+)
+
+// recordError records a warning event for an error that aborts the reconcile.
+// Conflicts are retried right away and not worth an event.
+func (r *TenantReconciler) recordError(tenant *neurallogv1.Tenant, reason, message string, err error) {
+	if errors.IsConflict(err) {
+		return
+	}
+	r.Recorder.Eventf(tenant, corev1.EventTypeWarning, reason, "%s: %v", message, err)
+}
+
+// recordTransitions records an event for each step condition and the phase
+// that changed since the start of the reconcile. Failed steps are recorded by
+// failStep, every time they fail.
+func (r *TenantReconciler) recordTransitions(tenant *neurallogv1.Tenant, previousPhase neurallogv1.TenantPhase, previousConditions []metav1.Condition) {
+	for _, conditionType := range stepConditions {
+		condition := meta.FindStatusCondition(tenant.Status.Conditions, conditionType)
+		if condition == nil || condition.Reason == neurallogv1.ReasonReconcileFailed {
+			continue
+		}
+		previous := meta.FindStatusCondition(previousConditions, conditionType)
+		if previous != nil && previous.Status == condition.Status && previous.Reason == condition.Reason {
+			continue
+		}
+		eventType := corev1.EventTypeNormal
+		if stepFailed(condition) {
+			eventType = corev1.EventTypeWarning
+		}
+		r.Recorder.Eventf(tenant, eventType, condition.Reason, "%s: %s", conditionType, condition.Message)
+	}
+
+	phase := tenant.Status.Phase
+	if phase == previousPhase {
+		return
+	}
+	eventType := corev1.EventTypeNormal
+	if phase == neurallogv1.TenantFailed {
+		eventType = corev1.EventTypeWarning
+	}
+	if previousPhase == "" {
+		r.Recorder.Eventf(tenant, eventType, eventPhaseChanged, "Tenant is %s", phase)
+		return
+	}
+	r.Recorder.Eventf(tenant, eventType, eventPhaseChanged, "Tenant phase changed from %s to %s", previousPhase, phase)
+}
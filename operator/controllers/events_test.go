@@ -0,0 +1,80 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+)
+
+var _ = Describe("Events", func() {
+	var (
+		tenant   *neurallogv1.Tenant
+		recorder *record.FakeRecorder
+		r        *TenantReconciler
+	)
+
+	BeforeEach(func() {
+		tenant = &neurallogv1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-tenant"},
+			Status:     neurallogv1.TenantStatus{Phase: neurallogv1.TenantProvisioning},
+		}
+		recorder = record.NewFakeRecorder(10)
+		r = &TenantReconciler{Recorder: recorder}
+	})
+
+	It("Should record the steps and phase that changed", func() {
+		setCondition(tenant, neurallogv1.ConditionServerReady, metav1.ConditionFalse, neurallogv1.ReasonProvisioning, "0/1 replicas are ready")
+		setCondition(tenant, neurallogv1.ConditionRedisReady, metav1.ConditionTrue, neurallogv1.ReasonReconciled, "1/1 replicas are ready")
+		previousConditions := append([]metav1.Condition(nil), tenant.Status.Conditions...)
+
+		setCondition(tenant, neurallogv1.ConditionServerReady, metav1.ConditionTrue, neurallogv1.ReasonReconciled, "1/1 replicas are ready")
+		setCondition(tenant, neurallogv1.ConditionRedisReady, metav1.ConditionTrue, neurallogv1.ReasonReconciled, "2/2 replicas are ready")
+		tenant.Status.Phase = neurallogv1.TenantRunning
+		r.recordTransitions(tenant, neurallogv1.TenantProvisioning, previousConditions)
+
+		Expect(recorder.Events).To(Receive(Equal("Normal Reconciled ServerReady: 1/1 replicas are ready")))
+		Expect(recorder.Events).To(Receive(Equal("Normal PhaseChanged Tenant phase changed from Provisioning to Running")))
+		Expect(recorder.Events).NotTo(Receive())
+	})
+
+	It("Should leave failed steps to failStep", func() {
+		setCondition(tenant, neurallogv1.ConditionRedisReady, metav1.ConditionFalse, neurallogv1.ReasonReconcileFailed, "connection refused")
+		tenant.Status.Phase = neurallogv1.TenantFailed
+		r.recordTransitions(tenant, neurallogv1.TenantProvisioning, nil)
+
+		Expect(recorder.Events).To(Receive(Equal("Warning PhaseChanged Tenant phase changed from Provisioning to Failed")))
+		Expect(recorder.Events).NotTo(Receive())
+	})
+
+	It("Should not record conflicts", func() {
+		conflict := apierrors.NewConflict(schema.GroupResource{Group: "neurallog.io", Resource: "tenants"}, tenant.Name, errors.New("modified"))
+		r.recordError(tenant, eventReconcileFailed, "Failed to update status", conflict)
+		Expect(recorder.Events).NotTo(Receive())
+
+		r.recordError(tenant, eventReconcileFailed, "Failed to update status", errors.New("timeout"))
+		Expect(recorder.Events).To(Receive(Equal("Warning ReconcileFailed Failed to update status: timeout")))
+	})
+})
@@ -0,0 +1,315 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+)
+
+// serverTLSSecretName is the Secret in the tenant namespace holding the
+// certificate of the exposed server
+const serverTLSSecretName = "neurallog-server-tls"
+
+// httpRouteGVK is the Gateway API HTTPRoute. The operator doesn't depend on
+// the Gateway API types, so HTTPRoutes are handled as unstructured objects.
+var httpRouteGVK = schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "HTTPRoute"}
+
+// reconcileExposure creates or updates the Ingress or HTTPRoute exposing the
+// tenant's server, and publishes its URL in the status. Everything is removed
+// when exposure is not configured.
+func (r *TenantReconciler) reconcileExposure(ctx context.Context, tenant *neurallogv1.Tenant) error {
+	logger := log.FromContext(ctx)
+
+	exposure := tenant.Spec.Exposure
+	if exposure == nil {
+		tenant.Status.URL = ""
+		if err := r.deleteIngress(ctx, tenant); err != nil {
+			return err
+		}
+		if err := r.deleteHTTPRoute(ctx, tenant); err != nil {
+			return err
+		}
+		return r.deleteServerTLSSecret(ctx, tenant)
+	}
+
+	host := exposureHostname(tenant)
+	if host == "" {
+		return fmt.Errorf("exposure requires a hostname or registry.baseDomain")
+	}
+
+	// Copy a user-supplied certificate into the tenant namespace
+	if tls := exposure.TLS; tls != nil && tls.SecretRef != nil {
+		if err := r.reconcileServerTLSSecret(ctx, tenant, *tls.SecretRef); err != nil {
+			return err
+		}
+	} else if err := r.deleteServerTLSSecret(ctx, tenant); err != nil {
+		return err
+	}
+
+	scheme := "http"
+	if exposure.Type == neurallogv1.ExposureHTTPRoute {
+		route := serverHTTPRoute(tenant, host)
+		if err := controllerutil.SetControllerReference(tenant, route, r.Scheme); err != nil {
+			logger.Error(err, "Failed to set owner reference on Server HTTPRoute")
+			return err
+		}
+		if err := r.apply(ctx, route); err != nil {
+			logger.Error(err, "Failed to apply Server HTTPRoute")
+			return err
+		}
+		logger.Info("Applied Server HTTPRoute", "httpRoute", route.GetName())
+		if err := r.deleteIngress(ctx, tenant); err != nil {
+			return err
+		}
+
+		// The Gateway listener terminates TLS
+		scheme = "https"
+	} else {
+		ingress := serverIngress(tenant, host)
+		if err := controllerutil.SetControllerReference(tenant, ingress, r.Scheme); err != nil {
+			logger.Error(err, "Failed to set owner reference on Server Ingress")
+			return err
+		}
+		if err := r.apply(ctx, ingress); err != nil {
+			logger.Error(err, "Failed to apply Server Ingress")
+			return err
+		}
+		logger.Info("Applied Server Ingress", "ingress", ingress.Name)
+		if err := r.deleteHTTPRoute(ctx, tenant); err != nil {
+			return err
+		}
+
+		if exposure.TLS != nil {
+			scheme = "https"
+		}
+	}
+
+	tenant.Status.URL = fmt.Sprintf("%s://%s", scheme, host)
+	return nil
+}
+
+// exposureHostname returns the hostname the tenant's server is exposed at
+func exposureHostname(tenant *neurallogv1.Tenant) string {
+	if hostname := tenant.Spec.Exposure.Hostname; hostname != "" {
+		return hostname
+	}
+	if baseDomain := registrySpec(tenant).BaseDomain; baseDomain != "" {
+		return fmt.Sprintf("%s.%s", tenant.Name, baseDomain)
+	}
+	return ""
+}
+
+// serverExposureLabels returns the labels of the objects exposing the server
+func serverExposureLabels(tenant *neurallogv1.Tenant) map[string]string {
+	return map[string]string{
+		"app":                    "neurallog-server",
+		"neurallog.io/tenant":    tenant.Name,
+		"neurallog.io/component": "server",
+	}
+}
+
+// serverIngress returns the Ingress routing the hostname to the server Service
+func serverIngress(tenant *neurallogv1.Tenant, host string) *networkingv1.Ingress {
+	exposure := tenant.Spec.Exposure
+
+	annotations := map[string]string{}
+	for key, value := range exposure.Annotations {
+		annotations[key] = value
+	}
+
+	pathType := networkingv1.PathTypePrefix
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "neurallog-server",
+			Namespace:   tenant.Status.Namespace,
+			Labels:      serverExposureLabels(tenant),
+			Annotations: annotations,
+		},
+		Spec: networkingv1.IngressSpec{
+			IngressClassName: exposure.IngressClassName,
+			Rules: []networkingv1.IngressRule{
+				{
+					Host: host,
+					IngressRuleValue: networkingv1.IngressRuleValue{
+						HTTP: &networkingv1.HTTPIngressRuleValue{
+							Paths: []networkingv1.HTTPIngressPath{
+								{
+									Path:     "/",
+									PathType: &pathType,
+									Backend: networkingv1.IngressBackend{
+										Service: &networkingv1.IngressServiceBackend{
+											Name: "neurallog-server",
+											Port: networkingv1.ServiceBackendPort{Name: "http"},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if tls := exposure.TLS; tls != nil {
+		ingress.Spec.TLS = []networkingv1.IngressTLS{{Hosts: []string{host}, SecretName: serverTLSSecretName}}
+
+		// cert-manager's ingress-shim issues the certificate into the Secret
+		if issuer := tls.IssuerRef; issuer != nil {
+			if issuer.Kind == "Issuer" {
+				annotations["cert-manager.io/issuer"] = issuer.Name
+			} else {
+				annotations["cert-manager.io/cluster-issuer"] = issuer.Name
+			}
+		}
+	}
+	return ingress
+}
+
+// serverHTTPRoute returns the HTTPRoute attaching the hostname to the Gateway
+func serverHTTPRoute(tenant *neurallogv1.Tenant, host string) *unstructured.Unstructured {
+	exposure := tenant.Spec.Exposure
+
+	parentRef := map[string]interface{}{
+		"name":      exposure.GatewayRef.Name,
+		"namespace": exposure.GatewayRef.Namespace,
+	}
+	if exposure.GatewayRef.SectionName != "" {
+		parentRef["sectionName"] = exposure.GatewayRef.SectionName
+	}
+
+	route := &unstructured.Unstructured{}
+	route.SetGroupVersionKind(httpRouteGVK)
+	route.SetName("neurallog-server")
+	route.SetNamespace(tenant.Status.Namespace)
+	route.SetLabels(serverExposureLabels(tenant))
+	if len(exposure.Annotations) > 0 {
+		route.SetAnnotations(exposure.Annotations)
+	}
+	route.Object["spec"] = map[string]interface{}{
+		"parentRefs": []interface{}{parentRef},
+		"hostnames":  []interface{}{host},
+		"rules": []interface{}{
+			map[string]interface{}{
+				"backendRefs": []interface{}{
+					map[string]interface{}{
+						"name": "neurallog-server",
+						"port": int64(3030),
+					},
+				},
+			},
+		},
+	}
+	return route
+}
+
+// reconcileServerTLSSecret copies the user-supplied certificate into the tenant namespace
+func (r *TenantReconciler) reconcileServerTLSSecret(ctx context.Context, tenant *neurallogv1.Tenant, ref neurallogv1.SecretReference) error {
+	logger := log.FromContext(ctx)
+
+	source, err := r.getReferencedSecret(ctx, tenant, "TLS", ref)
+	if err != nil {
+		return err
+	}
+	for _, key := range []string{corev1.TLSCertKey, corev1.TLSPrivateKeyKey} {
+		if len(source.Data[key]) == 0 {
+			return fmt.Errorf("TLS Secret %s/%s has no %q", ref.Namespace, ref.Name, key)
+		}
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      serverTLSSecretName,
+			Namespace: tenant.Status.Namespace,
+			Labels: map[string]string{
+				"neurallog.io/tenant":     tenant.Name,
+				"neurallog.io/managed-by": "tenant-operator",
+			},
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       source.Data[corev1.TLSCertKey],
+			corev1.TLSPrivateKeyKey: source.Data[corev1.TLSPrivateKeyKey],
+		},
+	}
+	if err := controllerutil.SetControllerReference(tenant, secret, r.Scheme); err != nil {
+		logger.Error(err, "Failed to set owner reference on Server TLS Secret")
+		return err
+	}
+	if err := r.apply(ctx, secret); err != nil {
+		logger.Error(err, "Failed to apply Server TLS Secret")
+		return err
+	}
+	logger.Info("Applied Server TLS Secret", "secret", secret.Name)
+	return nil
+}
+
+// deleteServerTLSSecret removes a copied certificate. A Secret issued by
+// cert-manager under the same name is left alone.
+func (r *TenantReconciler) deleteServerTLSSecret(ctx context.Context, tenant *neurallogv1.Tenant) error {
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, client.ObjectKey{Name: serverTLSSecretName, Namespace: tenant.Status.Namespace}, secret); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if secret.Labels["neurallog.io/managed-by"] != "tenant-operator" {
+		return nil
+	}
+	if err := client.IgnoreNotFound(r.Delete(ctx, secret)); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to delete Server TLS Secret")
+		return err
+	}
+	return nil
+}
+
+// deleteIngress removes the server Ingress
+func (r *TenantReconciler) deleteIngress(ctx context.Context, tenant *neurallogv1.Tenant) error {
+	ingress := &networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: "neurallog-server", Namespace: tenant.Status.Namespace}}
+	if err := client.IgnoreNotFound(r.Delete(ctx, ingress)); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to delete Server Ingress")
+		return err
+	}
+	return nil
+}
+
+// deleteHTTPRoute removes the server HTTPRoute. Clusters without the Gateway
+// API have none to remove.
+func (r *TenantReconciler) deleteHTTPRoute(ctx context.Context, tenant *neurallogv1.Tenant) error {
+	route := &unstructured.Unstructured{}
+	route.SetGroupVersionKind(httpRouteGVK)
+	route.SetName("neurallog-server")
+	route.SetNamespace(tenant.Status.Namespace)
+	if err := r.Delete(ctx, route); err != nil && !meta.IsNoMatchError(err) {
+		if err := client.IgnoreNotFound(err); err != nil {
+			log.FromContext(ctx).Error(err, "Failed to delete Server HTTPRoute")
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+)
+
+var _ = Describe("Exposure", func() {
+	var tenant *neurallogv1.Tenant
+
+	BeforeEach(func() {
+		tenant = &neurallogv1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-tenant"},
+			Spec: neurallogv1.TenantSpec{
+				Registry: &neurallogv1.RegistrySpec{BaseDomain: "neurallog.example.com"},
+				Exposure: &neurallogv1.ExposureSpec{Type: neurallogv1.ExposureIngress},
+			},
+			Status: neurallogv1.TenantStatus{Namespace: "tenant-test-tenant"},
+		}
+	})
+
+	It("Should expose the server under the base domain without a hostname", func() {
+		Expect(exposureHostname(tenant)).To(Equal("test-tenant.neurallog.example.com"))
+
+		tenant.Spec.Exposure.Hostname = "logs.example.com"
+		Expect(exposureHostname(tenant)).To(Equal("logs.example.com"))
+	})
+
+	It("Should request a certificate from the issuer for the Ingress", func() {
+		tenant.Spec.Exposure.Annotations = map[string]string{"nginx.ingress.kubernetes.io/proxy-body-size": "10m"}
+		tenant.Spec.Exposure.TLS = &neurallogv1.ExposureTLS{
+			IssuerRef: &neurallogv1.IssuerReference{Name: "letsencrypt", Kind: "ClusterIssuer"},
+		}
+
+		ingress := serverIngress(tenant, "test-tenant.neurallog.example.com")
+		Expect(ingress.Annotations).To(HaveKeyWithValue("cert-manager.io/cluster-issuer", "letsencrypt"))
+		Expect(ingress.Annotations).To(HaveKeyWithValue("nginx.ingress.kubernetes.io/proxy-body-size", "10m"))
+		Expect(tenant.Spec.Exposure.Annotations).NotTo(HaveKey("cert-manager.io/cluster-issuer"))
+		Expect(ingress.Spec.TLS).To(HaveLen(1))
+		Expect(ingress.Spec.TLS[0].SecretName).To(Equal(serverTLSSecretName))
+		Expect(ingress.Spec.Rules[0].Host).To(Equal("test-tenant.neurallog.example.com"))
+		Expect(ingress.Spec.Rules[0].HTTP.Paths[0].Backend.Service.Name).To(Equal("neurallog-server"))
+	})
+
+	It("Should attach the HTTPRoute to the Gateway", func() {
+		tenant.Spec.Exposure.Type = neurallogv1.ExposureHTTPRoute
+		tenant.Spec.Exposure.GatewayRef = &neurallogv1.GatewayReference{Name: "public", Namespace: "gateways", SectionName: "https"}
+
+		route := serverHTTPRoute(tenant, "test-tenant.neurallog.example.com")
+		Expect(route.GroupVersionKind()).To(Equal(httpRouteGVK))
+		parentRefs, _, err := unstructured.NestedSlice(route.Object, "spec", "parentRefs")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parentRefs).To(ConsistOf(map[string]interface{}{"name": "public", "namespace": "gateways", "sectionName": "https"}))
+		hostnames, _, err := unstructured.NestedStringSlice(route.Object, "spec", "hostnames")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hostnames).To(ConsistOf("test-tenant.neurallog.example.com"))
+	})
+
+	It("Should remove the Ingress and copied certificate when exposure is turned off", func() {
+		ingress := &networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: "neurallog-server", Namespace: "tenant-test-tenant"}}
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name:      serverTLSSecretName,
+			Namespace: "tenant-test-tenant",
+			Labels:    map[string]string{"neurallog.io/managed-by": "tenant-operator"},
+		}}
+		r := &TenantReconciler{Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(ingress, secret).Build()}
+
+		tenant.Spec.Exposure = nil
+		tenant.Status.URL = "http://test-tenant.neurallog.example.com"
+		Expect(r.reconcileExposure(context.Background(), tenant)).To(Succeed())
+		Expect(tenant.Status.URL).To(BeEmpty())
+		for _, obj := range []client.Object{ingress, secret} {
+			err := r.Get(context.Background(), client.ObjectKeyFromObject(obj), obj)
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		}
+	})
+
+	It("Should refuse a TLS Secret outside the tenant and operator namespaces", func() {
+		source := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "logs-tls", Namespace: "certificates"},
+			Data:       map[string][]byte{corev1.TLSCertKey: []byte("cert"), corev1.TLSPrivateKeyKey: []byte("key")},
+		}
+		r := &TenantReconciler{Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(source).Build()}
+
+		err := r.reconcileServerTLSSecret(context.Background(), tenant, neurallogv1.SecretReference{Name: "logs-tls", Namespace: "certificates"})
+		Expect(err).To(MatchError(ContainSubstring("not in the tenant namespace or the operator namespace")))
+		err = r.Get(context.Background(), client.ObjectKey{Name: serverTLSSecretName, Namespace: "tenant-test-tenant"}, &corev1.Secret{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("Should keep a certificate issued by cert-manager", func() {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: serverTLSSecretName, Namespace: "tenant-test-tenant"}}
+		r := &TenantReconciler{Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(secret).Build()}
+
+		Expect(r.deleteServerTLSSecret(context.Background(), tenant)).To(Succeed())
+		Expect(r.Get(context.Background(), client.ObjectKeyFromObject(secret), secret)).To(Succeed())
+	})
+})
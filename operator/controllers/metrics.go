@@ -0,0 +1,147 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+)
+
+var (
+	// authMissingTenants is the number of Tenants missing from the Auth service at the last sync
+	authMissingTenants = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "neurallog_auth_missing_tenants",
+		Help: "Number of Tenants not registered with the Auth service at the last sync",
+	})
+
+	// authOrphanedTenants is the number of Auth service tenants without a Tenant at the last sync
+	authOrphanedTenants = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "neurallog_auth_orphaned_tenants",
+		Help: "Number of tenants in the Auth service without a Tenant resource at the last sync",
+	})
+
+	// authRequestErrors counts failed requests to the Auth service
+	authRequestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "neurallog_auth_request_errors_total",
+		Help: "Number of failed requests to the Auth service by operation and HTTP status code",
+	}, []string{"operation", "code"})
+
+	// tenantPhase is 1 for the current phase of each Tenant and 0 for the others
+	tenantPhase = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "neurallog_tenant_phase",
+		Help: "The current phase of the Tenant",
+	}, []string{"tenant", "phase"})
+
+	// tenantComponentReady is 1 for each component of a Tenant that is running
+	tenantComponentReady = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "neurallog_tenant_component_ready",
+		Help: "Whether a component of the Tenant is running",
+	}, []string{"tenant", "component"})
+
+	// reconcileStepDuration is the duration of the individual reconcile steps
+	reconcileStepDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "neurallog_tenant_reconcile_step_duration_seconds",
+		Help:    "Duration of the Tenant reconcile steps by step and result",
+		Buckets: prometheus.ExponentialBuckets(0.005, 2, 12),
+	}, []string{"step", "result"})
+)
+
+// tenantPhases are the phases exported for each Tenant
+var tenantPhases = []neurallogv1.TenantPhase{
+	neurallogv1.TenantPending,
+	neurallogv1.TenantProvisioning,
+	neurallogv1.TenantRunning,
+	neurallogv1.TenantFailed,
+	neurallogv1.TenantSuspended,
+	neurallogv1.TenantTerminating,
+}
+
+func init() {
+	metrics.Registry.MustRegister(
+		authMissingTenants,
+		authOrphanedTenants,
+		authRequestErrors,
+		tenantPhase,
+		tenantComponentReady,
+		reconcileStepDuration,
+	)
+}
+
+// countAuthError records a failed request to the Auth service. Requests that
+// got no response are counted with the code "none".
+func countAuthError(operation string, statusCode int) {
+	code := "none"
+	if statusCode != 0 {
+		code = strconv.Itoa(statusCode)
+	}
+	authRequestErrors.WithLabelValues(operation, code).Inc()
+}
+
+// runStep runs a reconcile step and records its duration and result
+func runStep(ctx context.Context, tenant *neurallogv1.Tenant, step string, reconcile func(context.Context, *neurallogv1.Tenant) error) error {
+	start := time.Now()
+	err := reconcile(ctx, tenant)
+	observeStep(step, start, err)
+	return err
+}
+
+// observeStep records the duration and result of a reconcile step started at start
+func observeStep(step string, start time.Time, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	reconcileStepDuration.WithLabelValues(step, result).Observe(time.Since(start).Seconds())
+}
+
+// recordTenantMetrics exports the phase and component readiness of the tenant
+func recordTenantMetrics(tenant *neurallogv1.Tenant) {
+	for _, phase := range tenantPhases {
+		value := 0.0
+		if tenant.Status.Phase == phase {
+			value = 1
+		}
+		tenantPhase.WithLabelValues(tenant.Name, string(phase)).Set(value)
+	}
+
+	for _, component := range []struct {
+		name  string
+		phase neurallogv1.ComponentPhase
+	}{
+		{"redis", tenant.Status.RedisStatus.Phase},
+		{"server", tenant.Status.ServerStatus.Phase},
+		{"registry", tenant.Status.RegistryStatus.Phase},
+	} {
+		value := 0.0
+		if component.phase == neurallogv1.ComponentRunning {
+			value = 1
+		}
+		tenantComponentReady.WithLabelValues(tenant.Name, component.name).Set(value)
+	}
+}
+
+// forgetTenantMetrics removes the metrics of a deleted tenant
+func forgetTenantMetrics(name string) {
+	tenantPhase.DeletePartialMatch(prometheus.Labels{"tenant": name})
+	tenantComponentReady.DeletePartialMatch(prometheus.Labels{"tenant": name})
+}
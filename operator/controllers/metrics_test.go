@@ -0,0 +1,58 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+)
+
+var _ = Describe("Metrics", func() {
+	It("Should export the phase and component readiness of a tenant", func() {
+		tenant := &neurallogv1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{Name: "metrics-tenant"},
+			Status: neurallogv1.TenantStatus{
+				Phase:        neurallogv1.TenantProvisioning,
+				ServerStatus: neurallogv1.ComponentStatus{Phase: neurallogv1.ComponentRunning},
+			},
+		}
+		phases, components := testutil.CollectAndCount(tenantPhase), testutil.CollectAndCount(tenantComponentReady)
+		recordTenantMetrics(tenant)
+
+		Expect(testutil.ToFloat64(tenantPhase.WithLabelValues("metrics-tenant", "Provisioning"))).To(Equal(1.0))
+		Expect(testutil.ToFloat64(tenantPhase.WithLabelValues("metrics-tenant", "Running"))).To(Equal(0.0))
+		Expect(testutil.ToFloat64(tenantComponentReady.WithLabelValues("metrics-tenant", "server"))).To(Equal(1.0))
+		Expect(testutil.ToFloat64(tenantComponentReady.WithLabelValues("metrics-tenant", "redis"))).To(Equal(0.0))
+
+		forgetTenantMetrics("metrics-tenant")
+		Expect(testutil.CollectAndCount(tenantPhase)).To(Equal(phases))
+		Expect(testutil.CollectAndCount(tenantComponentReady)).To(Equal(components))
+	})
+
+	It("Should record the result of reconcile steps", func() {
+		failed := func(context.Context, *neurallogv1.Tenant) error { return errors.New("failed") }
+		Expect(runStep(context.Background(), &neurallogv1.Tenant{}, "test", failed)).NotTo(Succeed())
+		Expect(testutil.CollectAndCount(reconcileStepDuration, "neurallog_tenant_reconcile_step_duration_seconds")).To(BeNumerically(">", 0))
+	})
+})
@@ -0,0 +1,215 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+)
+
+// namespaceNotManagedRetryInterval is how often a tenant whose namespace is
+// refused checks the namespace again. Changes to unmanaged namespaces don't
+// trigger a reconcile.
+const namespaceNotManagedRetryInterval = time.Minute
+
+// namespaceNotManagedError is returned when the tenant namespace already
+// exists and the operator may not take it over
+type namespaceNotManagedError struct {
+	message string
+}
+
+func (e *namespaceNotManagedError) Error() string {
+	return e.message
+}
+
+// namespaceSpec returns the namespace settings of the tenant, empty if unset
+func namespaceSpec(tenant *neurallogv1.Tenant) neurallogv1.NamespaceSpec {
+	if tenant.Spec.Namespace == nil {
+		return neurallogv1.NamespaceSpec{}
+	}
+	return *tenant.Spec.Namespace
+}
+
+// reconcileNamespace creates or updates the namespace for the tenant. A
+// namespace that already exists is only taken over if it belongs to the tenant
+// or the tenant opts in to adopting it. An adopted namespace is recorded in
+// the status and gets no owner reference, so it isn't deleted with the tenant.
+func (r *TenantReconciler) reconcileNamespace(ctx context.Context, tenant *neurallogv1.Tenant) (*corev1.Namespace, error) {
+	logger := log.FromContext(ctx)
+	spec := namespaceSpec(tenant)
+
+	// The namespace recorded in the status is the tenant's, whatever the spec says
+	namespaceName := tenant.Status.Namespace
+	created, adopted := false, false
+	if namespaceName == "" {
+		namespaceName = tenant.NamespaceName()
+		existing := &corev1.Namespace{}
+		err := r.Get(ctx, client.ObjectKey{Name: namespaceName}, existing)
+		switch {
+		case errors.IsNotFound(err):
+			created = true
+			tenant.Status.NamespaceAdopted = false
+		case err != nil:
+			logger.Error(err, "Failed to get namespace")
+			return nil, err
+		default:
+			if err := checkNamespaceOwner(tenant, existing, spec.Adopt); err != nil {
+				return nil, err
+			}
+			adopted = existing.Labels["neurallog.io/managed-by"] != "tenant-operator"
+
+			// A namespace the operator created for the tenant is owned by it
+			tenant.Status.NamespaceAdopted = !ownedByTenant(tenant, existing)
+		}
+	}
+
+	// The operator's labels go last so the tenant can't override them
+	labels := map[string]string{}
+	for key, value := range spec.Labels {
+		labels[key] = value
+	}
+	labels["neurallog.io/tenant"] = tenant.Name
+	labels["neurallog.io/managed-by"] = "tenant-operator"
+
+	// Define namespace
+	namespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        namespaceName,
+			Labels:      labels,
+			Annotations: spec.Annotations,
+		},
+	}
+
+	// Set owner reference
+	if !tenant.Status.NamespaceAdopted {
+		if err := controllerutil.SetOwnerReference(tenant, namespace, r.Scheme); err != nil {
+			logger.Error(err, "Failed to set owner reference on namespace")
+			return nil, err
+		}
+	}
+
+	// Apply the namespace
+	if err := r.apply(ctx, namespace); err != nil {
+		logger.Error(err, "Failed to apply namespace")
+		return nil, err
+	}
+	logger.Info("Applied namespace", "namespace", namespaceName)
+
+	if created {
+		r.Recorder.Eventf(tenant, corev1.EventTypeNormal, eventNamespaceCreated, "Created namespace %s", namespaceName)
+	}
+	if adopted {
+		r.Recorder.Eventf(tenant, corev1.EventTypeNormal, eventNamespaceAdopted, "Adopted namespace %s", namespaceName)
+	}
+
+	return namespace, nil
+}
+
+// ownedByTenant reports whether the namespace has an owner reference to the tenant
+func ownedByTenant(tenant *neurallogv1.Tenant, namespace *corev1.Namespace) bool {
+	for _, ref := range namespace.OwnerReferences {
+		if ref.UID == tenant.UID {
+			return true
+		}
+	}
+	return false
+}
+
+// releaseNamespace hands an adopted namespace back when the tenant is deleted.
+// The namespace and everything in it are kept, and only the labels and
+// annotations the operator set and any owner reference to the tenant are
+// removed.
+func (r *TenantReconciler) releaseNamespace(ctx context.Context, tenant *neurallogv1.Tenant) error {
+	namespace := &corev1.Namespace{}
+	if err := r.Get(ctx, client.ObjectKey{Name: tenant.Status.Namespace}, namespace); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	spec := namespaceSpec(tenant)
+	patch := client.MergeFrom(namespace.DeepCopy())
+	delete(namespace.Labels, "neurallog.io/tenant")
+	delete(namespace.Labels, "neurallog.io/managed-by")
+	for key := range spec.Labels {
+		delete(namespace.Labels, key)
+	}
+	for key := range spec.Annotations {
+		delete(namespace.Annotations, key)
+	}
+	var owners []metav1.OwnerReference
+	for _, ref := range namespace.OwnerReferences {
+		if ref.UID != tenant.UID {
+			owners = append(owners, ref)
+		}
+	}
+	namespace.OwnerReferences = owners
+	return r.Patch(ctx, namespace, patch)
+}
+
+// checkNamespaceOwner returns a namespaceNotManagedError unless the existing
+// namespace belongs to the tenant or is unmanaged and may be adopted. The
+// namespace of another tenant, of Kubernetes or of the operator is never
+// taken over.
+func checkNamespaceOwner(tenant *neurallogv1.Tenant, namespace *corev1.Namespace, adopt bool) error {
+	if neurallogv1.ReservedNamespace(namespace.Name) {
+		return &namespaceNotManagedError{
+			message: fmt.Sprintf("Namespace %s is reserved for Kubernetes or the operator", namespace.Name),
+		}
+	}
+	if namespace.Labels["neurallog.io/managed-by"] == "tenant-operator" {
+		owner := namespace.Labels["neurallog.io/tenant"]
+		if owner == tenant.Name {
+			return nil
+		}
+		return &namespaceNotManagedError{
+			message: fmt.Sprintf("Namespace %s belongs to tenant %s", namespace.Name, owner),
+		}
+	}
+	if adopt {
+		return nil
+	}
+	return &namespaceNotManagedError{
+		message: fmt.Sprintf("Namespace %s already exists and is not managed by the operator; set spec.namespace.adopt to take it over", namespace.Name),
+	}
+}
+
+// refuseNamespace records that the tenant namespace can't be taken over. The
+// tenant fails without an error, since retrying right away won't help, and
+// checks again after namespaceNotManagedRetryInterval.
+func (r *TenantReconciler) refuseNamespace(ctx context.Context, tenant *neurallogv1.Tenant, err *namespaceNotManagedError) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	logger.Info("Refusing to take over namespace", "reason", err.Error())
+
+	setCondition(tenant, neurallogv1.ConditionNamespaceReady, metav1.ConditionFalse, neurallogv1.ReasonNamespaceNotManaged, err.Error())
+	updatePhase(tenant)
+	if err := r.updateStatus(ctx, tenant); err != nil {
+		logger.Error(err, "Failed to update Tenant status")
+		r.recordError(tenant, eventReconcileFailed, "Failed to update status", err)
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: namespaceNotManagedRetryInterval}, nil
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+)
+
+var _ = Describe("Namespace reconciler", func() {
+	var (
+		tenant   *neurallogv1.Tenant
+		existing *corev1.Namespace
+		recorder *record.FakeRecorder
+		r        *TenantReconciler
+	)
+
+	BeforeEach(func() {
+		tenant = &neurallogv1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-tenant", UID: "test-tenant-uid"},
+			Spec: neurallogv1.TenantSpec{
+				Namespace: &neurallogv1.NamespaceSpec{Name: "logging"},
+			},
+			Status: neurallogv1.TenantStatus{Phase: neurallogv1.TenantPending},
+		}
+		existing = &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: "logging", Labels: map[string]string{"team": "observability"}},
+		}
+
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(neurallogv1.AddToScheme(scheme)).To(Succeed())
+		recorder = record.NewFakeRecorder(10)
+		r = &TenantReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(tenant, existing).WithStatusSubresource(tenant).
+				WithInterceptorFuncs(interceptor.Funcs{Patch: applyWithUpdate}).Build(),
+			Scheme:   scheme,
+			Recorder: recorder,
+		}
+		Expect(r.Get(context.Background(), client.ObjectKeyFromObject(tenant), tenant)).To(Succeed())
+	})
+
+	It("Should refuse a namespace the operator doesn't manage", func() {
+		_, err := r.reconcileNamespace(context.Background(), tenant)
+		Expect(err).To(BeAssignableToTypeOf(&namespaceNotManagedError{}))
+		Expect(err.Error()).To(ContainSubstring("spec.namespace.adopt"))
+
+		result, err := r.refuseNamespace(context.Background(), tenant, err.(*namespaceNotManagedError))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(namespaceNotManagedRetryInterval))
+
+		condition := meta.FindStatusCondition(tenant.Status.Conditions, neurallogv1.ConditionNamespaceReady)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(neurallogv1.ReasonNamespaceNotManaged))
+		Expect(tenant.Status.Phase).To(Equal(neurallogv1.TenantFailed))
+		Expect(tenant.Status.Namespace).To(BeEmpty())
+
+		// The namespace is left as it was
+		namespace := &corev1.Namespace{}
+		Expect(r.Get(context.Background(), client.ObjectKeyFromObject(existing), namespace)).To(Succeed())
+		Expect(namespace.Labels).To(Equal(map[string]string{"team": "observability"}))
+	})
+
+	It("Should adopt an unmanaged namespace only when asked to", func() {
+		Expect(checkNamespaceOwner(tenant, existing, false)).NotTo(Succeed())
+		Expect(checkNamespaceOwner(tenant, existing, true)).To(Succeed())
+	})
+
+	It("Should adopt a namespace without owning it and release it on deletion", func() {
+		tenant.Spec.Namespace.Adopt = true
+		tenant.Spec.Namespace.Labels = map[string]string{"pod-security.kubernetes.io/enforce": "restricted"}
+		tenant.Spec.Namespace.Annotations = map[string]string{"cost-center": "logging"}
+		namespace, err := r.reconcileNamespace(context.Background(), tenant)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tenant.Status.NamespaceAdopted).To(BeTrue())
+		Expect(namespace.OwnerReferences).To(BeEmpty())
+		Expect(recorder.Events).To(Receive(ContainSubstring("Adopted namespace logging")))
+
+		// The adoption is recorded when the tenant first takes the namespace
+		tenant.Status.Namespace = namespace.Name
+		Expect(r.Status().Update(context.Background(), tenant)).To(Succeed())
+		_, err = r.reconcileNamespace(context.Background(), tenant)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Get(context.Background(), client.ObjectKeyFromObject(tenant), tenant)).To(Succeed())
+		Expect(tenant.Status.NamespaceAdopted).To(BeTrue())
+
+		Expect(r.releaseNamespace(context.Background(), tenant)).To(Succeed())
+		Expect(r.Get(context.Background(), client.ObjectKeyFromObject(existing), namespace)).To(Succeed())
+		Expect(namespace.Labels).NotTo(HaveKey("neurallog.io/tenant"))
+		Expect(namespace.Labels).NotTo(HaveKey("neurallog.io/managed-by"))
+		Expect(namespace.Labels).NotTo(HaveKey("pod-security.kubernetes.io/enforce"))
+		Expect(namespace.Annotations).NotTo(HaveKey("cost-center"))
+		Expect(namespace.OwnerReferences).To(BeEmpty())
+	})
+
+	It("Should own a namespace it creates", func() {
+		tenant.Spec.Namespace.Name = "tenant-logging"
+		namespace, err := r.reconcileNamespace(context.Background(), tenant)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tenant.Status.NamespaceAdopted).To(BeFalse())
+		Expect(namespace.OwnerReferences).To(HaveLen(1))
+		Expect(namespace.OwnerReferences[0].UID).To(Equal(tenant.UID))
+
+		// Taken over again before the status is saved, it is still the tenant's own
+		stored := &corev1.Namespace{}
+		Expect(r.Get(context.Background(), client.ObjectKey{Name: "tenant-logging"}, stored)).To(Succeed())
+		Expect(ownedByTenant(tenant, stored)).To(BeTrue())
+		_, err = r.reconcileNamespace(context.Background(), tenant)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tenant.Status.NamespaceAdopted).To(BeFalse())
+	})
+
+	It("Should never adopt a namespace of Kubernetes or the operator", func() {
+		defer func(namespace string) { neurallogv1.OperatorNamespace = namespace }(neurallogv1.OperatorNamespace)
+		neurallogv1.OperatorNamespace = "neurallog-system"
+
+		for _, name := range []string{"kube-system", "default", "neurallog-system"} {
+			err := checkNamespaceOwner(tenant, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}, true)
+			Expect(err).To(BeAssignableToTypeOf(&namespaceNotManagedError{}))
+			Expect(err.Error()).To(ContainSubstring("reserved"))
+		}
+	})
+
+	It("Should never take over the namespace of another tenant", func() {
+		existing.Labels = map[string]string{
+			"neurallog.io/tenant":     "other-tenant",
+			"neurallog.io/managed-by": "tenant-operator",
+		}
+		err := checkNamespaceOwner(tenant, existing, true)
+		Expect(err).To(BeAssignableToTypeOf(&namespaceNotManagedError{}))
+		Expect(err.Error()).To(ContainSubstring("other-tenant"))
+
+		existing.Labels["neurallog.io/tenant"] = tenant.Name
+		Expect(checkNamespaceOwner(tenant, existing, false)).To(Succeed())
+	})
+})
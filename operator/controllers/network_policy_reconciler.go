@@ -20,15 +20,14 @@ import (
 	"context"
 	"fmt"
 
-	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
-	corev1 "k8s.io/api/core/v1"
 	neurallogv1 "github.com/neurallog/operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 )
 
@@ -44,14 +43,9 @@ func (r *TenantReconciler) reconcileNetworkPolicies(ctx context.Context, tenant
 	}
 
 	// Check if network policies are enabled
-	enabled := true
-	if tenant.Spec.NetworkPolicy.Enabled != nil {
-		enabled = *tenant.Spec.NetworkPolicy.Enabled
-	}
-
-	if !enabled {
+	if !networkPoliciesEnabled(tenant) {
 		logger.Info("Network policies are disabled for this tenant", "tenant", tenant.Name)
-		return nil
+		return r.pruneNetworkPolicies(ctx, tenant, nil)
 	}
 
 	// Create default network policies
@@ -66,13 +60,84 @@ func (r *TenantReconciler) reconcileNetworkPolicies(ctx context.Context, tenant
 		return err
 	}
 
+	// Remove the policies of rules that were removed from the spec
+	if err := r.pruneNetworkPolicies(ctx, tenant, networkPolicyNames(tenant)); err != nil {
+		logger.Error(err, "Failed to remove stale network policies")
+		return err
+	}
+
 	return nil
 }
 
+// networkPolicyNames returns the names of the network policies the tenant's
+// spec asks for, including the one the Redis step creates for Sentinel
+func networkPolicyNames(tenant *neurallogv1.Tenant) map[string]bool {
+	networkPolicy := networkPolicySpec(tenant)
+	names := map[string]bool{
+		"default-deny-all":       true,
+		"allow-internal-traffic": true,
+		"allow-api-access":       true,
+	}
+	if redisSentinelEnabled(tenant) {
+		names[redisSentinelNetworkPolicyName] = true
+	}
+	for i := range networkPolicy.IngressRules {
+		names[fmt.Sprintf("custom-ingress-%d", i)] = true
+	}
+	for i := range networkPolicy.EgressRules {
+		names[fmt.Sprintf("custom-egress-%d", i)] = true
+	}
+	return names
+}
+
+// pruneNetworkPolicies deletes the tenant's network policies that are not in
+// keep. Policies the operator didn't create are left alone.
+func (r *TenantReconciler) pruneNetworkPolicies(ctx context.Context, tenant *neurallogv1.Tenant, keep map[string]bool) error {
+	logger := log.FromContext(ctx)
+
+	policies := &networkingv1.NetworkPolicyList{}
+	if err := r.List(ctx, policies, client.InNamespace(tenant.Status.Namespace), client.MatchingLabels{
+		"neurallog.io/tenant":    tenant.Name,
+		"neurallog.io/component": "network-policy",
+	}); err != nil {
+		return err
+	}
+	for i := range policies.Items {
+		policy := &policies.Items[i]
+		if keep[policy.Name] || !metav1.IsControlledBy(policy, tenant) {
+			continue
+		}
+		if err := client.IgnoreNotFound(r.Delete(ctx, policy)); err != nil {
+			logger.Error(err, "Failed to delete network policy", "policy", policy.Name)
+			return err
+		}
+		logger.Info("Deleted network policy", "policy", policy.Name)
+	}
+	return nil
+}
+
+// networkPolicySpec returns the tenant's network policy configuration, empty if unset
+func networkPolicySpec(tenant *neurallogv1.Tenant) neurallogv1.NetworkPolicySpec {
+	if tenant.Spec.NetworkPolicy == nil {
+		return neurallogv1.NetworkPolicySpec{}
+	}
+	return *tenant.Spec.NetworkPolicy
+}
+
+// networkPoliciesEnabled reports whether network policies should be created for the tenant
+func networkPoliciesEnabled(tenant *neurallogv1.Tenant) bool {
+	networkPolicy := networkPolicySpec(tenant)
+	if networkPolicy.Enabled != nil {
+		return *networkPolicy.Enabled
+	}
+	return true
+}
+
 // reconcileDefaultNetworkPolicies creates or updates default network policies
 func (r *TenantReconciler) reconcileDefaultNetworkPolicies(ctx context.Context, tenant *neurallogv1.Tenant) error {
 	logger := log.FromContext(ctx)
 	namespaceName := tenant.Status.Namespace
+	networkPolicy := networkPolicySpec(tenant)
 
 	// Create default deny all ingress policy
 	denyAllPolicy := &networkingv1.NetworkPolicy{
@@ -99,30 +164,12 @@ func (r *TenantReconciler) reconcileDefaultNetworkPolicies(ctx context.Context,
 		return err
 	}
 
-	// Create or update the network policy
-	existingPolicy := &networkingv1.NetworkPolicy{}
-	err := r.Get(ctx, client.ObjectKey{Name: denyAllPolicy.Name, Namespace: denyAllPolicy.Namespace}, existingPolicy)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			// Create network policy
-			if err := r.Create(ctx, denyAllPolicy); err != nil {
-				logger.Error(err, "Failed to create default deny network policy")
-				return err
-			}
-			logger.Info("Created default deny network policy", "policy", denyAllPolicy.Name)
-		} else {
-			logger.Error(err, "Failed to get default deny network policy")
-			return err
-		}
-	} else {
-		// Update network policy if it exists
-		existingPolicy.Spec = denyAllPolicy.Spec
-		if err := r.Update(ctx, existingPolicy); err != nil {
-			logger.Error(err, "Failed to update default deny network policy")
-			return err
-		}
-		logger.Info("Updated default deny network policy", "policy", existingPolicy.Name)
+	// Apply the network policy
+	if err := r.apply(ctx, denyAllPolicy); err != nil {
+		logger.Error(err, "Failed to apply default deny network policy")
+		return err
 	}
+	logger.Info("Applied default deny network policy", "policy", denyAllPolicy.Name)
 
 	// Create allow internal traffic policy
 	allowInternalPolicy := &networkingv1.NetworkPolicy{
@@ -158,30 +205,12 @@ func (r *TenantReconciler) reconcileDefaultNetworkPolicies(ctx context.Context,
 		return err
 	}
 
-	// Create or update the network policy
-	existingInternalPolicy := &networkingv1.NetworkPolicy{}
-	err = r.Get(ctx, client.ObjectKey{Name: allowInternalPolicy.Name, Namespace: allowInternalPolicy.Namespace}, existingInternalPolicy)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			// Create network policy
-			if err := r.Create(ctx, allowInternalPolicy); err != nil {
-				logger.Error(err, "Failed to create allow internal traffic network policy")
-				return err
-			}
-			logger.Info("Created allow internal traffic network policy", "policy", allowInternalPolicy.Name)
-		} else {
-			logger.Error(err, "Failed to get allow internal traffic network policy")
-			return err
-		}
-	} else {
-		// Update network policy if it exists
-		existingInternalPolicy.Spec = allowInternalPolicy.Spec
-		if err := r.Update(ctx, existingInternalPolicy); err != nil {
-			logger.Error(err, "Failed to update allow internal traffic network policy")
-			return err
-		}
-		logger.Info("Updated allow internal traffic network policy", "policy", existingInternalPolicy.Name)
+	// Apply the network policy
+	if err := r.apply(ctx, allowInternalPolicy); err != nil {
+		logger.Error(err, "Failed to apply allow internal traffic network policy")
+		return err
 	}
+	logger.Info("Applied allow internal traffic network policy", "policy", allowInternalPolicy.Name)
 
 	// Create allow API access policy
 	allowApiPolicy := &networkingv1.NetworkPolicy{
@@ -223,8 +252,8 @@ func (r *TenantReconciler) reconcileDefaultNetworkPolicies(ctx context.Context,
 	}
 
 	// Add allowed namespaces if specified
-	if len(tenant.Spec.NetworkPolicy.AllowedNamespaces) > 0 {
-		for _, ns := range tenant.Spec.NetworkPolicy.AllowedNamespaces {
+	if len(networkPolicy.AllowedNamespaces) > 0 {
+		for _, ns := range networkPolicy.AllowedNamespaces {
 			allowApiPolicy.Spec.Ingress[0].From = append(allowApiPolicy.Spec.Ingress[0].From, networkingv1.NetworkPolicyPeer{
 				NamespaceSelector: &metav1.LabelSelector{
 					MatchLabels: map[string]string{
@@ -241,30 +270,12 @@ func (r *TenantReconciler) reconcileDefaultNetworkPolicies(ctx context.Context,
 		return err
 	}
 
-	// Create or update the network policy
-	existingApiPolicy := &networkingv1.NetworkPolicy{}
-	err = r.Get(ctx, client.ObjectKey{Name: allowApiPolicy.Name, Namespace: allowApiPolicy.Namespace}, existingApiPolicy)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			// Create network policy
-			if err := r.Create(ctx, allowApiPolicy); err != nil {
-				logger.Error(err, "Failed to create allow API access network policy")
-				return err
-			}
-			logger.Info("Created allow API access network policy", "policy", allowApiPolicy.Name)
-		} else {
-			logger.Error(err, "Failed to get allow API access network policy")
-			return err
-		}
-	} else {
-		// Update network policy if it exists
-		existingApiPolicy.Spec = allowApiPolicy.Spec
-		if err := r.Update(ctx, existingApiPolicy); err != nil {
-			logger.Error(err, "Failed to update allow API access network policy")
-			return err
-		}
-		logger.Info("Updated allow API access network policy", "policy", existingApiPolicy.Name)
+	// Apply the network policy
+	if err := r.apply(ctx, allowApiPolicy); err != nil {
+		logger.Error(err, "Failed to apply allow API access network policy")
+		return err
 	}
+	logger.Info("Applied allow API access network policy", "policy", allowApiPolicy.Name)
 
 	return nil
 }
@@ -273,9 +284,10 @@ func (r *TenantReconciler) reconcileDefaultNetworkPolicies(ctx context.Context,
 func (r *TenantReconciler) reconcileCustomNetworkPolicies(ctx context.Context, tenant *neurallogv1.Tenant) error {
 	logger := log.FromContext(ctx)
 	namespaceName := tenant.Status.Namespace
+	networkPolicy := networkPolicySpec(tenant)
 
 	// Process custom ingress rules
-	for i, rule := range tenant.Spec.NetworkPolicy.IngressRules {
+	for i, rule := range networkPolicy.IngressRules {
 		policyName := fmt.Sprintf("custom-ingress-%d", i)
 
 		// Create network policy for the ingress rule
@@ -334,34 +346,16 @@ func (r *TenantReconciler) reconcileCustomNetworkPolicies(ctx context.Context, t
 			return err
 		}
 
-		// Create or update the network policy
-		existingPolicy := &networkingv1.NetworkPolicy{}
-		err := r.Get(ctx, client.ObjectKey{Name: ingressPolicy.Name, Namespace: ingressPolicy.Namespace}, existingPolicy)
-		if err != nil {
-			if errors.IsNotFound(err) {
-				// Create network policy
-				if err := r.Create(ctx, ingressPolicy); err != nil {
-					logger.Error(err, "Failed to create custom ingress network policy")
-					return err
-				}
-				logger.Info("Created custom ingress network policy", "policy", ingressPolicy.Name)
-			} else {
-				logger.Error(err, "Failed to get custom ingress network policy")
-				return err
-			}
-		} else {
-			// Update network policy if it exists
-			existingPolicy.Spec = ingressPolicy.Spec
-			if err := r.Update(ctx, existingPolicy); err != nil {
-				logger.Error(err, "Failed to update custom ingress network policy")
-				return err
-			}
-			logger.Info("Updated custom ingress network policy", "policy", existingPolicy.Name)
+		// Apply the network policy
+		if err := r.apply(ctx, ingressPolicy); err != nil {
+			logger.Error(err, "Failed to apply custom ingress network policy")
+			return err
 		}
+		logger.Info("Applied custom ingress network policy", "policy", ingressPolicy.Name)
 	}
 
 	// Process custom egress rules
-	for i, rule := range tenant.Spec.NetworkPolicy.EgressRules {
+	for i, rule := range networkPolicy.EgressRules {
 		policyName := fmt.Sprintf("custom-egress-%d", i)
 
 		// Create network policy for the egress rule
@@ -420,30 +414,12 @@ func (r *TenantReconciler) reconcileCustomNetworkPolicies(ctx context.Context, t
 			return err
 		}
 
-		// Create or update the network policy
-		existingPolicy := &networkingv1.NetworkPolicy{}
-		err := r.Get(ctx, client.ObjectKey{Name: egressPolicy.Name, Namespace: egressPolicy.Namespace}, existingPolicy)
-		if err != nil {
-			if errors.IsNotFound(err) {
-				// Create network policy
-				if err := r.Create(ctx, egressPolicy); err != nil {
-					logger.Error(err, "Failed to create custom egress network policy")
-					return err
-				}
-				logger.Info("Created custom egress network policy", "policy", egressPolicy.Name)
-			} else {
-				logger.Error(err, "Failed to get custom egress network policy")
-				return err
-			}
-		} else {
-			// Update network policy if it exists
-			existingPolicy.Spec = egressPolicy.Spec
-			if err := r.Update(ctx, existingPolicy); err != nil {
-				logger.Error(err, "Failed to update custom egress network policy")
-				return err
-			}
-			logger.Info("Updated custom egress network policy", "policy", existingPolicy.Name)
+		// Apply the network policy
+		if err := r.apply(ctx, egressPolicy); err != nil {
+			logger.Error(err, "Failed to apply custom egress network policy")
+			return err
 		}
+		logger.Info("Applied custom egress network policy", "policy", egressPolicy.Name)
 	}
 
 	return nil
@@ -0,0 +1,117 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+)
+
+var _ = Describe("Network policy reconciler", func() {
+	var (
+		tenant *neurallogv1.Tenant
+		r      *TenantReconciler
+	)
+
+	policyNames := func() []string {
+		policies := &networkingv1.NetworkPolicyList{}
+		Expect(r.List(context.Background(), policies, client.InNamespace("tenant-test-tenant"))).To(Succeed())
+		names := []string{}
+		for _, policy := range policies.Items {
+			names = append(names, policy.Name)
+		}
+		return names
+	}
+
+	BeforeEach(func() {
+		tenant = &neurallogv1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-tenant", UID: "test-tenant-uid"},
+			Spec: neurallogv1.TenantSpec{
+				NetworkPolicy: &neurallogv1.NetworkPolicySpec{
+					IngressRules: []neurallogv1.NetworkPolicyRule{
+						{From: map[string]string{"app": "collector"}},
+						{From: map[string]string{"app": "dashboard"}},
+					},
+					EgressRules: []neurallogv1.NetworkPolicyRule{
+						{To: map[string]string{"app": "exporter"}},
+					},
+				},
+			},
+			Status: neurallogv1.TenantStatus{Namespace: "tenant-test-tenant"},
+		}
+
+		// A policy the tenant's users created themselves
+		userPolicy := &networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "allow-monitoring", Namespace: "tenant-test-tenant"},
+		}
+
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(neurallogv1.AddToScheme(scheme)).To(Succeed())
+		r = &TenantReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(userPolicy).
+				WithInterceptorFuncs(interceptor.Funcs{Patch: applyWithUpdate}).Build(),
+			Scheme: scheme,
+		}
+		Expect(r.reconcileNetworkPolicies(context.Background(), tenant)).To(Succeed())
+		Expect(policyNames()).To(ConsistOf("default-deny-all", "allow-internal-traffic", "allow-api-access",
+			"custom-ingress-0", "custom-ingress-1", "custom-egress-0", "allow-monitoring"))
+	})
+
+	It("Should delete the policies of removed rules", func() {
+		tenant.Spec.NetworkPolicy.IngressRules = tenant.Spec.NetworkPolicy.IngressRules[:1]
+		tenant.Spec.NetworkPolicy.EgressRules = nil
+		Expect(r.reconcileNetworkPolicies(context.Background(), tenant)).To(Succeed())
+		Expect(policyNames()).To(ConsistOf("default-deny-all", "allow-internal-traffic", "allow-api-access",
+			"custom-ingress-0", "allow-monitoring"))
+	})
+
+	It("Should keep the Sentinel policy of a Sentinel tenant through a full reconcile", func() {
+		sentinelTenant := &neurallogv1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{Name: "ha-tenant", Finalizers: []string{"neurallog.io/finalizer"}},
+			Spec: neurallogv1.TenantSpec{
+				Redis: &neurallogv1.RedisSpec{Sentinel: &neurallogv1.SentinelSpec{}},
+			},
+			Status: neurallogv1.TenantStatus{Phase: neurallogv1.TenantPending, Namespace: "tenant-ha-tenant"},
+		}
+		r = newFakeTenantReconciler(sentinelTenant)
+
+		_, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(sentinelTenant)})
+		Expect(err).NotTo(HaveOccurred())
+		policy := &networkingv1.NetworkPolicy{}
+		Expect(r.Get(context.Background(), client.ObjectKey{Name: redisSentinelNetworkPolicyName, Namespace: "tenant-ha-tenant"}, policy)).To(Succeed())
+	})
+
+	It("Should delete every policy of the operator when network policies are disabled", func() {
+		enabled := false
+		tenant.Spec.NetworkPolicy.Enabled = &enabled
+		Expect(r.reconcileNetworkPolicies(context.Background(), tenant)).To(Succeed())
+		Expect(policyNames()).To(ConsistOf("allow-monitoring"))
+	})
+})
@@ -0,0 +1,219 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// OpenFGAClient manages tenant stores and authorization models in OpenFGA
+type OpenFGAClient interface {
+	// URL returns the base URL of the OpenFGA API
+	URL() string
+
+	// GetStore returns the store with the given ID
+	GetStore(ctx context.Context, storeID string) (*OpenFGAStore, error)
+
+	// FindStore returns the store with the given name, or nil if there is none
+	FindStore(ctx context.Context, name string) (*OpenFGAStore, error)
+
+	// CreateStore creates a store with the given name
+	CreateStore(ctx context.Context, name string) (*OpenFGAStore, error)
+
+	// WriteAuthorizationModel writes a new version of the store's authorization
+	// model and returns its ID
+	WriteAuthorizationModel(ctx context.Context, storeID string, model []byte) (string, error)
+}
+
+// OpenFGAStore is an OpenFGA store
+type OpenFGAStore struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// OpenFGAClientConfig defines how the operator connects to OpenFGA
+type OpenFGAClientConfig struct {
+	// URL is the base URL of the OpenFGA HTTP API
+	URL string
+
+	// Timeout is the timeout for a single request to OpenFGA
+	Timeout time.Duration
+
+	// TokenFile is the path to a file containing a preshared key
+	TokenFile string
+}
+
+// httpOpenFGAClient is the HTTP implementation of OpenFGAClient
+type httpOpenFGAClient struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewOpenFGAClient creates an OpenFGAClient from the given configuration
+func NewOpenFGAClient(cfg OpenFGAClientConfig) (OpenFGAClient, error) {
+	if _, err := url.Parse(cfg.URL); err != nil {
+		return nil, fmt.Errorf("invalid OpenFGA URL %q: %w", cfg.URL, err)
+	}
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+
+	// Read the preshared key if configured
+	token := ""
+	if cfg.TokenFile != "" {
+		data, err := os.ReadFile(cfg.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read OpenFGA token file: %w", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+
+	return &httpOpenFGAClient{
+		baseURL:    strings.TrimSuffix(cfg.URL, "/"),
+		token:      token,
+		httpClient: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// URL returns the base URL of the OpenFGA API
+func (c *httpOpenFGAClient) URL() string {
+	return c.baseURL
+}
+
+// do sends a request to OpenFGA and decodes the response into out if the
+// response has the expected status
+func (c *httpOpenFGAClient) do(ctx context.Context, method, path string, body io.Reader, expected int, out interface{}) error {
+	logger := log.FromContext(ctx)
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+
+	// Set headers
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		logger.Error(err, "Failed to connect to OpenFGA")
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Read the response body
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		logger.Error(err, "Failed to read response from OpenFGA")
+		return err
+	}
+
+	// Check the response status
+	if resp.StatusCode != expected {
+		logger.Error(nil, "Unexpected response from OpenFGA", "method", method, "path", path, "statusCode", resp.StatusCode, "response", string(data))
+		return fmt.Errorf("OpenFGA %s %s failed: %d", method, path, resp.StatusCode)
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		logger.Error(err, "Failed to parse response from OpenFGA")
+		return err
+	}
+	return nil
+}
+
+// GetStore returns the store with the given ID
+func (c *httpOpenFGAClient) GetStore(ctx context.Context, storeID string) (*OpenFGAStore, error) {
+	store := &OpenFGAStore{}
+	if err := c.do(ctx, http.MethodGet, "/stores/"+url.PathEscape(storeID), nil, http.StatusOK, store); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// FindStore returns the store with the given name, or nil if there is none
+func (c *httpOpenFGAClient) FindStore(ctx context.Context, name string) (*OpenFGAStore, error) {
+	continuationToken := ""
+	for {
+		query := url.Values{"page_size": {"100"}}
+		if continuationToken != "" {
+			query.Set("continuation_token", continuationToken)
+		}
+
+		var response struct {
+			Stores            []OpenFGAStore `json:"stores"`
+			ContinuationToken string         `json:"continuation_token"`
+		}
+		if err := c.do(ctx, http.MethodGet, "/stores?"+query.Encode(), nil, http.StatusOK, &response); err != nil {
+			return nil, err
+		}
+		for i := range response.Stores {
+			if response.Stores[i].Name == name {
+				return &response.Stores[i], nil
+			}
+		}
+
+		if response.ContinuationToken == "" {
+			return nil, nil
+		}
+		continuationToken = response.ContinuationToken
+	}
+}
+
+// CreateStore creates a store with the given name
+func (c *httpOpenFGAClient) CreateStore(ctx context.Context, name string) (*OpenFGAStore, error) {
+	body, err := json.Marshal(map[string]string{"name": name})
+	if err != nil {
+		return nil, err
+	}
+
+	store := &OpenFGAStore{}
+	if err := c.do(ctx, http.MethodPost, "/stores", bytes.NewReader(body), http.StatusCreated, store); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// WriteAuthorizationModel writes a new version of the store's authorization model
+func (c *httpOpenFGAClient) WriteAuthorizationModel(ctx context.Context, storeID string, model []byte) (string, error) {
+	var response struct {
+		AuthorizationModelID string `json:"authorization_model_id"`
+	}
+	path := "/stores/" + url.PathEscape(storeID) + "/authorization-models"
+	if err := c.do(ctx, http.MethodPost, path, bytes.NewReader(model), http.StatusCreated, &response); err != nil {
+		return "", err
+	}
+	return response.AuthorizationModelID, nil
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+)
+
+const (
+	// tenantQuotaName is the ResourceQuota that enforces the tenant's resources
+	tenantQuotaName = "tenant-quota"
+
+	// tenantLimitRangeName is the LimitRange that defaults and caps the tenant's containers
+	tenantLimitRangeName = "tenant-limits"
+)
+
+// reconcileResourceQuota enforces the tenant's resources in its namespace with
+// a ResourceQuota and a LimitRange, and reports the quota usage in the status.
+// Both are removed when the tenant has no resources set.
+func (r *TenantReconciler) reconcileResourceQuota(ctx context.Context, tenant *neurallogv1.Tenant) error {
+	logger := log.FromContext(ctx)
+
+	hard, err := quotaHard(tenant.Spec.Resources)
+	if err != nil {
+		return err
+	}
+	if len(hard) == 0 {
+		tenant.Status.Quota = nil
+		for _, obj := range []client.Object{
+			&corev1.ResourceQuota{ObjectMeta: metav1.ObjectMeta{Name: tenantQuotaName, Namespace: tenant.Status.Namespace}},
+			&corev1.LimitRange{ObjectMeta: metav1.ObjectMeta{Name: tenantLimitRangeName, Namespace: tenant.Status.Namespace}},
+		} {
+			if err := client.IgnoreNotFound(r.Delete(ctx, obj)); err != nil {
+				logger.Error(err, "Failed to delete tenant resource limits", "name", obj.GetName())
+				return err
+			}
+		}
+		return nil
+	}
+
+	labels := map[string]string{
+		"neurallog.io/tenant":     tenant.Name,
+		"neurallog.io/managed-by": "tenant-operator",
+	}
+
+	// Define the ResourceQuota
+	quota := &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      tenantQuotaName,
+			Namespace: tenant.Status.Namespace,
+			Labels:    labels,
+		},
+		Spec: corev1.ResourceQuotaSpec{Hard: hard},
+	}
+	if err := controllerutil.SetControllerReference(tenant, quota, r.Scheme); err != nil {
+		logger.Error(err, "Failed to set owner reference on ResourceQuota")
+		return err
+	}
+	if err := r.apply(ctx, quota); err != nil {
+		logger.Error(err, "Failed to apply ResourceQuota")
+		return err
+	}
+	logger.Info("Applied ResourceQuota", "resourceQuota", quota.Name)
+
+	// Define the LimitRange
+	limitRange := &corev1.LimitRange{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      tenantLimitRangeName,
+			Namespace: tenant.Status.Namespace,
+			Labels:    labels,
+		},
+		Spec: limitRangeSpec(hard),
+	}
+	if err := controllerutil.SetControllerReference(tenant, limitRange, r.Scheme); err != nil {
+		logger.Error(err, "Failed to set owner reference on LimitRange")
+		return err
+	}
+	if err := r.apply(ctx, limitRange); err != nil {
+		logger.Error(err, "Failed to apply LimitRange")
+		return err
+	}
+	logger.Info("Applied LimitRange", "limitRange", limitRange.Name)
+
+	// Report the usage the quota controller last observed
+	tenant.Status.Quota = quota.Status.DeepCopy()
+	return nil
+}
+
+// quotaHard returns the hard limits of the tenant's ResourceQuota. Storage
+// limits the total size of the tenant's volume claims.
+func quotaHard(resources *neurallogv1.ResourceRequirements) (corev1.ResourceList, error) {
+	hard := corev1.ResourceList{}
+	if resources == nil {
+		return hard, nil
+	}
+	cpu, memory := resourceLimit(resources.CPU), resourceLimit(resources.Memory)
+	storage := resourceLimit(resources.Storage).Limit
+	if storage == "" {
+		storage = resourceLimit(resources.Storage).Request
+	}
+
+	for _, q := range []struct {
+		name  corev1.ResourceName
+		value string
+	}{
+		{corev1.ResourceRequestsCPU, cpu.Request},
+		{corev1.ResourceLimitsCPU, cpu.Limit},
+		{corev1.ResourceRequestsMemory, memory.Request},
+		{corev1.ResourceLimitsMemory, memory.Limit},
+		{corev1.ResourceRequestsStorage, storage},
+	} {
+		if q.value == "" {
+			continue
+		}
+		quantity, err := parseQuantity(q.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s quantity: %w", q.name, err)
+		}
+		hard[q.name] = quantity
+	}
+	return hard, nil
+}
+
+// limitRangeSpec returns a LimitRange that gives containers without resources
+// the default container resources, so they are admitted under the quota, and
+// caps a single container or volume claim at the tenant's whole quota
+func limitRangeSpec(hard corev1.ResourceList) corev1.LimitRangeSpec {
+	container := corev1.LimitRangeItem{
+		Type:           corev1.LimitTypeContainer,
+		Max:            corev1.ResourceList{},
+		Default:        corev1.ResourceList{},
+		DefaultRequest: corev1.ResourceList{},
+	}
+	defaults := neurallogv1.DefaultContainerResources
+	for _, res := range []struct {
+		name           corev1.ResourceName
+		request, limit corev1.ResourceName
+		defaults       neurallogv1.ResourceLimit
+	}{
+		{corev1.ResourceCPU, corev1.ResourceRequestsCPU, corev1.ResourceLimitsCPU, resourceLimit(defaults.CPU)},
+		{corev1.ResourceMemory, corev1.ResourceRequestsMemory, corev1.ResourceLimitsMemory, resourceLimit(defaults.Memory)},
+	} {
+		// Defaults must not exceed the quota or the maximum
+		limit, hasLimit := hard[res.limit]
+		request, hasRequest := hard[res.request]
+		defaultLimit := limitRangeDefault(res.defaults.Limit, limit, hasLimit)
+		defaultRequest := limitRangeDefault(res.defaults.Request, request, hasRequest)
+		if defaultRequest.Cmp(defaultLimit) > 0 {
+			defaultRequest = defaultLimit
+		}
+
+		if hasLimit {
+			container.Max[res.name] = limit
+		}
+		container.Default[res.name] = defaultLimit
+		container.DefaultRequest[res.name] = defaultRequest
+	}
+
+	spec := corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{container}}
+	if storage, ok := hard[corev1.ResourceRequestsStorage]; ok {
+		spec.Limits = append(spec.Limits, corev1.LimitRangeItem{
+			Type: corev1.LimitTypePersistentVolumeClaim,
+			Max:  corev1.ResourceList{corev1.ResourceStorage: storage},
+		})
+	}
+	return spec
+}
+
+// limitRangeDefault returns the default quantity, lowered to the cap if there is one
+func limitRangeDefault(value string, ceiling resource.Quantity, hasCeiling bool) resource.Quantity {
+	quantity := resource.MustParse(value)
+	if hasCeiling && quantity.Cmp(ceiling) > 0 {
+		return ceiling
+	}
+	return quantity
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+)
+
+var _ = Describe("Resource quota reconciler", func() {
+	It("Should set only the quota fields the tenant sets", func() {
+		hard, err := quotaHard(&neurallogv1.ResourceRequirements{
+			CPU:     &neurallogv1.ResourceLimit{Limit: "4"},
+			Storage: &neurallogv1.ResourceLimit{Request: "20Gi"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hard).To(Equal(corev1.ResourceList{
+			corev1.ResourceLimitsCPU:       resource.MustParse("4"),
+			corev1.ResourceRequestsStorage: resource.MustParse("20Gi"),
+		}))
+	})
+
+	It("Should set no quota when the tenant sets no resources", func() {
+		hard, err := quotaHard(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hard).To(BeEmpty())
+	})
+
+	It("Should reject invalid quantities", func() {
+		_, err := quotaHard(&neurallogv1.ResourceRequirements{
+			Memory: &neurallogv1.ResourceLimit{Limit: "lots"},
+		})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should keep container defaults within the quota", func() {
+		spec := limitRangeSpec(corev1.ResourceList{
+			corev1.ResourceLimitsCPU:       resource.MustParse("100m"),
+			corev1.ResourceRequestsMemory:  resource.MustParse("32Mi"),
+			corev1.ResourceRequestsStorage: resource.MustParse("20Gi"),
+		})
+		Expect(spec.Limits).To(HaveLen(2))
+
+		container := spec.Limits[0]
+		Expect(container.Max).To(Equal(corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")}))
+		Expect(container.Default[corev1.ResourceCPU]).To(Equal(resource.MustParse("100m")))
+		Expect(container.DefaultRequest[corev1.ResourceCPU]).To(Equal(resource.MustParse("50m")))
+		Expect(container.Default[corev1.ResourceMemory]).To(Equal(resource.MustParse("256Mi")))
+		Expect(container.DefaultRequest[corev1.ResourceMemory]).To(Equal(resource.MustParse("32Mi")))
+
+		Expect(spec.Limits[1].Type).To(Equal(corev1.LimitTypePersistentVolumeClaim))
+		Expect(spec.Limits[1].Max[corev1.ResourceStorage]).To(Equal(resource.MustParse("20Gi")))
+	})
+
+	It("Should remove the quota when the tenant has no resources", func() {
+		quota := &corev1.ResourceQuota{ObjectMeta: metav1.ObjectMeta{Name: tenantQuotaName, Namespace: "tenant-test-tenant"}}
+		tenant := &neurallogv1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-tenant"},
+			Status: neurallogv1.TenantStatus{
+				Namespace: "tenant-test-tenant",
+				Quota:     &corev1.ResourceQuotaStatus{},
+			},
+		}
+		r := &TenantReconciler{Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(quota).Build()}
+
+		Expect(r.reconcileResourceQuota(context.Background(), tenant)).To(Succeed())
+		Expect(tenant.Status.Quota).To(BeNil())
+		err := r.Get(context.Background(), client.ObjectKeyFromObject(quota), &corev1.ResourceQuota{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+})
@@ -0,0 +1,504 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+)
+
+const (
+	// redisBackupName is the name of the backup CronJob and the label of its jobs
+	redisBackupName = "redis-backup"
+
+	// redisBackupPVCName is the PersistentVolumeClaim the operator creates for backups
+	redisBackupPVCName = "redis-backups"
+
+	// redisBackupCredentialsName is the copy of the S3 credentials in the tenant namespace
+	redisBackupCredentialsName = "redis-backup-credentials"
+
+	// redisBackupContainerName is the container that reports the backup result
+	redisBackupContainerName = "backup"
+)
+
+// redisBackupPVCScript saves an RDB snapshot to the backup volume and prunes old backups
+const redisBackupPVCScript = `set -e
+NAME="redis-backup-$(date +%Y%m%d%H%M%S).rdb"
+redis-cli -h "$REDIS_HOST" --rdb "/backups/$NAME"
+SIZE="$(wc -c < "/backups/$NAME")"
+ls -1 /backups/redis-backup-*.rdb | sort -r | tail -n +$((RETENTION + 1)) | while read -r old; do
+  rm -f "$old"
+done
+printf '{"name":"%s","sizeBytes":%s}' "$NAME" $SIZE > /dev/termination-log
+`
+
+// redisBackupDumpScript saves an RDB snapshot for upload
+const redisBackupDumpScript = `redis-cli -h "$REDIS_HOST" --rdb /work/dump.rdb`
+
+// redisBackupS3Script uploads the RDB snapshot with the MinIO client and prunes
+// old backups. Listings are in key order, which is oldest first for these names.
+const redisBackupS3Script = `set -e
+NAME="redis-backup-$(date +%Y%m%d%H%M%S).rdb"
+DEST="backup/$S3_BUCKET/$S3_PREFIX"
+mc alias set backup "$S3_ENDPOINT" "$S3_ACCESS_KEY_ID" "$S3_SECRET_ACCESS_KEY" > /dev/null
+mc cp /work/dump.rdb "$DEST/$NAME"
+SIZE="$(wc -c < /work/dump.rdb)"
+BACKUPS=()
+while read -r line; do
+  file="${line##* }"
+  case "$file" in redis-backup-*.rdb) BACKUPS+=("$file") ;; esac
+done < <(mc ls "$DEST/")
+for ((i = 0; i < ${#BACKUPS[@]} - RETENTION; i++)); do
+  mc rm "$DEST/${BACKUPS[$i]}"
+done
+printf '{"name":"%s","sizeBytes":%s}' "$NAME" $SIZE > /dev/termination-log
+`
+
+// reconcileRedisBackup creates or updates the scheduled Redis backups for the tenant
+func (r *TenantReconciler) reconcileRedisBackup(ctx context.Context, tenant *neurallogv1.Tenant) error {
+	logger := log.FromContext(ctx)
+	backup := redisSpec(tenant).Backup
+
+	// Remove the CronJob if backups were disabled, keeping the backups themselves
+	if backup == nil {
+		cronJob := &batchv1.CronJob{}
+		err := r.Get(ctx, client.ObjectKey{Name: redisBackupName, Namespace: tenant.Status.Namespace}, cronJob)
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			logger.Error(err, "Failed to get Redis backup CronJob")
+			return err
+		}
+		if err := client.IgnoreNotFound(r.Delete(ctx, cronJob)); err != nil {
+			logger.Error(err, "Failed to delete Redis backup CronJob")
+			return err
+		}
+		logger.Info("Deleted Redis backup CronJob", "cronJob", cronJob.Name)
+		return nil
+	}
+
+	// Prepare the destination
+	if backup.Destination.S3 != nil {
+		if err := r.reconcileRedisBackupCredentials(ctx, tenant); err != nil {
+			logger.Error(err, "Failed to reconcile Redis backup credentials")
+			return err
+		}
+	} else if backup.Destination.PVC != nil && backup.Destination.PVC.ClaimName == "" {
+		if err := r.reconcileRedisBackupPVC(ctx, tenant); err != nil {
+			logger.Error(err, "Failed to reconcile Redis backup PersistentVolumeClaim")
+			return err
+		}
+	}
+
+	cronJob, err := r.reconcileRedisBackupCronJob(ctx, tenant)
+	if err != nil {
+		logger.Error(err, "Failed to reconcile Redis backup CronJob")
+		return err
+	}
+
+	return r.updateRedisBackupStatus(ctx, tenant, cronJob)
+}
+
+// reconcileRedisBackupCredentials copies the S3 credentials into the tenant namespace
+func (r *TenantReconciler) reconcileRedisBackupCredentials(ctx context.Context, tenant *neurallogv1.Tenant) error {
+	logger := log.FromContext(ctx)
+	ref := redisSpec(tenant).Backup.Destination.S3.CredentialsSecretRef
+
+	source, err := r.getReferencedSecret(ctx, tenant, "backup credentials", ref)
+	if err != nil {
+		return err
+	}
+	data := map[string][]byte{}
+	for _, key := range []string{"accessKeyId", "secretAccessKey"} {
+		value, ok := source.Data[key]
+		if !ok {
+			return fmt.Errorf("backup credentials Secret %s/%s has no %q key", ref.Namespace, ref.Name, key)
+		}
+		data[key] = value
+	}
+
+	// Define Secret
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      redisBackupCredentialsName,
+			Namespace: tenant.Status.Namespace,
+			Labels: map[string]string{
+				"app":                    redisBackupName,
+				"neurallog.io/tenant":    tenant.Name,
+				"neurallog.io/component": "redis",
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: data,
+	}
+
+	// Set owner reference
+	if err := controllerutil.SetControllerReference(tenant, secret, r.Scheme); err != nil {
+		logger.Error(err, "Failed to set owner reference on Redis backup credentials Secret")
+		return err
+	}
+
+	// Apply the Secret
+	if err := r.apply(ctx, secret); err != nil {
+		logger.Error(err, "Failed to apply Redis backup credentials Secret")
+		return err
+	}
+	logger.Info("Applied Redis backup credentials Secret", "secret", secret.Name)
+	return nil
+}
+
+// reconcileRedisBackupPVC creates or updates the PersistentVolumeClaim backups are stored in
+func (r *TenantReconciler) reconcileRedisBackupPVC(ctx context.Context, tenant *neurallogv1.Tenant) error {
+	logger := log.FromContext(ctx)
+	destination := redisSpec(tenant).Backup.Destination.PVC
+
+	sizeValue := neurallogv1.DefaultRedisBackupSize
+	if destination.Size != "" {
+		sizeValue = destination.Size
+	}
+	size, err := parseQuantity(sizeValue)
+	if err != nil {
+		logger.Error(err, "Invalid Redis backup volume size")
+		return err
+	}
+
+	// Define PersistentVolumeClaim
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      redisBackupPVCName,
+			Namespace: tenant.Status.Namespace,
+			Labels: map[string]string{
+				"app":                    redisBackupName,
+				"neurallog.io/tenant":    tenant.Name,
+				"neurallog.io/component": "redis",
+			},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{
+				corev1.ReadWriteOnce,
+			},
+			StorageClassName: destination.StorageClassName,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: size,
+				},
+			},
+		},
+	}
+
+	// Set owner reference
+	if err := controllerutil.SetControllerReference(tenant, pvc, r.Scheme); err != nil {
+		logger.Error(err, "Failed to set owner reference on Redis backup PersistentVolumeClaim")
+		return err
+	}
+
+	// Apply the PersistentVolumeClaim
+	if err := r.apply(ctx, pvc); err != nil {
+		logger.Error(err, "Failed to apply Redis backup PersistentVolumeClaim")
+		return err
+	}
+	logger.Info("Applied Redis backup PersistentVolumeClaim", "pvc", pvc.Name)
+	return nil
+}
+
+// reconcileRedisBackupCronJob creates or updates the CronJob that runs the backups
+func (r *TenantReconciler) reconcileRedisBackupCronJob(ctx context.Context, tenant *neurallogv1.Tenant) (*batchv1.CronJob, error) {
+	logger := log.FromContext(ctx)
+	redis := redisSpec(tenant)
+	backup := redis.Backup
+
+	retention := neurallogv1.DefaultRedisBackupRetention
+	if backup.Retention != nil {
+		retention = *backup.Retention
+	}
+
+	redisImage := neurallogv1.DefaultRedisImage
+	if redis.Image != "" {
+		redisImage = redis.Image
+	}
+
+	env := []corev1.EnvVar{
+		{
+			Name:  "REDIS_HOST",
+			Value: redisHost(tenant),
+		},
+		{
+			Name:  "RETENTION",
+			Value: fmt.Sprintf("%d", retention),
+		},
+	}
+
+	// Render the pod for the destination
+	var podSpec corev1.PodSpec
+	if backup.Destination.S3 != nil {
+		workMount := corev1.VolumeMount{Name: "work", MountPath: "/work"}
+		podSpec = corev1.PodSpec{
+			InitContainers: []corev1.Container{
+				{
+					Name:         "dump",
+					Image:        redisImage,
+					Command:      []string{"sh", "-c", redisBackupDumpScript},
+					Env:          env,
+					VolumeMounts: []corev1.VolumeMount{workMount},
+				},
+			},
+			Containers: []corev1.Container{
+				{
+					Name:         redisBackupContainerName,
+					Image:        redisBackupUploaderImage(tenant),
+					Command:      []string{"bash", "-c", redisBackupS3Script},
+					Env:          append(env, redisBackupS3Env(tenant)...),
+					VolumeMounts: []corev1.VolumeMount{workMount},
+				},
+			},
+			Volumes: []corev1.Volume{
+				{
+					Name: "work",
+					VolumeSource: corev1.VolumeSource{
+						EmptyDir: &corev1.EmptyDirVolumeSource{},
+					},
+				},
+			},
+		}
+	} else {
+		image := redisImage
+		if backup.Image != "" {
+			image = backup.Image
+		}
+		podSpec = corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:    redisBackupContainerName,
+					Image:   image,
+					Command: []string{"sh", "-c", redisBackupPVCScript},
+					Env:     env,
+					VolumeMounts: []corev1.VolumeMount{
+						{
+							Name:      "backups",
+							MountPath: "/backups",
+						},
+					},
+				},
+			},
+			Volumes: []corev1.Volume{
+				{
+					Name: "backups",
+					VolumeSource: corev1.VolumeSource{
+						PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+							ClaimName: redisBackupClaimName(tenant),
+						},
+					},
+				},
+			},
+		}
+	}
+	podSpec.RestartPolicy = corev1.RestartPolicyNever
+
+	labels := map[string]string{
+		"app":                    redisBackupName,
+		"neurallog.io/tenant":    tenant.Name,
+		"neurallog.io/component": "redis",
+	}
+
+	// Don't back up while Redis is stopped
+	suspend := stoppedReason(tenant) != ""
+	successfulJobsHistoryLimit := int32(3)
+	failedJobsHistoryLimit := int32(1)
+	backoffLimit := int32(2)
+
+	// Create CronJob object
+	cronJob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      redisBackupName,
+			Namespace: tenant.Status.Namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.CronJobSpec{
+			Schedule:                   backup.Schedule,
+			Suspend:                    &suspend,
+			ConcurrencyPolicy:          batchv1.ForbidConcurrent,
+			SuccessfulJobsHistoryLimit: &successfulJobsHistoryLimit,
+			FailedJobsHistoryLimit:     &failedJobsHistoryLimit,
+			JobTemplate: batchv1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: batchv1.JobSpec{
+					BackoffLimit: &backoffLimit,
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: labels,
+						},
+						Spec: podSpec,
+					},
+				},
+			},
+		},
+	}
+
+	// Set owner reference
+	if err := controllerutil.SetControllerReference(tenant, cronJob, r.Scheme); err != nil {
+		logger.Error(err, "Failed to set owner reference on Redis backup CronJob")
+		return nil, err
+	}
+
+	// Apply the CronJob
+	if err := r.apply(ctx, cronJob); err != nil {
+		logger.Error(err, "Failed to apply Redis backup CronJob")
+		return nil, err
+	}
+	logger.Info("Applied Redis backup CronJob", "cronJob", cronJob.Name)
+	return cronJob, nil
+}
+
+// redisBackupClaimName returns the PersistentVolumeClaim backups are stored in
+// for a PVC destination
+func redisBackupClaimName(tenant *neurallogv1.Tenant) string {
+	if claimName := redisSpec(tenant).Backup.Destination.PVC.ClaimName; claimName != "" {
+		return claimName
+	}
+	return redisBackupPVCName
+}
+
+// redisBackupUploaderImage returns the image that transfers backups to and from S3
+func redisBackupUploaderImage(tenant *neurallogv1.Tenant) string {
+	if image := redisSpec(tenant).Backup.Image; image != "" {
+		return image
+	}
+	return neurallogv1.DefaultBackupUploaderImage
+}
+
+// redisBackupS3Env returns the environment that locates the S3 backups of the tenant
+func redisBackupS3Env(tenant *neurallogv1.Tenant) []corev1.EnvVar {
+	s3 := redisSpec(tenant).Backup.Destination.S3
+	prefix := s3.Prefix
+	if prefix == "" {
+		prefix = tenant.Name
+	}
+	return []corev1.EnvVar{
+		{Name: "S3_ENDPOINT", Value: s3.Endpoint},
+		{Name: "S3_BUCKET", Value: s3.Bucket},
+		{Name: "S3_PREFIX", Value: strings.Trim(prefix, "/")},
+		secretEnvVar("S3_ACCESS_KEY_ID", redisBackupCredentialsName, "accessKeyId"),
+		secretEnvVar("S3_SECRET_ACCESS_KEY", redisBackupCredentialsName, "secretAccessKey"),
+	}
+}
+
+// secretEnvVar returns an environment variable read from a Secret key
+func secretEnvVar(name, secretName, key string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: name,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+				Key:                  key,
+			},
+		},
+	}
+}
+
+// redisBackupResult is the termination message written by a successful backup
+type redisBackupResult struct {
+	Name      string `json:"name"`
+	SizeBytes int64  `json:"sizeBytes"`
+}
+
+// updateRedisBackupStatus records the most recent successful backup in the tenant status
+func (r *TenantReconciler) updateRedisBackupStatus(ctx context.Context, tenant *neurallogv1.Tenant, cronJob *batchv1.CronJob) error {
+	logger := log.FromContext(ctx)
+	status := &tenant.Status.RedisStatus
+
+	// Nothing to do until a backup succeeds that isn't recorded yet
+	lastSuccess := cronJob.Status.LastSuccessfulTime
+	if lastSuccess == nil || (status.LastBackup != nil && status.LastBackup.Time != nil && !status.LastBackup.Time.Before(lastSuccess)) {
+		return nil
+	}
+
+	// Find the most recent successful backup job
+	jobs := &batchv1.JobList{}
+	if err := r.List(ctx, jobs, client.InNamespace(tenant.Status.Namespace), client.MatchingLabels{"app": redisBackupName}); err != nil {
+		logger.Error(err, "Failed to list Redis backup jobs")
+		return err
+	}
+	var latest *batchv1.Job
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if job.Status.Succeeded == 0 || job.Status.CompletionTime == nil {
+			continue
+		}
+		if latest == nil || latest.Status.CompletionTime.Before(job.Status.CompletionTime) {
+			latest = job
+		}
+	}
+	if latest == nil {
+		return nil
+	}
+
+	// Read the result the backup container reported
+	backupStatus, err := r.redisBackupJobResult(ctx, latest)
+	if err != nil {
+		return err
+	}
+
+	status.LastBackup = backupStatus
+	logger.Info("Recorded Redis backup", "name", backupStatus.Name, "sizeBytes", backupStatus.SizeBytes)
+	return nil
+}
+
+// redisBackupJobResult returns the backup a succeeded backup job reported
+func (r *TenantReconciler) redisBackupJobResult(ctx context.Context, job *batchv1.Job) (*neurallogv1.BackupStatus, error) {
+	logger := log.FromContext(ctx)
+
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(job.Namespace), client.MatchingLabels{"job-name": job.Name}); err != nil {
+		logger.Error(err, "Failed to list Redis backup pods", "job", job.Name)
+		return nil, err
+	}
+	backupStatus := &neurallogv1.BackupStatus{Time: job.Status.CompletionTime}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodSucceeded {
+			continue
+		}
+		for _, containerStatus := range pod.Status.ContainerStatuses {
+			terminated := containerStatus.State.Terminated
+			if containerStatus.Name != redisBackupContainerName || terminated == nil || terminated.Message == "" {
+				continue
+			}
+			var result redisBackupResult
+			if err := json.Unmarshal([]byte(terminated.Message), &result); err != nil {
+				logger.Info("Ignoring unreadable Redis backup result", "pod", pod.Name, "error", err.Error())
+				continue
+			}
+			backupStatus.Name = result.Name
+			backupStatus.SizeBytes = result.SizeBytes
+		}
+	}
+	return backupStatus, nil
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+)
+
+var _ = Describe("Redis backup reconciler", func() {
+	var (
+		tenant *neurallogv1.Tenant
+		r      *TenantReconciler
+	)
+
+	BeforeEach(func() {
+		tenant = &neurallogv1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-tenant"},
+			Spec: neurallogv1.TenantSpec{
+				Redis: &neurallogv1.RedisSpec{Backup: &neurallogv1.RedisBackupSpec{
+					Destination: neurallogv1.BackupDestination{S3: &neurallogv1.S3BackupDestination{
+						Endpoint: "http://minio.minio.svc:9000",
+						Bucket:   "redis-backups",
+					}},
+				}},
+			},
+			Status: neurallogv1.TenantStatus{Namespace: "tenant-test-tenant"},
+		}
+		secrets := []client.Object{}
+		for _, namespace := range []string{"tenant-test-tenant", "minio"} {
+			secrets = append(secrets, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "minio-credentials", Namespace: namespace},
+				Data: map[string][]byte{
+					"accessKeyId":     []byte("access-key"),
+					"secretAccessKey": []byte("secret-key"),
+				},
+			})
+		}
+
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(neurallogv1.AddToScheme(scheme)).To(Succeed())
+		r = &TenantReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(secrets...).
+				WithInterceptorFuncs(interceptor.Funcs{Patch: applyWithUpdate}).Build(),
+			Scheme: scheme,
+		}
+	})
+
+	It("Should copy the S3 credentials from the tenant namespace", func() {
+		tenant.Spec.Redis.Backup.Destination.S3.CredentialsSecretRef = neurallogv1.SecretReference{Name: "minio-credentials", Namespace: "tenant-test-tenant"}
+		Expect(r.reconcileRedisBackupCredentials(context.Background(), tenant)).To(Succeed())
+
+		secret := &corev1.Secret{}
+		Expect(r.Get(context.Background(), client.ObjectKey{Name: redisBackupCredentialsName, Namespace: "tenant-test-tenant"}, secret)).To(Succeed())
+		Expect(string(secret.Data["secretAccessKey"])).To(Equal("secret-key"))
+	})
+
+	It("Should refuse S3 credentials in another namespace", func() {
+		tenant.Spec.Redis.Backup.Destination.S3.CredentialsSecretRef = neurallogv1.SecretReference{Name: "minio-credentials", Namespace: "minio"}
+		err := r.reconcileRedisBackupCredentials(context.Background(), tenant)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("not in the tenant namespace"))
+
+		err = r.Get(context.Background(), client.ObjectKey{Name: redisBackupCredentialsName, Namespace: "tenant-test-tenant"}, &corev1.Secret{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+})
@@ -19,10 +19,10 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"sort"
 
-	"k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
@@ -31,36 +31,91 @@ import (
 	corev1 "k8s.io/api/core/v1"
 )
 
-// reconcileRedisConfigMap creates or updates the Redis ConfigMap
-func (r *TenantReconciler) reconcileRedisConfigMap(ctx context.Context, tenant *neurallogv1.Tenant) (*corev1.ConfigMap, error) {
+// reconcileRedis creates or updates Redis resources for the tenant
+func (r *TenantReconciler) reconcileRedis(ctx context.Context, tenant *neurallogv1.Tenant) error {
 	logger := log.FromContext(ctx)
-	namespaceName := tenant.Status.Namespace
+	logger.Info("Reconciling Redis resources", "tenant", tenant.Name)
 
-	// Default Redis configuration
-	redisConf := `# Redis configuration for NeuralLog
-port 6379
-bind 0.0.0.0
-protected-mode yes
-daemonize no
+	if tenant.Status.Namespace == "" {
+		logger.Info("Namespace not yet created, skipping Redis reconciliation")
+		return nil
+	}
 
-# Memory management
-maxmemory 256mb
-maxmemory-policy allkeys-lru
+	// Create or update Redis ConfigMap
+	configMap, err := r.reconcileRedisConfigMap(ctx, tenant)
+	if err != nil {
+		logger.Error(err, "Failed to reconcile Redis ConfigMap")
+		return err
+	}
 
-# Persistence
-appendonly yes
-appendfsync everysec
+	// Create or update Redis Service
+	if _, err := r.reconcileRedisService(ctx, tenant); err != nil {
+		logger.Error(err, "Failed to reconcile Redis Service")
+		return err
+	}
 
-# Logging
-loglevel notice
-logfile ""`
+	// Create or update Redis StatefulSet
+	statefulSet, err := r.reconcileRedisStatefulSet(ctx, tenant, configMap)
+	if err != nil {
+		logger.Error(err, "Failed to reconcile Redis StatefulSet")
+		return err
+	}
 
-	// Apply custom configuration if provided
-	if tenant.Spec.Redis.Config != nil {
-		for key, value := range tenant.Spec.Redis.Config {
-			redisConf += fmt.Sprintf("\n%s %s", key, value)
+	var sentinelSet *appsv1.StatefulSet
+	if redisSentinelEnabled(tenant) {
+		// Create or update Sentinel and track the primary
+		sentinelSet, err = r.reconcileRedisSentinel(ctx, tenant, configMap)
+		if err != nil {
+			logger.Error(err, "Failed to reconcile Redis Sentinel")
+			return err
+		}
+		if stoppedReason(tenant) == "" {
+			if err := r.reconcileRedisPrimary(ctx, tenant); err != nil {
+				logger.Error(err, "Failed to reconcile Redis primary")
+				return err
+			}
 		}
+	} else if tenant.Status.RedisStatus.Primary != "" {
+		// Without Sentinel, remove what a previous HA configuration left behind
+		if err := r.cleanupRedisSentinel(ctx, tenant); err != nil {
+			logger.Error(err, "Failed to clean up Redis Sentinel")
+			return err
+		}
+	}
+
+	// Create or update scheduled backups
+	if err := r.reconcileRedisBackup(ctx, tenant); err != nil {
+		logger.Error(err, "Failed to reconcile Redis backups")
+		return err
+	}
+
+	return r.updateRedisStatus(ctx, tenant, statefulSet, sentinelSet)
+}
+
+// redisSpec returns the tenant's Redis configuration, empty if unset
+func redisSpec(tenant *neurallogv1.Tenant) neurallogv1.RedisSpec {
+	if tenant.Spec.Redis == nil {
+		return neurallogv1.RedisSpec{}
+	}
+	return *tenant.Spec.Redis
+}
+
+// redisReplicas returns the number of Redis instances the tenant runs
+func redisReplicas(tenant *neurallogv1.Tenant) int32 {
+	redis := redisSpec(tenant)
+	if redis.Replicas != nil {
+		return *redis.Replicas
 	}
+	if redisSentinelEnabled(tenant) {
+		return neurallogv1.DefaultRedisHAReplicas
+	}
+	return neurallogv1.DefaultReplicas
+}
+
+// reconcileRedisConfigMap creates or updates the Redis ConfigMap
+func (r *TenantReconciler) reconcileRedisConfigMap(ctx context.Context, tenant *neurallogv1.Tenant) (*corev1.ConfigMap, error) {
+	logger := log.FromContext(ctx)
+	namespaceName := tenant.Status.Namespace
 
 	// Create ConfigMap object
 	configMap := &corev1.ConfigMap{
@@ -74,89 +129,116 @@ logfile ""`
 			},
 		},
 		Data: map[string]string{
-			"redis.conf": redisConf,
+			"redis.conf": redisConfig(redisSpec(tenant)),
 		},
 	}
 
+	// Add the startup scripts for Sentinel mode
+	if redisSentinelEnabled(tenant) {
+		configMap.Data["start-redis.sh"] = redisStartScript
+		configMap.Data["start-sentinel.sh"] = sentinelStartScript
+	}
+
 	// Set owner reference
 	if err := controllerutil.SetControllerReference(tenant, configMap, r.Scheme); err != nil {
 		logger.Error(err, "Failed to set owner reference on Redis ConfigMap")
 		return nil, err
 	}
 
-	// Create or update the ConfigMap
-	existingConfigMap := &corev1.ConfigMap{}
-	err := r.Get(ctx, client.ObjectKey{Name: configMap.Name, Namespace: configMap.Namespace}, existingConfigMap)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			// Create ConfigMap
-			if err := r.Create(ctx, configMap); err != nil {
-				logger.Error(err, "Failed to create Redis ConfigMap")
-				return nil, err
-			}
-			logger.Info("Created Redis ConfigMap", "configMap", configMap.Name)
-			return configMap, nil
-		}
-		logger.Error(err, "Failed to get Redis ConfigMap")
+	// Apply the ConfigMap
+	if err := r.apply(ctx, configMap); err != nil {
+		logger.Error(err, "Failed to apply Redis ConfigMap")
 		return nil, err
 	}
+	logger.Info("Applied Redis ConfigMap", "configMap", configMap.Name)
+	return configMap, nil
+}
 
-	// Update ConfigMap if it exists
-	existingConfigMap.Data = configMap.Data
-	if err := r.Update(ctx, existingConfigMap); err != nil {
-		logger.Error(err, "Failed to update Redis ConfigMap")
-		return nil, err
+// redisConfig returns the redis.conf for the tenant: the defaults followed by
+// the tenant's settings. The settings are sorted, so the ConfigMap only changes
+// when they do.
+func redisConfig(redis neurallogv1.RedisSpec) string {
+	// Default Redis configuration
+	redisConf := `# Redis configuration for NeuralLog
+port 6379
+bind 0.0.0.0
+protected-mode yes
+daemonize no
+
+# Memory management
+maxmemory 256mb
+maxmemory-policy allkeys-lru
+
+# Persistence
+appendonly yes
+appendfsync everysec
+
+# Logging
+loglevel notice
+logfile ""`
+
+	// Apply custom configuration if provided
+	keys := make([]string, 0, len(redis.Config))
+	for key := range redis.Config {
+		keys = append(keys, key)
 	}
-	logger.Info("Updated Redis ConfigMap", "configMap", existingConfigMap.Name)
-	return existingConfigMap, nil
+	sort.Strings(keys)
+	for _, key := range keys {
+		redisConf += fmt.Sprintf("\n%s %s", key, redis.Config[key])
+	}
+
+	return redisConf
 }
 
 // reconcileRedisStatefulSet creates or updates the Redis StatefulSet
 func (r *TenantReconciler) reconcileRedisStatefulSet(ctx context.Context, tenant *neurallogv1.Tenant, configMap *corev1.ConfigMap) (*appsv1.StatefulSet, error) {
 	logger := log.FromContext(ctx)
 	namespaceName := tenant.Status.Namespace
+	redis := redisSpec(tenant)
 
-	// Default values
-	replicas := int32(1)
-	if tenant.Spec.Redis.Replicas != nil {
-		replicas = *tenant.Spec.Redis.Replicas
+	// Default values, with Redis stopped while suspended or restoring
+	replicas := redisReplicas(tenant)
+	if stoppedReason(tenant) != "" {
+		replicas = 0
 	}
 
-	image := "redis:7-alpine"
-	if tenant.Spec.Redis.Image != "" {
-		image = tenant.Spec.Redis.Image
+	image := neurallogv1.DefaultRedisImage
+	if redis.Image != "" {
+		image = redis.Image
 	}
 
 	// Resource requirements
-	resources := corev1.ResourceRequirements{
-		Requests: corev1.ResourceList{
-			corev1.ResourceCPU:    resource.MustParse("100m"),
-			corev1.ResourceMemory: resource.MustParse("128Mi"),
-		},
-		Limits: corev1.ResourceList{
-			corev1.ResourceCPU:    resource.MustParse("300m"),
-			corev1.ResourceMemory: resource.MustParse("256Mi"),
-		},
+	resources, err := resourceRequirements(redis.Resources, neurallogv1.DefaultRedisResources)
+	if err != nil {
+		logger.Error(err, "Invalid Redis resources")
+		return nil, err
 	}
 
-	// Apply custom resource requirements if provided
-	if tenant.Spec.Redis.Resources.CPU.Request != "" {
-		resources.Requests[corev1.ResourceCPU] = resource.MustParse(tenant.Spec.Redis.Resources.CPU.Request)
-	}
-	if tenant.Spec.Redis.Resources.CPU.Limit != "" {
-		resources.Limits[corev1.ResourceCPU] = resource.MustParse(tenant.Spec.Redis.Resources.CPU.Limit)
-	}
-	if tenant.Spec.Redis.Resources.Memory.Request != "" {
-		resources.Requests[corev1.ResourceMemory] = resource.MustParse(tenant.Spec.Redis.Resources.Memory.Request)
+	// Storage size
+	storageValue := neurallogv1.DefaultRedisStorage
+	if redis.Storage != "" {
+		storageValue = redis.Storage
 	}
-	if tenant.Spec.Redis.Resources.Memory.Limit != "" {
-		resources.Limits[corev1.ResourceMemory] = resource.MustParse(tenant.Spec.Redis.Resources.Memory.Limit)
+	storageSize, err := parseQuantity(storageValue)
+	if err != nil {
+		logger.Error(err, "Invalid Redis storage size")
+		return nil, err
 	}
 
-	// Storage size
-	storageSize := "1Gi"
-	if tenant.Spec.Redis.Storage != "" {
-		storageSize = tenant.Spec.Redis.Storage
+	// In Sentinel mode the startup script decides whether to run as a replica
+	command := []string{"redis-server", "/etc/redis/redis.conf"}
+	configItems := []corev1.KeyToPath{
+		{
+			Key:  "redis.conf",
+			Path: "redis.conf",
+		},
+	}
+	if redisSentinelEnabled(tenant) {
+		command = []string{"sh", "/etc/redis/start-redis.sh"}
+		configItems = append(configItems, corev1.KeyToPath{
+			Key:  "start-redis.sh",
+			Path: "start-redis.sh",
+		})
 	}
 
 	// Create StatefulSet object
@@ -189,12 +271,9 @@ func (r *TenantReconciler) reconcileRedisStatefulSet(ctx context.Context, tenant
 				Spec: corev1.PodSpec{
 					Containers: []corev1.Container{
 						{
-							Name:  "redis",
-							Image: image,
-							Command: []string{
-								"redis-server",
-								"/etc/redis/redis.conf",
-							},
+							Name:    "redis",
+							Image:   image,
+							Command: command,
 							Ports: []corev1.ContainerPort{
 								{
 									Name:          "redis",
@@ -243,12 +322,7 @@ func (r *TenantReconciler) reconcileRedisStatefulSet(ctx context.Context, tenant
 									LocalObjectReference: corev1.LocalObjectReference{
 										Name: configMap.Name,
 									},
-									Items: []corev1.KeyToPath{
-										{
-											Key:  "redis.conf",
-											Path: "redis.conf",
-										},
-									},
+									Items: configItems,
 								},
 							},
 						},
@@ -266,7 +340,7 @@ func (r *TenantReconciler) reconcileRedisStatefulSet(ctx context.Context, tenant
 						},
 						Resources: corev1.ResourceRequirements{
 							Requests: corev1.ResourceList{
-								corev1.ResourceStorage: resource.MustParse(storageSize),
+								corev1.ResourceStorage: storageSize,
 							},
 						},
 					},
@@ -281,34 +355,13 @@ func (r *TenantReconciler) reconcileRedisStatefulSet(ctx context.Context, tenant
 		return nil, err
 	}
 
-	// Create or update the StatefulSet
-	existingStatefulSet := &appsv1.StatefulSet{}
-	err := r.Get(ctx, client.ObjectKey{Name: statefulSet.Name, Namespace: statefulSet.Namespace}, existingStatefulSet)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			// Create StatefulSet
-			if err := r.Create(ctx, statefulSet); err != nil {
-				logger.Error(err, "Failed to create Redis StatefulSet")
-				return nil, err
-			}
-			logger.Info("Created Redis StatefulSet", "statefulSet", statefulSet.Name)
-			return statefulSet, nil
-		}
-		logger.Error(err, "Failed to get Redis StatefulSet")
-		return nil, err
-	}
-
-	// Update StatefulSet if it exists
-	existingStatefulSet.Spec.Replicas = statefulSet.Spec.Replicas
-	existingStatefulSet.Spec.Template.Spec.Containers[0].Image = statefulSet.Spec.Template.Spec.Containers[0].Image
-	existingStatefulSet.Spec.Template.Spec.Containers[0].Resources = statefulSet.Spec.Template.Spec.Containers[0].Resources
-	
-	if err := r.Update(ctx, existingStatefulSet); err != nil {
-		logger.Error(err, "Failed to update Redis StatefulSet")
+	// Apply the StatefulSet
+	if err := r.apply(ctx, statefulSet); err != nil {
+		logger.Error(err, "Failed to apply Redis StatefulSet")
 		return nil, err
 	}
-	logger.Info("Updated Redis StatefulSet", "statefulSet", existingStatefulSet.Name)
-	return existingStatefulSet, nil
+	logger.Info("Applied Redis StatefulSet", "statefulSet", statefulSet.Name)
+	return statefulSet, nil
 }
 
 // reconcileRedisService creates or updates the Redis Service
@@ -348,57 +401,51 @@ func (r *TenantReconciler) reconcileRedisService(ctx context.Context, tenant *ne
 		return nil, err
 	}
 
-	// Create or update the Service
-	existingService := &corev1.Service{}
-	err := r.Get(ctx, client.ObjectKey{Name: service.Name, Namespace: service.Namespace}, existingService)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			// Create Service
-			if err := r.Create(ctx, service); err != nil {
-				logger.Error(err, "Failed to create Redis Service")
-				return nil, err
-			}
-			logger.Info("Created Redis Service", "service", service.Name)
-			return service, nil
-		}
-		logger.Error(err, "Failed to get Redis Service")
-		return nil, err
-	}
-
-	// Update Service if it exists
-	existingService.Spec.Ports = service.Spec.Ports
-	if err := r.Update(ctx, existingService); err != nil {
-		logger.Error(err, "Failed to update Redis Service")
+	// Apply the Service
+	if err := r.apply(ctx, service); err != nil {
+		logger.Error(err, "Failed to apply Redis Service")
 		return nil, err
 	}
-	logger.Info("Updated Redis Service", "service", existingService.Name)
-	return existingService, nil
+	logger.Info("Applied Redis Service", "service", service.Name)
+	return service, nil
 }
 
-// updateRedisStatus updates the Redis status in the tenant
-func (r *TenantReconciler) updateRedisStatus(ctx context.Context, tenant *neurallogv1.Tenant, statefulSet *appsv1.StatefulSet) error {
+// updateRedisStatus updates the Redis status in the tenant. sentinelSet is nil
+// unless Sentinel is enabled.
+func (r *TenantReconciler) updateRedisStatus(ctx context.Context, tenant *neurallogv1.Tenant, statefulSet, sentinelSet *appsv1.StatefulSet) error {
 	logger := log.FromContext(ctx)
+	status := &tenant.Status.RedisStatus
 
 	// Update Redis status
-	tenant.Status.RedisStatus = neurallogv1.ComponentStatus{
+	status.ComponentStatus = neurallogv1.ComponentStatus{
 		TotalReplicas: *statefulSet.Spec.Replicas,
 		ReadyReplicas: statefulSet.Status.ReadyReplicas,
 	}
 
 	// Set phase based on readiness
-	if statefulSet.Status.ReadyReplicas == 0 {
-		tenant.Status.RedisStatus.Phase = neurallogv1.ComponentPending
-		tenant.Status.RedisStatus.Message = "Redis is being provisioned"
-	} else if statefulSet.Status.ReadyReplicas < *statefulSet.Spec.Replicas {
-		tenant.Status.RedisStatus.Phase = neurallogv1.ComponentPending
-		tenant.Status.RedisStatus.Message = fmt.Sprintf("Redis is scaling up (%d/%d replicas ready)", statefulSet.Status.ReadyReplicas, *statefulSet.Spec.Replicas)
-	} else {
-		tenant.Status.RedisStatus.Phase = neurallogv1.ComponentRunning
-		tenant.Status.RedisStatus.Message = "Redis is running"
+	switch {
+	case stoppedReason(tenant) != "":
+		status.Phase = neurallogv1.ComponentPending
+		status.Message = fmt.Sprintf("Redis is %s", stoppedReason(tenant))
+	case statefulSet.Status.ReadyReplicas == 0:
+		status.Phase = neurallogv1.ComponentPending
+		status.Message = "Redis is being provisioned"
+	case statefulSet.Status.ReadyReplicas < *statefulSet.Spec.Replicas:
+		status.Phase = neurallogv1.ComponentPending
+		status.Message = fmt.Sprintf("Redis is scaling up (%d/%d replicas ready)", statefulSet.Status.ReadyReplicas, *statefulSet.Spec.Replicas)
+	case sentinelSet == nil:
+		status.Phase = neurallogv1.ComponentRunning
+		status.Message = "Redis is running"
+	case sentinelSet.Status.ReadyReplicas < *sentinelSet.Spec.Replicas:
+		status.Phase = neurallogv1.ComponentPending
+		status.Message = fmt.Sprintf("Redis Sentinel is starting (%d/%d Sentinels ready)", sentinelSet.Status.ReadyReplicas, *sentinelSet.Spec.Replicas)
+	default:
+		status.Phase = neurallogv1.ComponentRunning
+		status.Message = fmt.Sprintf("Redis is running with primary %s", status.Primary)
 	}
 
 	// Update tenant status
-	if err := r.Status().Update(ctx, tenant); err != nil {
+	if err := r.updateStatus(ctx, tenant); err != nil {
 		logger.Error(err, "Failed to update Redis status")
 		return err
 	}
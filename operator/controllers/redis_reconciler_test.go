@@ -0,0 +1,48 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+)
+
+var _ = Describe("Redis reconciler", func() {
+	It("Should write the custom configuration in a stable order", func() {
+		redis := neurallogv1.RedisSpec{Config: map[string]string{
+			"timeout":          "300",
+			"maxmemory":        "512mb",
+			"tcp-keepalive":    "60",
+			"hz":               "20",
+			"maxmemory-policy": "volatile-lru",
+		}}
+
+		config := redisConfig(redis)
+		Expect(config).To(HaveSuffix("\nhz 20\nmaxmemory 512mb\nmaxmemory-policy volatile-lru\ntcp-keepalive 60\ntimeout 300"))
+		for i := 0; i < 20; i++ {
+			Expect(redisConfig(redis)).To(Equal(config))
+		}
+	})
+
+	It("Should write only the defaults without custom configuration", func() {
+		config := redisConfig(neurallogv1.RedisSpec{})
+		Expect(config).To(HavePrefix("# Redis configuration for NeuralLog"))
+		Expect(config).To(HaveSuffix(`logfile ""`))
+	})
+})
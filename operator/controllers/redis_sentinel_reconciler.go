@@ -0,0 +1,543 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+)
+
+const (
+	// redisSentinelName is the name of the Sentinel StatefulSet and its headless Service
+	redisSentinelName = "redis-sentinel"
+
+	// redisSentinelNetworkPolicyName is the network policy that lets the operator query Sentinel
+	redisSentinelNetworkPolicyName = "allow-operator-redis-sentinel"
+
+	// redisPrimaryServiceName is the Service that always points at the Redis primary
+	redisPrimaryServiceName = "redis-primary"
+
+	// redisRoleLabel marks each Redis pod as the primary or a replica
+	redisRoleLabel = "neurallog.io/redis-role"
+
+	// sentinelMasterName is the name Sentinel monitors the Redis primary under
+	sentinelMasterName = "mymaster"
+
+	// sentinelPort is the port Sentinel listens on
+	sentinelPort = 26379
+
+	// redisPrimaryPollInterval is how often the primary is checked with Sentinel enabled,
+	// since a failover doesn't necessarily change any object the operator watches
+	redisPrimaryPollInterval = 30 * time.Second
+)
+
+// redisStartScript starts Redis as the primary known to Sentinel or as a
+// replica of it. Before any Sentinel knows a primary, the first pod is primary.
+const redisStartScript = `#!/bin/sh
+set -e
+HOST="$(hostname -f)"
+PRIMARY="$(timeout 5 redis-cli -h redis-sentinel -p 26379 sentinel get-master-addr-by-name mymaster 2>/dev/null | head -n 1 || true)"
+if [ -z "$PRIMARY" ]; then
+  PRIMARY="redis-0.${HOST#*.}"
+fi
+if [ "$PRIMARY" = "$HOST" ]; then
+  exec redis-server /etc/redis/redis.conf --replica-announce-ip "$HOST"
+fi
+exec redis-server /etc/redis/redis.conf --replica-announce-ip "$HOST" --replicaof "$PRIMARY" 6379
+`
+
+// sentinelStartScript writes the Sentinel configuration, monitoring the primary
+// known to the other Sentinels or the first Redis pod, and starts Sentinel
+const sentinelStartScript = `#!/bin/sh
+set -e
+HOST="$(hostname -f)"
+PRIMARY="$(timeout 5 redis-cli -h redis-sentinel -p 26379 sentinel get-master-addr-by-name mymaster 2>/dev/null | head -n 1 || true)"
+if [ -z "$PRIMARY" ]; then
+  PRIMARY="redis-0.redis.$(echo "$HOST" | cut -d. -f3-)"
+fi
+cat > /data/sentinel.conf <<EOF
+port 26379
+protected-mode no
+sentinel resolve-hostnames yes
+sentinel announce-hostnames yes
+sentinel announce-ip ${HOST}
+sentinel monitor mymaster ${PRIMARY} 6379 ${SENTINEL_QUORUM}
+sentinel down-after-milliseconds mymaster 5000
+sentinel failover-timeout mymaster 60000
+sentinel parallel-syncs mymaster 1
+EOF
+exec redis-server /data/sentinel.conf --sentinel
+`
+
+// redisSentinelEnabled reports whether Redis runs in high availability mode
+func redisSentinelEnabled(tenant *neurallogv1.Tenant) bool {
+	return redisSpec(tenant).Sentinel != nil
+}
+
+// redisHost returns the host clients use to reach the Redis primary
+func redisHost(tenant *neurallogv1.Tenant) string {
+	if redisSentinelEnabled(tenant) {
+		return redisPrimaryServiceName
+	}
+	return "redis"
+}
+
+// redisURL returns the URL tenant services use to reach the Redis primary
+func redisURL(tenant *neurallogv1.Tenant) string {
+	return fmt.Sprintf("redis://%s:6379", redisHost(tenant))
+}
+
+// reconcileRedisSentinel creates or updates the Sentinel and primary Service for Redis HA
+func (r *TenantReconciler) reconcileRedisSentinel(ctx context.Context, tenant *neurallogv1.Tenant, configMap *corev1.ConfigMap) (*appsv1.StatefulSet, error) {
+	logger := log.FromContext(ctx)
+
+	if _, err := r.reconcileRedisPrimaryService(ctx, tenant); err != nil {
+		logger.Error(err, "Failed to reconcile Redis primary Service")
+		return nil, err
+	}
+
+	if _, err := r.reconcileRedisSentinelService(ctx, tenant); err != nil {
+		logger.Error(err, "Failed to reconcile Redis Sentinel Service")
+		return nil, err
+	}
+
+	statefulSet, err := r.reconcileRedisSentinelStatefulSet(ctx, tenant, configMap)
+	if err != nil {
+		logger.Error(err, "Failed to reconcile Redis Sentinel StatefulSet")
+		return nil, err
+	}
+
+	// The operator queries Sentinel for the primary, allow it through the default deny policy
+	if networkPoliciesEnabled(tenant) {
+		if err := r.reconcileRedisSentinelNetworkPolicy(ctx, tenant); err != nil {
+			logger.Error(err, "Failed to reconcile Redis Sentinel network policy")
+			return nil, err
+		}
+	}
+
+	return statefulSet, nil
+}
+
+// reconcileRedisPrimaryService creates or updates the Service pointing at the Redis primary
+func (r *TenantReconciler) reconcileRedisPrimaryService(ctx context.Context, tenant *neurallogv1.Tenant) (*corev1.Service, error) {
+	logger := log.FromContext(ctx)
+
+	// Create Service object
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      redisPrimaryServiceName,
+			Namespace: tenant.Status.Namespace,
+			Labels: map[string]string{
+				"app":                    "redis",
+				"neurallog.io/tenant":    tenant.Name,
+				"neurallog.io/component": "redis",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{
+				"app":          "redis",
+				redisRoleLabel: "primary",
+			},
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "redis",
+					Port:       6379,
+					TargetPort: intstr.FromString("redis"),
+				},
+			},
+		},
+	}
+
+	// Set owner reference
+	if err := controllerutil.SetControllerReference(tenant, service, r.Scheme); err != nil {
+		logger.Error(err, "Failed to set owner reference on Redis primary Service")
+		return nil, err
+	}
+
+	// Apply the Service
+	if err := r.apply(ctx, service); err != nil {
+		logger.Error(err, "Failed to apply Redis primary Service")
+		return nil, err
+	}
+	logger.Info("Applied Redis primary Service", "service", service.Name)
+	return service, nil
+}
+
+// reconcileRedisSentinelService creates or updates the headless Service for Sentinel
+func (r *TenantReconciler) reconcileRedisSentinelService(ctx context.Context, tenant *neurallogv1.Tenant) (*corev1.Service, error) {
+	logger := log.FromContext(ctx)
+
+	// Create Service object
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      redisSentinelName,
+			Namespace: tenant.Status.Namespace,
+			Labels: map[string]string{
+				"app":                    redisSentinelName,
+				"neurallog.io/tenant":    tenant.Name,
+				"neurallog.io/component": "redis",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{
+				"app": redisSentinelName,
+			},
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "sentinel",
+					Port:       sentinelPort,
+					TargetPort: intstr.FromString("sentinel"),
+				},
+			},
+			ClusterIP: "None", // Headless service for StatefulSet
+		},
+	}
+
+	// Set owner reference
+	if err := controllerutil.SetControllerReference(tenant, service, r.Scheme); err != nil {
+		logger.Error(err, "Failed to set owner reference on Redis Sentinel Service")
+		return nil, err
+	}
+
+	// Apply the Service
+	if err := r.apply(ctx, service); err != nil {
+		logger.Error(err, "Failed to apply Redis Sentinel Service")
+		return nil, err
+	}
+	logger.Info("Applied Redis Sentinel Service", "service", service.Name)
+	return service, nil
+}
+
+// reconcileRedisSentinelStatefulSet creates or updates the Sentinel StatefulSet
+func (r *TenantReconciler) reconcileRedisSentinelStatefulSet(ctx context.Context, tenant *neurallogv1.Tenant, configMap *corev1.ConfigMap) (*appsv1.StatefulSet, error) {
+	logger := log.FromContext(ctx)
+	redis := redisSpec(tenant)
+	sentinel := redis.Sentinel
+
+	// Default values
+	replicas := neurallogv1.DefaultSentinelReplicas
+	if sentinel.Replicas != nil {
+		replicas = *sentinel.Replicas
+	}
+
+	quorum := neurallogv1.DefaultSentinelQuorum(replicas)
+	if sentinel.Quorum != nil {
+		quorum = *sentinel.Quorum
+	}
+
+	// Stop Sentinel with Redis, so after a restore it forgets the old primary
+	if stoppedReason(tenant) != "" {
+		replicas = 0
+	}
+
+	image := neurallogv1.DefaultRedisImage
+	if redis.Image != "" {
+		image = redis.Image
+	}
+
+	// Resource requirements
+	resources, err := resourceRequirements(nil, neurallogv1.DefaultSentinelResources)
+	if err != nil {
+		logger.Error(err, "Invalid Redis Sentinel resources")
+		return nil, err
+	}
+
+	labels := map[string]string{
+		"app":                    redisSentinelName,
+		"neurallog.io/tenant":    tenant.Name,
+		"neurallog.io/component": "redis",
+	}
+
+	// Create StatefulSet object
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      redisSentinelName,
+			Namespace: tenant.Status.Namespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": redisSentinelName,
+				},
+			},
+			ServiceName: redisSentinelName,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:    "sentinel",
+							Image:   image,
+							Command: []string{"sh", "/etc/redis/start-sentinel.sh"},
+							Env: []corev1.EnvVar{
+								{
+									Name:  "SENTINEL_QUORUM",
+									Value: fmt.Sprintf("%d", quorum),
+								},
+							},
+							Ports: []corev1.ContainerPort{
+								{
+									Name:          "sentinel",
+									ContainerPort: sentinelPort,
+								},
+							},
+							Resources: resources,
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      "sentinel-data",
+									MountPath: "/data",
+								},
+								{
+									Name:      "redis-config",
+									MountPath: "/etc/redis",
+								},
+							},
+							ReadinessProbe: &corev1.Probe{
+								ProbeHandler: corev1.ProbeHandler{
+									Exec: &corev1.ExecAction{
+										Command: []string{
+											"redis-cli",
+											"-p",
+											fmt.Sprintf("%d", sentinelPort),
+											"ping",
+										},
+									},
+								},
+								InitialDelaySeconds: 5,
+								PeriodSeconds:       10,
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "sentinel-data",
+							VolumeSource: corev1.VolumeSource{
+								EmptyDir: &corev1.EmptyDirVolumeSource{},
+							},
+						},
+						{
+							Name: "redis-config",
+							VolumeSource: corev1.VolumeSource{
+								ConfigMap: &corev1.ConfigMapVolumeSource{
+									LocalObjectReference: corev1.LocalObjectReference{
+										Name: configMap.Name,
+									},
+									Items: []corev1.KeyToPath{
+										{
+											Key:  "start-sentinel.sh",
+											Path: "start-sentinel.sh",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	// Set owner reference
+	if err := controllerutil.SetControllerReference(tenant, statefulSet, r.Scheme); err != nil {
+		logger.Error(err, "Failed to set owner reference on Redis Sentinel StatefulSet")
+		return nil, err
+	}
+
+	// Apply the StatefulSet
+	if err := r.apply(ctx, statefulSet); err != nil {
+		logger.Error(err, "Failed to apply Redis Sentinel StatefulSet")
+		return nil, err
+	}
+	logger.Info("Applied Redis Sentinel StatefulSet", "statefulSet", statefulSet.Name)
+	return statefulSet, nil
+}
+
+// reconcileRedisSentinelNetworkPolicy allows the operator to query Sentinel
+func (r *TenantReconciler) reconcileRedisSentinelNetworkPolicy(ctx context.Context, tenant *neurallogv1.Tenant) error {
+	logger := log.FromContext(ctx)
+
+	protocol := corev1.ProtocolTCP
+	port := intstr.FromInt(sentinelPort)
+	policy := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      redisSentinelNetworkPolicyName,
+			Namespace: tenant.Status.Namespace,
+			Labels: map[string]string{
+				"neurallog.io/tenant":    tenant.Name,
+				"neurallog.io/component": "network-policy",
+				"neurallog.io/policy":    "default",
+			},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": redisSentinelName,
+				},
+			},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{
+				{
+					From: []networkingv1.NetworkPolicyPeer{
+						{
+							NamespaceSelector: &metav1.LabelSelector{},
+							PodSelector: &metav1.LabelSelector{
+								MatchLabels: map[string]string{
+									"control-plane": "controller-manager",
+								},
+							},
+						},
+					},
+					Ports: []networkingv1.NetworkPolicyPort{
+						{
+							Protocol: &protocol,
+							Port:     &port,
+						},
+					},
+				},
+			},
+			PolicyTypes: []networkingv1.PolicyType{
+				networkingv1.PolicyTypeIngress,
+			},
+		},
+	}
+
+	// Set owner reference
+	if err := controllerutil.SetControllerReference(tenant, policy, r.Scheme); err != nil {
+		logger.Error(err, "Failed to set owner reference on Redis Sentinel network policy")
+		return err
+	}
+
+	// Apply the network policy
+	if err := r.apply(ctx, policy); err != nil {
+		logger.Error(err, "Failed to apply Redis Sentinel network policy")
+		return err
+	}
+	logger.Info("Applied Redis Sentinel network policy", "policy", policy.Name)
+	return nil
+}
+
+// reconcileRedisPrimary finds the current primary through Sentinel, labels the
+// Redis pods with their role so the primary Service follows it, and records failovers
+func (r *TenantReconciler) reconcileRedisPrimary(ctx context.Context, tenant *neurallogv1.Tenant) error {
+	logger := log.FromContext(ctx)
+	status := &tenant.Status.RedisStatus
+
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(tenant.Status.Namespace), client.MatchingLabels{"app": "redis"}); err != nil {
+		logger.Error(err, "Failed to list Redis pods")
+		return err
+	}
+
+	// Ask Sentinel for the primary, falling back to the last known one while
+	// Sentinel is unreachable and to the first pod while bootstrapping
+	primary := status.Primary
+	addr := fmt.Sprintf("%s.%s.svc:%d", redisSentinelName, tenant.Status.Namespace, sentinelPort)
+	host, err := getSentinelPrimary(ctx, addr, sentinelMasterName)
+	if err != nil {
+		logger.Info("Unable to query Redis Sentinel, keeping the last known primary", "error", err.Error())
+	} else if pod := redisPodForHost(pods.Items, host); pod != "" {
+		primary = pod
+	} else {
+		logger.Info("Redis primary reported by Sentinel doesn't match any pod", "host", host)
+	}
+	if primary == "" {
+		primary = "redis-0"
+	}
+
+	// Record failovers
+	if status.Primary != "" && status.Primary != primary {
+		logger.Info("Redis failover detected", "previousPrimary", status.Primary, "primary", primary)
+		now := metav1.Now()
+		status.LastFailoverTime = &now
+	}
+	status.Primary = primary
+
+	// Label the pods so the primary Service selects only the primary
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		role := "replica"
+		if pod.Name == primary {
+			role = "primary"
+		}
+		if pod.Labels[redisRoleLabel] == role {
+			continue
+		}
+
+		patch := client.MergeFrom(pod.DeepCopy())
+		if pod.Labels == nil {
+			pod.Labels = map[string]string{}
+		}
+		pod.Labels[redisRoleLabel] = role
+		if err := r.Patch(ctx, pod, patch); err != nil {
+			logger.Error(err, "Failed to label Redis pod", "pod", pod.Name, "role", role)
+			return err
+		}
+		logger.Info("Labeled Redis pod", "pod", pod.Name, "role", role)
+	}
+
+	return nil
+}
+
+// redisPodForHost returns the name of the pod a Sentinel-reported host refers
+// to. Sentinel reports either the pod's FQDN or its IP.
+func redisPodForHost(pods []corev1.Pod, host string) string {
+	for _, pod := range pods {
+		if host == pod.Name || strings.HasPrefix(host, pod.Name+".") || (pod.Status.PodIP != "" && host == pod.Status.PodIP) {
+			return pod.Name
+		}
+	}
+	return ""
+}
+
+// cleanupRedisSentinel removes the Sentinel resources when HA mode is disabled
+func (r *TenantReconciler) cleanupRedisSentinel(ctx context.Context, tenant *neurallogv1.Tenant) error {
+	logger := log.FromContext(ctx)
+	namespaceName := tenant.Status.Namespace
+
+	objects := []client.Object{
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: redisSentinelName, Namespace: namespaceName}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: redisSentinelName, Namespace: namespaceName}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: redisPrimaryServiceName, Namespace: namespaceName}},
+		&networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: redisSentinelNetworkPolicyName, Namespace: namespaceName}},
+	}
+	for _, obj := range objects {
+		if err := client.IgnoreNotFound(r.Delete(ctx, obj)); err != nil {
+			logger.Error(err, "Failed to delete Redis Sentinel resource", "name", obj.GetName())
+			return err
+		}
+	}
+
+	tenant.Status.RedisStatus.Primary = ""
+	tenant.Status.RedisStatus.LastFailoverTime = nil
+	return nil
+}
@@ -0,0 +1,338 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// reconcileRegistry creates or updates Endpoint Registry resources for the tenant
+func (r *TenantReconciler) reconcileRegistry(ctx context.Context, tenant *neurallogv1.Tenant) error {
+	logger := log.FromContext(ctx)
+	logger.Info("Reconciling Registry resources", "tenant", tenant.Name)
+
+	if tenant.Status.Namespace == "" {
+		logger.Info("Namespace not yet created, skipping Registry reconciliation")
+		return nil
+	}
+
+	// Create or update Registry ConfigMap
+	configMap, err := r.reconcileRegistryConfigMap(ctx, tenant)
+	if err != nil {
+		logger.Error(err, "Failed to reconcile Registry ConfigMap")
+		return err
+	}
+
+	// Create or update Registry Service
+	if _, err := r.reconcileRegistryService(ctx, tenant); err != nil {
+		logger.Error(err, "Failed to reconcile Registry Service")
+		return err
+	}
+
+	// Create or update Registry Deployment
+	deployment, err := r.reconcileRegistryDeployment(ctx, tenant, configMap)
+	if err != nil {
+		logger.Error(err, "Failed to reconcile Registry Deployment")
+		return err
+	}
+
+	return r.updateRegistryStatus(ctx, tenant, deployment)
+}
+
+// registrySpec returns the tenant's Registry configuration, empty if unset
+func registrySpec(tenant *neurallogv1.Tenant) neurallogv1.RegistrySpec {
+	if tenant.Spec.Registry == nil {
+		return neurallogv1.RegistrySpec{}
+	}
+	return *tenant.Spec.Registry
+}
+
+// registryEndpoints returns the endpoint URLs published by the Registry.
+// When a base domain is configured the endpoints are public hostnames below it,
+// otherwise they fall back to the in-cluster service addresses.
+func registryEndpoints(tenant *neurallogv1.Tenant) map[string]string {
+	baseDomain := registrySpec(tenant).BaseDomain
+	if baseDomain == "" {
+		return map[string]string{
+			"TENANT_ID":    tenant.Name,
+			"SERVER_URL":   fmt.Sprintf("http://neurallog-server.%s.svc:3030", tenant.Status.Namespace),
+			"AUTH_URL":     "http://auth:3000",
+			"REGISTRY_URL": fmt.Sprintf("http://%s-registry.%s.svc:3031", tenant.Name, tenant.Status.Namespace),
+		}
+	}
+
+	return map[string]string{
+		"TENANT_ID":    tenant.Name,
+		"BASE_DOMAIN":  baseDomain,
+		"SERVER_URL":   fmt.Sprintf("https://%s.%s", tenant.Name, baseDomain),
+		"AUTH_URL":     fmt.Sprintf("https://auth.%s", baseDomain),
+		"REGISTRY_URL": fmt.Sprintf("https://registry.%s.%s", tenant.Name, baseDomain),
+	}
+}
+
+// reconcileRegistryConfigMap creates or updates the Registry ConfigMap holding the endpoint URLs
+func (r *TenantReconciler) reconcileRegistryConfigMap(ctx context.Context, tenant *neurallogv1.Tenant) (*corev1.ConfigMap, error) {
+	logger := log.FromContext(ctx)
+
+	// Define ConfigMap
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-registry-config", tenant.Name),
+			Namespace: tenant.Status.Namespace,
+			Labels: map[string]string{
+				"app":                     "neurallog-registry",
+				"neurallog.io/tenant":     tenant.Name,
+				"neurallog.io/component":  "registry",
+				"neurallog.io/managed-by": "tenant-operator",
+			},
+		},
+		Data: registryEndpoints(tenant),
+	}
+
+	// Set owner reference
+	if err := controllerutil.SetControllerReference(tenant, configMap, r.Scheme); err != nil {
+		logger.Error(err, "Failed to set owner reference on Registry ConfigMap")
+		return nil, err
+	}
+
+	// Apply the ConfigMap
+	if err := r.apply(ctx, configMap); err != nil {
+		logger.Error(err, "Failed to apply Registry ConfigMap")
+		return nil, err
+	}
+	logger.Info("Applied Registry ConfigMap", "configMap", configMap.Name)
+	return configMap, nil
+}
+
+// reconcileRegistryService creates or updates the Registry Service
+func (r *TenantReconciler) reconcileRegistryService(ctx context.Context, tenant *neurallogv1.Tenant) (*corev1.Service, error) {
+	logger := log.FromContext(ctx)
+
+	// Define Service
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-registry", tenant.Name),
+			Namespace: tenant.Status.Namespace,
+			Labels: map[string]string{
+				"app":                     "neurallog-registry",
+				"neurallog.io/tenant":     tenant.Name,
+				"neurallog.io/component":  "registry",
+				"neurallog.io/managed-by": "tenant-operator",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{
+				"app":                 "neurallog-registry",
+				"neurallog.io/tenant": tenant.Name,
+			},
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "http",
+					Port:       3031,
+					TargetPort: intstr.FromString("http"),
+				},
+			},
+			Type: corev1.ServiceTypeClusterIP,
+		},
+	}
+
+	// Set owner reference
+	if err := controllerutil.SetControllerReference(tenant, service, r.Scheme); err != nil {
+		logger.Error(err, "Failed to set owner reference on Registry Service")
+		return nil, err
+	}
+
+	// Apply the Service
+	if err := r.apply(ctx, service); err != nil {
+		logger.Error(err, "Failed to apply Registry Service")
+		return nil, err
+	}
+	logger.Info("Applied Registry Service", "service", service.Name)
+	return service, nil
+}
+
+// reconcileRegistryDeployment creates or updates the Registry Deployment
+func (r *TenantReconciler) reconcileRegistryDeployment(ctx context.Context, tenant *neurallogv1.Tenant, configMap *corev1.ConfigMap) (*appsv1.Deployment, error) {
+	logger := log.FromContext(ctx)
+	registry := registrySpec(tenant)
+
+	// Define labels
+	labels := map[string]string{
+		"app":                     "neurallog-registry",
+		"neurallog.io/tenant":     tenant.Name,
+		"neurallog.io/component":  "registry",
+		"neurallog.io/managed-by": "tenant-operator",
+	}
+
+	// Default values, with the registry stopped while suspended
+	replicas := neurallogv1.DefaultReplicas
+	if registry.Replicas != nil {
+		replicas = *registry.Replicas
+	}
+	if tenant.Status.Suspension != nil {
+		replicas = 0
+	}
+
+	image := neurallogv1.DefaultRegistryImage
+	if registry.Image != "" {
+		image = registry.Image
+	}
+
+	// Resource requirements
+	resources, err := resourceRequirements(registry.Resources, neurallogv1.DefaultRegistryResources)
+	if err != nil {
+		logger.Error(err, "Invalid Registry resources")
+		return nil, err
+	}
+
+	// Create Deployment object
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-registry", tenant.Name),
+			Namespace: tenant.Status.Namespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app":                 "neurallog-registry",
+					"neurallog.io/tenant": tenant.Name,
+				},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  "registry",
+							Image: image,
+							Ports: []corev1.ContainerPort{
+								{
+									Name:          "http",
+									ContainerPort: 3031,
+								},
+							},
+							Env: []corev1.EnvVar{
+								{
+									Name:  "PORT",
+									Value: "3031",
+								},
+							},
+							EnvFrom: []corev1.EnvFromSource{
+								{
+									ConfigMapRef: &corev1.ConfigMapEnvSource{
+										LocalObjectReference: corev1.LocalObjectReference{
+											Name: configMap.Name,
+										},
+									},
+								},
+							},
+							Resources: resources,
+							LivenessProbe: &corev1.Probe{
+								ProbeHandler: corev1.ProbeHandler{
+									HTTPGet: &corev1.HTTPGetAction{
+										Path: "/health",
+										Port: intstr.FromString("http"),
+									},
+								},
+								InitialDelaySeconds: 15,
+								PeriodSeconds:       20,
+								TimeoutSeconds:      5,
+								FailureThreshold:    3,
+								SuccessThreshold:    1,
+							},
+							ReadinessProbe: &corev1.Probe{
+								ProbeHandler: corev1.ProbeHandler{
+									HTTPGet: &corev1.HTTPGetAction{
+										Path: "/health",
+										Port: intstr.FromString("http"),
+									},
+								},
+								InitialDelaySeconds: 5,
+								PeriodSeconds:       10,
+								TimeoutSeconds:      5,
+								FailureThreshold:    3,
+								SuccessThreshold:    1,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	// Set owner reference
+	if err := controllerutil.SetControllerReference(tenant, deployment, r.Scheme); err != nil {
+		logger.Error(err, "Failed to set owner reference on Registry Deployment")
+		return nil, err
+	}
+
+	// Apply the Deployment
+	if err := r.apply(ctx, deployment); err != nil {
+		logger.Error(err, "Failed to apply Registry Deployment")
+		return nil, err
+	}
+	logger.Info("Applied Registry Deployment", "deployment", deployment.Name)
+	return deployment, nil
+}
+
+// updateRegistryStatus updates the Registry status in the tenant
+func (r *TenantReconciler) updateRegistryStatus(ctx context.Context, tenant *neurallogv1.Tenant, deployment *appsv1.Deployment) error {
+	logger := log.FromContext(ctx)
+
+	// Update Registry status
+	tenant.Status.RegistryStatus = neurallogv1.ComponentStatus{
+		TotalReplicas: *deployment.Spec.Replicas,
+		ReadyReplicas: deployment.Status.ReadyReplicas,
+	}
+
+	// Set phase based on readiness
+	if tenant.Status.Suspension != nil {
+		tenant.Status.RegistryStatus.Phase = neurallogv1.ComponentPending
+		tenant.Status.RegistryStatus.Message = "Registry is suspended"
+	} else if deployment.Status.ReadyReplicas == 0 {
+		tenant.Status.RegistryStatus.Phase = neurallogv1.ComponentPending
+		tenant.Status.RegistryStatus.Message = "Registry is being provisioned"
+	} else if deployment.Status.ReadyReplicas < *deployment.Spec.Replicas {
+		tenant.Status.RegistryStatus.Phase = neurallogv1.ComponentPending
+		tenant.Status.RegistryStatus.Message = fmt.Sprintf("Registry is scaling up (%d/%d replicas ready)", deployment.Status.ReadyReplicas, *deployment.Spec.Replicas)
+	} else {
+		tenant.Status.RegistryStatus.Phase = neurallogv1.ComponentRunning
+		tenant.Status.RegistryStatus.Message = "Registry is running"
+	}
+
+	// Update tenant status
+	if err := r.updateStatus(ctx, tenant); err != nil {
+		logger.Error(err, "Failed to update Registry status")
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+)
+
+// resourceRequirements builds container resource requirements from a tenant
+// component spec, using defaults for any request or limit that isn't set.
+// Invalid quantities are returned as errors rather than panicking, since the
+// validating webhook may not be installed.
+func resourceRequirements(spec *neurallogv1.ResourceRequirements, defaults neurallogv1.ResourceRequirements) (corev1.ResourceRequirements, error) {
+	resources := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{},
+		Limits:   corev1.ResourceList{},
+	}
+	if spec == nil {
+		spec = &neurallogv1.ResourceRequirements{}
+	}
+	cpu, memory := resourceLimit(spec.CPU), resourceLimit(spec.Memory)
+	defaultCPU, defaultMemory := resourceLimit(defaults.CPU), resourceLimit(defaults.Memory)
+
+	for _, q := range []struct {
+		list     corev1.ResourceList
+		name     corev1.ResourceName
+		value    string
+		fallback string
+	}{
+		{resources.Requests, corev1.ResourceCPU, cpu.Request, defaultCPU.Request},
+		{resources.Limits, corev1.ResourceCPU, cpu.Limit, defaultCPU.Limit},
+		{resources.Requests, corev1.ResourceMemory, memory.Request, defaultMemory.Request},
+		{resources.Limits, corev1.ResourceMemory, memory.Limit, defaultMemory.Limit},
+	} {
+		value := q.value
+		if value == "" {
+			value = q.fallback
+		}
+		if value == "" {
+			continue
+		}
+		quantity, err := parseQuantity(value)
+		if err != nil {
+			return resources, fmt.Errorf("invalid %s quantity: %w", q.name, err)
+		}
+		q.list[q.name] = quantity
+	}
+
+	return resources, nil
+}
+
+// resourceLimit returns the request and limit of a resource, empty if unset
+func resourceLimit(limit *neurallogv1.ResourceLimit) neurallogv1.ResourceLimit {
+	if limit == nil {
+		return neurallogv1.ResourceLimit{}
+	}
+	return *limit
+}
+
+// parseQuantity parses a resource quantity from the tenant spec
+func parseQuantity(value string) (resource.Quantity, error) {
+	quantity, err := resource.ParseQuantity(value)
+	if err != nil {
+		return quantity, fmt.Errorf("%q: %w", value, err)
+	}
+	return quantity, nil
+}
@@ -0,0 +1,41 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+)
+
+// getReferencedSecret gets a Secret referenced by the tenant. The operator can
+// read Secrets in every namespace, so references outside the tenant namespace
+// and the operator namespace are refused even if the webhook let them through.
+func (r *TenantReconciler) getReferencedSecret(ctx context.Context, tenant *neurallogv1.Tenant, description string, ref neurallogv1.SecretReference) (*corev1.Secret, error) {
+	if !tenant.SecretNamespaceAllowed(ref.Namespace) {
+		return nil, fmt.Errorf("%s Secret %s/%s is not in the tenant namespace or the operator namespace", description, ref.Namespace, ref.Name)
+	}
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, client.ObjectKey{Name: ref.Name, Namespace: ref.Namespace}, secret); err != nil {
+		return nil, fmt.Errorf("failed to get %s Secret %s/%s: %w", description, ref.Namespace, ref.Name, err)
+	}
+	return secret, nil
+}
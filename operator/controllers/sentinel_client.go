@@ -0,0 +1,130 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sentinelTimeout bounds a single query to Redis Sentinel
+const sentinelTimeout = 5 * time.Second
+
+// getSentinelPrimary asks the Sentinel at addr for the host of the primary it
+// monitors under masterName
+func getSentinelPrimary(ctx context.Context, addr, masterName string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, sentinelTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return "", err
+	}
+
+	if _, err := io.WriteString(conn, encodeRESPCommand("SENTINEL", "get-master-addr-by-name", masterName)); err != nil {
+		return "", err
+	}
+
+	reply, err := readRESPArray(bufio.NewReader(conn))
+	if err != nil {
+		return "", err
+	}
+	if len(reply) != 2 {
+		return "", fmt.Errorf("Sentinel at %s doesn't monitor %q", addr, masterName)
+	}
+	return reply[0], nil
+}
+
+// encodeRESPCommand encodes a command as a RESP array of bulk strings
+func encodeRESPCommand(args ...string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	return b.String()
+}
+
+// readRESPArray reads a RESP array of bulk strings. A null array is returned as nil.
+func readRESPArray(r *bufio.Reader) ([]string, error) {
+	line, err := readRESPLine(r)
+	if err != nil {
+		return nil, err
+	}
+	switch line[0] {
+	case '*':
+	case '-':
+		return nil, fmt.Errorf("Sentinel returned an error: %s", line[1:])
+	default:
+		return nil, fmt.Errorf("unexpected reply from Sentinel: %q", line)
+	}
+
+	count, err := strconv.Atoi(line[1:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid array length from Sentinel: %q", line)
+	}
+	if count < 0 {
+		return nil, nil
+	}
+
+	values := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		line, err := readRESPLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if line[0] != '$' {
+			return nil, fmt.Errorf("unexpected array element from Sentinel: %q", line)
+		}
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("invalid bulk string length from Sentinel: %q", line)
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		values = append(values, string(data[:size]))
+	}
+	return values, nil
+}
+
+// readRESPLine reads a single non-empty RESP line without its terminator
+func readRESPLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+	if line == "" {
+		return "", fmt.Errorf("empty reply from Sentinel")
+	}
+	return line, nil
+}
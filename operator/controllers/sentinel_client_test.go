@@ -0,0 +1,93 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bufio"
+	"context"
+	"net"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Sentinel Client", func() {
+	var (
+		listener net.Listener
+		reply    string
+		received chan []string
+	)
+
+	BeforeEach(func() {
+		var err error
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		received = make(chan []string, 1)
+
+		go func() {
+			defer GinkgoRecover()
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			command, err := readRESPArray(bufio.NewReader(conn))
+			Expect(err).NotTo(HaveOccurred())
+			received <- command
+			_, _ = conn.Write([]byte(reply))
+		}()
+	})
+
+	AfterEach(func() {
+		listener.Close()
+	})
+
+	It("Should return the primary host reported by Sentinel", func() {
+		reply = "*2\r\n$35\r\nredis-1.redis.tenant-test.svc.local\r\n$4\r\n6379\r\n"
+
+		host, err := getSentinelPrimary(context.Background(), listener.Addr().String(), sentinelMasterName)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(host).To(Equal("redis-1.redis.tenant-test.svc.local"))
+		Expect(<-received).To(Equal([]string{"SENTINEL", "get-master-addr-by-name", sentinelMasterName}))
+	})
+
+	It("Should return an error when Sentinel doesn't know the primary", func() {
+		reply = "*-1\r\n"
+
+		_, err := getSentinelPrimary(context.Background(), listener.Addr().String(), sentinelMasterName)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should return Sentinel errors", func() {
+		reply = "-ERR unknown command\r\n"
+
+		_, err := getSentinelPrimary(context.Background(), listener.Addr().String(), sentinelMasterName)
+		Expect(err).To(MatchError(ContainSubstring("unknown command")))
+	})
+
+	It("Should map the reported host to a Redis pod", func() {
+		pods := []corev1.Pod{
+			{ObjectMeta: metav1.ObjectMeta{Name: "redis-0"}, Status: corev1.PodStatus{PodIP: "10.0.0.10"}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "redis-1"}, Status: corev1.PodStatus{PodIP: "10.0.0.11"}},
+		}
+		Expect(redisPodForHost(pods, "redis-1.redis.tenant-test.svc.cluster.local")).To(Equal("redis-1"))
+		Expect(redisPodForHost(pods, "10.0.0.10")).To(Equal("redis-0"))
+		Expect(redisPodForHost(pods, "10.0.0.12")).To(BeEmpty())
+	})
+})
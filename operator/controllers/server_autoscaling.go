@@ -0,0 +1,200 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+)
+
+// serverAutoscaled reports whether the server's replicas are managed by a
+// HorizontalPodAutoscaler. A stopped server is scaled to zero by the operator,
+// which also pauses the autoscaler.
+func serverAutoscaled(tenant *neurallogv1.Tenant) bool {
+	return serverSpec(tenant).Autoscaling != nil && stoppedReason(tenant) == ""
+}
+
+// serverMinReplicas returns the lower limit for the number of server instances
+func serverMinReplicas(autoscaling *neurallogv1.AutoscalingSpec) int32 {
+	if autoscaling.MinReplicas != nil {
+		return *autoscaling.MinReplicas
+	}
+	return neurallogv1.DefaultReplicas
+}
+
+// reconcileServerAutoscaling creates or updates the HorizontalPodAutoscaler and
+// PodDisruptionBudget of the server, or removes them without autoscaling
+func (r *TenantReconciler) reconcileServerAutoscaling(ctx context.Context, tenant *neurallogv1.Tenant) error {
+	logger := log.FromContext(ctx)
+	namespaceName := tenant.Status.Namespace
+
+	autoscaling := serverSpec(tenant).Autoscaling
+	if autoscaling == nil {
+		for _, obj := range []client.Object{
+			&autoscalingv2.HorizontalPodAutoscaler{ObjectMeta: metav1.ObjectMeta{Name: "neurallog-server", Namespace: namespaceName}},
+			&policyv1.PodDisruptionBudget{ObjectMeta: metav1.ObjectMeta{Name: "neurallog-server", Namespace: namespaceName}},
+		} {
+			if err := client.IgnoreNotFound(r.Delete(ctx, obj)); err != nil {
+				logger.Error(err, "Failed to delete Server autoscaling resource", "name", obj.GetName())
+				return err
+			}
+		}
+		return nil
+	}
+
+	labels := map[string]string{
+		"app":                    "neurallog-server",
+		"neurallog.io/tenant":    tenant.Name,
+		"neurallog.io/component": "server",
+	}
+	minReplicas := serverMinReplicas(autoscaling)
+
+	metrics, err := serverMetrics(autoscaling)
+	if err != nil {
+		return err
+	}
+
+	// Define the HorizontalPodAutoscaler
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "neurallog-server",
+			Namespace: namespaceName,
+			Labels:    labels,
+		},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       "neurallog-server",
+			},
+			MinReplicas: &minReplicas,
+			MaxReplicas: autoscaling.MaxReplicas,
+			Metrics:     metrics,
+		},
+	}
+	if err := controllerutil.SetControllerReference(tenant, hpa, r.Scheme); err != nil {
+		logger.Error(err, "Failed to set owner reference on Server HorizontalPodAutoscaler")
+		return err
+	}
+	if err := r.apply(ctx, hpa); err != nil {
+		logger.Error(err, "Failed to apply Server HorizontalPodAutoscaler")
+		return err
+	}
+	logger.Info("Applied Server HorizontalPodAutoscaler", "horizontalPodAutoscaler", hpa.Name)
+
+	// Keep all but one of the minimum replicas available during voluntary
+	// disruptions, so a node drain can always proceed
+	pdb := &policyv1.PodDisruptionBudget{ObjectMeta: metav1.ObjectMeta{Name: "neurallog-server", Namespace: namespaceName}}
+	minAvailable := minReplicas - 1
+	if minAvailable < 1 {
+		if err := client.IgnoreNotFound(r.Delete(ctx, pdb)); err != nil {
+			logger.Error(err, "Failed to delete Server PodDisruptionBudget")
+			return err
+		}
+		return nil
+	}
+
+	pdb.Labels = labels
+	pdb.Spec = policyv1.PodDisruptionBudgetSpec{
+		MinAvailable: &intstr.IntOrString{Type: intstr.Int, IntVal: minAvailable},
+		Selector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{
+				"app": "neurallog-server",
+			},
+		},
+	}
+	if err := controllerutil.SetControllerReference(tenant, pdb, r.Scheme); err != nil {
+		logger.Error(err, "Failed to set owner reference on Server PodDisruptionBudget")
+		return err
+	}
+	if err := r.apply(ctx, pdb); err != nil {
+		logger.Error(err, "Failed to apply Server PodDisruptionBudget")
+		return err
+	}
+	logger.Info("Applied Server PodDisruptionBudget", "podDisruptionBudget", pdb.Name)
+	return nil
+}
+
+// serverMetrics returns the metrics the server is scaled on
+func serverMetrics(autoscaling *neurallogv1.AutoscalingSpec) ([]autoscalingv2.MetricSpec, error) {
+	var metrics []autoscalingv2.MetricSpec
+	for _, target := range []struct {
+		name        corev1.ResourceName
+		utilization *int32
+	}{
+		{corev1.ResourceCPU, autoscaling.TargetCPUUtilization},
+		{corev1.ResourceMemory, autoscaling.TargetMemoryUtilization},
+	} {
+		if target.utilization == nil {
+			continue
+		}
+		metrics = append(metrics, autoscalingv2.MetricSpec{
+			Type: autoscalingv2.ResourceMetricSourceType,
+			Resource: &autoscalingv2.ResourceMetricSource{
+				Name: target.name,
+				Target: autoscalingv2.MetricTarget{
+					Type:               autoscalingv2.UtilizationMetricType,
+					AverageUtilization: target.utilization,
+				},
+			},
+		})
+	}
+
+	for _, metric := range autoscaling.Metrics {
+		value, err := parseQuantity(metric.TargetAverageValue)
+		if err != nil {
+			return nil, fmt.Errorf("invalid target of metric %s: %w", metric.Name, err)
+		}
+		metrics = append(metrics, autoscalingv2.MetricSpec{
+			Type: autoscalingv2.PodsMetricSourceType,
+			Pods: &autoscalingv2.PodsMetricSource{
+				Metric: autoscalingv2.MetricIdentifier{Name: metric.Name},
+				Target: autoscalingv2.MetricTarget{
+					Type:         autoscalingv2.AverageValueMetricType,
+					AverageValue: &value,
+				},
+			},
+		})
+	}
+
+	// Scale on CPU if nothing else is configured
+	if len(metrics) == 0 {
+		utilization := neurallogv1.DefaultTargetCPUUtilization
+		metrics = append(metrics, autoscalingv2.MetricSpec{
+			Type: autoscalingv2.ResourceMetricSourceType,
+			Resource: &autoscalingv2.ResourceMetricSource{
+				Name: corev1.ResourceCPU,
+				Target: autoscalingv2.MetricTarget{
+					Type:               autoscalingv2.UtilizationMetricType,
+					AverageUtilization: &utilization,
+				},
+			},
+		})
+	}
+	return metrics, nil
+}
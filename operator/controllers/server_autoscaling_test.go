@@ -0,0 +1,160 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+)
+
+var _ = Describe("Server autoscaling", func() {
+	var tenant *neurallogv1.Tenant
+
+	BeforeEach(func() {
+		minReplicas := int32(2)
+		tenant = &neurallogv1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-tenant"},
+			Spec: neurallogv1.TenantSpec{
+				Server: &neurallogv1.ServerSpec{
+					Autoscaling: &neurallogv1.AutoscalingSpec{MinReplicas: &minReplicas, MaxReplicas: 10},
+				},
+			},
+			Status: neurallogv1.TenantStatus{Namespace: "tenant-test-tenant"},
+		}
+	})
+
+	It("Should leave the replicas to the autoscaler while the tenant runs", func() {
+		Expect(serverAutoscaled(tenant)).To(BeTrue())
+		Expect(serverReplicas(tenant)).To(Equal(int32(2)))
+
+		tenant.Status.Suspension = &neurallogv1.SuspensionStatus{Reason: neurallogv1.SuspensionRequested}
+		Expect(serverAutoscaled(tenant)).To(BeFalse())
+	})
+
+	It("Should keep the current replicas until the autoscaler sets them", func() {
+		// A new Deployment starts at the minimum
+		Expect(*serverDeploymentReplicas(tenant, nil)).To(Equal(int32(2)))
+
+		// Turning autoscaling on keeps the replicas the server runs with
+		replicas := int32(5)
+		existing := &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Replicas: &replicas}}
+		Expect(*serverDeploymentReplicas(tenant, existing)).To(Equal(int32(5)))
+
+		// Once the autoscaler has set them, they are left out
+		existing.ManagedFields = []metav1.ManagedFieldsEntry{
+			{Manager: fieldManager, FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:replicas":{},"f:template":{}}}`)}},
+			{Manager: "kube-controller-manager", Subresource: "scale", FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:replicas":{}}}`)}},
+		}
+		Expect(serverDeploymentReplicas(tenant, existing)).To(BeNil())
+	})
+
+	It("Should start a resumed server with the replicas it was stopped with", func() {
+		replicas := int32(7)
+		existing := &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Replicas: &replicas}}
+
+		tenant.Status.Suspension = &neurallogv1.SuspensionStatus{Reason: neurallogv1.SuspensionRequested}
+		Expect(*serverDeploymentReplicas(tenant, existing)).To(BeZero())
+
+		// Resuming, the Deployment is stopped and the recorded replicas are restored
+		tenant.Status.Suspension = nil
+		tenant.Status.StoppedServerReplicas = &replicas
+		stopped := int32(0)
+		existing.Spec.Replicas = &stopped
+		Expect(*serverDeploymentReplicas(tenant, existing)).To(Equal(int32(7)))
+
+		// Within the autoscaling bounds
+		tenant.Spec.Server.Autoscaling.MaxReplicas = 4
+		Expect(*serverDeploymentReplicas(tenant, existing)).To(Equal(int32(4)))
+	})
+
+	It("Should record the replicas when an autoscaled server is stopped", func() {
+		replicas := int32(6)
+		existing := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "neurallog-server", Namespace: "tenant-test-tenant"},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		}
+		tenant.Status.Suspension = &neurallogv1.SuspensionStatus{Reason: neurallogv1.SuspensionRequested}
+		s := runtime.NewScheme()
+		Expect(scheme.AddToScheme(s)).To(Succeed())
+		Expect(neurallogv1.AddToScheme(s)).To(Succeed())
+		r := &TenantReconciler{
+			Client: fake.NewClientBuilder().WithScheme(s).WithObjects(tenant, existing).WithStatusSubresource(tenant).
+				WithInterceptorFuncs(interceptor.Funcs{Patch: applyWithUpdate}).Build(),
+			Scheme: s,
+		}
+		Expect(r.Get(context.Background(), client.ObjectKeyFromObject(tenant), tenant)).To(Succeed())
+
+		deployment, err := r.reconcileServerDeployment(context.Background(), tenant)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*deployment.Spec.Replicas).To(BeZero())
+		Expect(tenant.Status.StoppedServerReplicas).NotTo(BeNil())
+		Expect(*tenant.Status.StoppedServerReplicas).To(Equal(int32(6)))
+
+		stored := &neurallogv1.Tenant{}
+		Expect(r.Get(context.Background(), client.ObjectKeyFromObject(tenant), stored)).To(Succeed())
+		Expect(*stored.Status.StoppedServerReplicas).To(Equal(int32(6)))
+	})
+
+	It("Should scale on CPU if no target is set", func() {
+		metrics, err := serverMetrics(tenant.Spec.Server.Autoscaling)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(metrics).To(HaveLen(1))
+		Expect(metrics[0].Resource.Name).To(Equal(corev1.ResourceCPU))
+		Expect(*metrics[0].Resource.Target.AverageUtilization).To(Equal(neurallogv1.DefaultTargetCPUUtilization))
+	})
+
+	It("Should scale on custom pod metrics", func() {
+		tenant.Spec.Server.Autoscaling.Metrics = []neurallogv1.AutoscalingMetric{
+			{Name: "neurallog_log_ingest_rate", TargetAverageValue: "500"},
+		}
+		metrics, err := serverMetrics(tenant.Spec.Server.Autoscaling)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(metrics).To(HaveLen(1))
+		Expect(metrics[0].Type).To(Equal(autoscalingv2.PodsMetricSourceType))
+		Expect(metrics[0].Pods.Metric.Name).To(Equal("neurallog_log_ingest_rate"))
+		Expect(metrics[0].Pods.Target.AverageValue.Cmp(resource.MustParse("500"))).To(BeZero())
+	})
+
+	It("Should remove the autoscaler and disruption budget when autoscaling is turned off", func() {
+		hpa := &autoscalingv2.HorizontalPodAutoscaler{ObjectMeta: metav1.ObjectMeta{Name: "neurallog-server", Namespace: "tenant-test-tenant"}}
+		pdb := &policyv1.PodDisruptionBudget{ObjectMeta: metav1.ObjectMeta{Name: "neurallog-server", Namespace: "tenant-test-tenant"}}
+		r := &TenantReconciler{Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(hpa, pdb).Build()}
+
+		tenant.Spec.Server.Autoscaling = nil
+		Expect(r.reconcileServerAutoscaling(context.Background(), tenant)).To(Succeed())
+		for _, obj := range []client.Object{hpa, pdb} {
+			err := r.Get(context.Background(), client.ObjectKeyFromObject(obj), obj)
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		}
+	})
+})
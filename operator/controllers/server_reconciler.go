@@ -18,10 +18,10 @@ package controllers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 
 	"k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -33,46 +33,96 @@ import (
 	corev1 "k8s.io/api/core/v1"
 )
 
-// reconcileServerDeployment creates or updates the Server Deployment
-func (r *TenantReconciler) reconcileServerDeployment(ctx context.Context, tenant *neurallogv1.Tenant) (*appsv1.Deployment, error) {
+// reconcileServer creates or updates Server resources for the tenant
+func (r *TenantReconciler) reconcileServer(ctx context.Context, tenant *neurallogv1.Tenant) error {
 	logger := log.FromContext(ctx)
-	namespaceName := tenant.Status.Namespace
+	logger.Info("Reconciling Server resources", "tenant", tenant.Name)
 
-	// Default values
-	replicas := int32(1)
-	if tenant.Spec.Server.Replicas != nil {
-		replicas = *tenant.Spec.Server.Replicas
+	if tenant.Status.Namespace == "" {
+		logger.Info("Namespace not yet created, skipping Server reconciliation")
+		return nil
 	}
 
-	image := "neurallog/server:latest"
-	if tenant.Spec.Server.Image != "" {
-		image = tenant.Spec.Server.Image
+	// Create or update Server Service
+	if _, err := r.reconcileServerService(ctx, tenant); err != nil {
+		logger.Error(err, "Failed to reconcile Server Service")
+		return err
 	}
 
-	// Resource requirements
-	resources := corev1.ResourceRequirements{
-		Requests: corev1.ResourceList{
-			corev1.ResourceCPU:    resource.MustParse("100m"),
-			corev1.ResourceMemory: resource.MustParse("128Mi"),
-		},
-		Limits: corev1.ResourceList{
-			corev1.ResourceCPU:    resource.MustParse("500m"),
-			corev1.ResourceMemory: resource.MustParse("512Mi"),
-		},
+	// Create or update Server Deployment
+	deployment, err := r.reconcileServerDeployment(ctx, tenant)
+	if err != nil {
+		logger.Error(err, "Failed to reconcile Server Deployment")
+		return err
+	}
+
+	// Create, update or remove the Server autoscaler
+	if err := r.reconcileServerAutoscaling(ctx, tenant); err != nil {
+		logger.Error(err, "Failed to reconcile Server autoscaling")
+		return err
+	}
+
+	return r.updateServerStatus(ctx, tenant, deployment)
+}
+
+// serverSpec returns the tenant's server configuration, empty if unset
+func serverSpec(tenant *neurallogv1.Tenant) neurallogv1.ServerSpec {
+	if tenant.Spec.Server == nil {
+		return neurallogv1.ServerSpec{}
 	}
+	return *tenant.Spec.Server
+}
 
-	// Apply custom resource requirements if provided
-	if tenant.Spec.Server.Resources.CPU.Request != "" {
-		resources.Requests[corev1.ResourceCPU] = resource.MustParse(tenant.Spec.Server.Resources.CPU.Request)
+// serverReplicas returns the number of server instances the tenant runs, at
+// least, with autoscaling
+func serverReplicas(tenant *neurallogv1.Tenant) int32 {
+	server := serverSpec(tenant)
+	if autoscaling := server.Autoscaling; autoscaling != nil {
+		return serverMinReplicas(autoscaling)
 	}
-	if tenant.Spec.Server.Resources.CPU.Limit != "" {
-		resources.Limits[corev1.ResourceCPU] = resource.MustParse(tenant.Spec.Server.Resources.CPU.Limit)
+	if server.Replicas != nil {
+		return *server.Replicas
 	}
-	if tenant.Spec.Server.Resources.Memory.Request != "" {
-		resources.Requests[corev1.ResourceMemory] = resource.MustParse(tenant.Spec.Server.Resources.Memory.Request)
+	return neurallogv1.DefaultReplicas
+}
+
+// reconcileServerDeployment creates or updates the Server Deployment
+func (r *TenantReconciler) reconcileServerDeployment(ctx context.Context, tenant *neurallogv1.Tenant) (*appsv1.Deployment, error) {
+	logger := log.FromContext(ctx)
+	namespaceName := tenant.Status.Namespace
+	server := serverSpec(tenant)
+
+	// The replicas depend on the running Deployment while it is autoscaled
+	existing := &appsv1.Deployment{}
+	if err := r.Get(ctx, client.ObjectKey{Name: "neurallog-server", Namespace: namespaceName}, existing); err != nil {
+		if !errors.IsNotFound(err) {
+			logger.Error(err, "Failed to get Server Deployment")
+			return nil, err
+		}
+		existing = nil
 	}
-	if tenant.Spec.Server.Resources.Memory.Limit != "" {
-		resources.Limits[corev1.ResourceMemory] = resource.MustParse(tenant.Spec.Server.Resources.Memory.Limit)
+
+	// Remember the autoscaled replicas before the server is stopped, so it
+	// starts again at the same scale
+	if stoppedReason(tenant) != "" && server.Autoscaling != nil && tenant.Status.StoppedServerReplicas == nil &&
+		existing != nil && existing.Spec.Replicas != nil && *existing.Spec.Replicas > 0 {
+		tenant.Status.StoppedServerReplicas = existing.Spec.Replicas
+		if err := r.updateStatus(ctx, tenant); err != nil {
+			logger.Error(err, "Failed to record the Server replicas")
+			return nil, err
+		}
+	}
+
+	image := neurallogv1.DefaultServerImage
+	if server.Image != "" {
+		image = server.Image
+	}
+
+	// Resource requirements
+	resources, err := resourceRequirements(server.Resources, neurallogv1.DefaultServerResources)
+	if err != nil {
+		logger.Error(err, "Invalid Server resources")
+		return nil, err
 	}
 
 	// Environment variables
@@ -87,7 +137,7 @@ func (r *TenantReconciler) reconcileServerDeployment(ctx context.Context, tenant
 		},
 		{
 			Name:  "REDIS_URL",
-			Value: "redis://redis:6379",
+			Value: redisURL(tenant),
 		},
 		{
 			Name:  "LOG_LEVEL",
@@ -99,9 +149,12 @@ func (r *TenantReconciler) reconcileServerDeployment(ctx context.Context, tenant
 		},
 	}
 
+	// Connect the server to the tenant's OpenFGA store
+	env = append(env, r.openFGAEnv(tenant)...)
+
 	// Add custom environment variables if provided
-	if tenant.Spec.Server.Env != nil {
-		for _, envVar := range tenant.Spec.Server.Env {
+	if server.Env != nil {
+		for _, envVar := range server.Env {
 			kubeEnvVar := corev1.EnvVar{
 				Name:  envVar.Name,
 				Value: envVar.Value,
@@ -143,7 +196,6 @@ func (r *TenantReconciler) reconcileServerDeployment(ctx context.Context, tenant
 			},
 		},
 		Spec: appsv1.DeploymentSpec{
-			Replicas: &replicas,
 			Selector: &metav1.LabelSelector{
 				MatchLabels: map[string]string{
 					"app": "neurallog-server",
@@ -217,41 +269,80 @@ func (r *TenantReconciler) reconcileServerDeployment(ctx context.Context, tenant
 		},
 	}
 
+	deployment.Spec.Replicas = serverDeploymentReplicas(tenant, existing)
+
 	// Set owner reference
 	if err := controllerutil.SetControllerReference(tenant, deployment, r.Scheme); err != nil {
 		logger.Error(err, "Failed to set owner reference on Server Deployment")
 		return nil, err
 	}
 
-	// Create or update the Deployment
-	existingDeployment := &appsv1.Deployment{}
-	err := r.Get(ctx, client.ObjectKey{Name: deployment.Name, Namespace: deployment.Namespace}, existingDeployment)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			// Create Deployment
-			if err := r.Create(ctx, deployment); err != nil {
-				logger.Error(err, "Failed to create Server Deployment")
-				return nil, err
-			}
-			logger.Info("Created Server Deployment", "deployment", deployment.Name)
-			return deployment, nil
-		}
-		logger.Error(err, "Failed to get Server Deployment")
+	// Apply the Deployment
+	if err := r.apply(ctx, deployment); err != nil {
+		logger.Error(err, "Failed to apply Server Deployment")
 		return nil, err
 	}
+	logger.Info("Applied Server Deployment", "deployment", deployment.Name)
 
-	// Update Deployment if it exists
-	existingDeployment.Spec.Replicas = deployment.Spec.Replicas
-	existingDeployment.Spec.Template.Spec.Containers[0].Image = deployment.Spec.Template.Spec.Containers[0].Image
-	existingDeployment.Spec.Template.Spec.Containers[0].Resources = deployment.Spec.Template.Spec.Containers[0].Resources
-	existingDeployment.Spec.Template.Spec.Containers[0].Env = deployment.Spec.Template.Spec.Containers[0].Env
-	
-	if err := r.Update(ctx, existingDeployment); err != nil {
-		logger.Error(err, "Failed to update Server Deployment")
-		return nil, err
+	// The recorded replicas are restored, updateServerStatus saves the status
+	if stoppedReason(tenant) == "" {
+		tenant.Status.StoppedServerReplicas = nil
+	}
+	return deployment, nil
+}
+
+// serverDeploymentReplicas returns the replicas to apply to the server
+// Deployment, or nil to leave them to the autoscaler.
+//
+// With server-side apply, a field the operator stops applying is removed if
+// no other manager has set it, and the Deployment falls back to one replica.
+// So when autoscaling is turned on or the server starts again, the operator
+// keeps applying the current or recorded replicas until the autoscaler has set
+// them itself.
+func serverDeploymentReplicas(tenant *neurallogv1.Tenant, existing *appsv1.Deployment) *int32 {
+	var replicas int32
+	switch {
+	case stoppedReason(tenant) != "":
+		replicas = 0
+	case !serverAutoscaled(tenant):
+		replicas = serverReplicas(tenant)
+	case tenant.Status.StoppedServerReplicas != nil:
+		replicas = *tenant.Status.StoppedServerReplicas
+		autoscaling := serverSpec(tenant).Autoscaling
+		if minReplicas := serverMinReplicas(autoscaling); replicas < minReplicas {
+			replicas = minReplicas
+		}
+		if replicas > autoscaling.MaxReplicas {
+			replicas = autoscaling.MaxReplicas
+		}
+	case existing == nil || existing.Spec.Replicas == nil || *existing.Spec.Replicas == 0:
+		replicas = serverReplicas(tenant)
+	case replicasSetByOthers(existing):
+		return nil
+	default:
+		replicas = *existing.Spec.Replicas
 	}
-	logger.Info("Updated Server Deployment", "deployment", existingDeployment.Name)
-	return existingDeployment, nil
+	return &replicas
+}
+
+// replicasSetByOthers reports whether a field manager other than the operator,
+// such as the autoscaler, has set the replicas of the Deployment
+func replicasSetByOthers(deployment *appsv1.Deployment) bool {
+	for _, entry := range deployment.ManagedFields {
+		if entry.Manager == fieldManager || entry.FieldsV1 == nil {
+			continue
+		}
+		var fields struct {
+			Spec map[string]json.RawMessage `json:"f:spec"`
+		}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			continue
+		}
+		if _, ok := fields.Spec["f:replicas"]; ok {
+			return true
+		}
+	}
+	return false
 }
 
 // reconcileServerService creates or updates the Server Service
@@ -291,31 +382,13 @@ func (r *TenantReconciler) reconcileServerService(ctx context.Context, tenant *n
 		return nil, err
 	}
 
-	// Create or update the Service
-	existingService := &corev1.Service{}
-	err := r.Get(ctx, client.ObjectKey{Name: service.Name, Namespace: service.Namespace}, existingService)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			// Create Service
-			if err := r.Create(ctx, service); err != nil {
-				logger.Error(err, "Failed to create Server Service")
-				return nil, err
-			}
-			logger.Info("Created Server Service", "service", service.Name)
-			return service, nil
-		}
-		logger.Error(err, "Failed to get Server Service")
+	// Apply the Service
+	if err := r.apply(ctx, service); err != nil {
+		logger.Error(err, "Failed to apply Server Service")
 		return nil, err
 	}
-
-	// Update Service if it exists
-	existingService.Spec.Ports = service.Spec.Ports
-	if err := r.Update(ctx, existingService); err != nil {
-		logger.Error(err, "Failed to update Server Service")
-		return nil, err
-	}
-	logger.Info("Updated Server Service", "service", existingService.Name)
-	return existingService, nil
+	logger.Info("Applied Server Service", "service", service.Name)
+	return service, nil
 }
 
 // updateServerStatus updates the Server status in the tenant
@@ -329,7 +402,10 @@ func (r *TenantReconciler) updateServerStatus(ctx context.Context, tenant *neura
 	}
 
 	// Set phase based on readiness
-	if deployment.Status.ReadyReplicas == 0 {
+	if reason := stoppedReason(tenant); reason != "" {
+		tenant.Status.ServerStatus.Phase = neurallogv1.ComponentPending
+		tenant.Status.ServerStatus.Message = fmt.Sprintf("Server is %s", reason)
+	} else if deployment.Status.ReadyReplicas == 0 {
 		tenant.Status.ServerStatus.Phase = neurallogv1.ComponentPending
 		tenant.Status.ServerStatus.Message = "Server is being provisioned"
 	} else if deployment.Status.ReadyReplicas < *deployment.Spec.Replicas {
@@ -341,7 +417,7 @@ func (r *TenantReconciler) updateServerStatus(ctx context.Context, tenant *neura
 	}
 
 	// Update tenant status
-	if err := r.Status().Update(ctx, tenant); err != nil {
+	if err := r.updateStatus(ctx, tenant); err != nil {
 		logger.Error(err, "Failed to update Server status")
 		return err
 	}
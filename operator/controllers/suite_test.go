@@ -18,8 +18,14 @@ package controllers
 
 import (
 	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"path/filepath"
 	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -30,8 +36,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	neurallogv1 "github.com/neurallog/operator/api/v1"
+	neurallogv2 "github.com/neurallog/operator/api/v2"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -43,6 +51,7 @@ var k8sClient client.Client
 var testEnv *envtest.Environment
 var ctx context.Context
 var cancel context.CancelFunc
+var mockAuthServer *httptest.Server
 
 func TestControllers(t *testing.T) {
 	RegisterFailHandler(Fail)
@@ -55,33 +64,76 @@ var _ = BeforeSuite(func() {
 
 	ctx, cancel = context.WithCancel(context.TODO())
 
+	// Both Tenant versions are registered before the environment starts, so
+	// envtest points the CRD's conversion webhook at the manager below
+	err := neurallogv1.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
+	err = neurallogv2.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
+
+	//+kubebuilder:scaffold:scheme
+
 	By("bootstrapping test environment")
 	testEnv = &envtest.Environment{
 		CRDDirectoryPaths:     []string{filepath.Join("..", "config", "crd", "bases")},
 		ErrorIfCRDPathMissing: true,
 	}
 
-	var err error
 	// cfg is defined in this file globally.
 	cfg, err = testEnv.Start()
 	Expect(err).NotTo(HaveOccurred())
 	Expect(cfg).NotTo(BeNil())
 
-	err = neurallogv1.AddToScheme(scheme.Scheme)
-	Expect(err).NotTo(HaveOccurred())
-
-	//+kubebuilder:scaffold:scheme
-
 	k8sClient, err = client.New(cfg, client.Options{Scheme: scheme.Scheme})
 	Expect(err).NotTo(HaveOccurred())
 	Expect(k8sClient).NotTo(BeNil())
 
+	webhookOptions := testEnv.WebhookInstallOptions
 	k8sManager, err := ctrl.NewManager(cfg, ctrl.Options{
 		Scheme: scheme.Scheme,
+		WebhookServer: webhook.NewServer(webhook.Options{
+			Host:    webhookOptions.LocalServingHost,
+			Port:    webhookOptions.LocalServingPort,
+			CertDir: webhookOptions.LocalServingCertDir,
+		}),
 	})
 	Expect(err).ToNot(HaveOccurred())
 
+	err = (&neurallogv2.Tenant{}).SetupWebhookWithManager(k8sManager)
+	Expect(err).ToNot(HaveOccurred())
+
+	// Mock Auth Service
+	mockAuthServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodGet:
+			// List tenants
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintln(w, `{"status":"success","tenants":[]}`)
+		case http.MethodPost:
+			// Create tenant
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintln(w, `{"status":"success","message":"Tenant created successfully","admin":{"userId":"test-admin","password":"generated-password"}}`)
+		case http.MethodDelete:
+			// Delete tenant
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintln(w, `{"status":"success","message":"Tenant deleted successfully"}`)
+		}
+	}))
+
+	authClient, err := NewAuthClient(AuthClientConfig{URL: mockAuthServer.URL})
+	Expect(err).ToNot(HaveOccurred())
+
 	err = (&TenantReconciler{
+		Client:      k8sManager.GetClient(),
+		Scheme:      k8sManager.GetScheme(),
+		AuthClient:  authClient,
+		AuthTenants: NewAuthTenantCache(authClient, 0),
+		Recorder:    k8sManager.GetEventRecorderFor("tenant-controller"),
+	}).SetupWithManager(k8sManager)
+	Expect(err).ToNot(HaveOccurred())
+
+	err = (&TenantRestoreReconciler{
 		Client: k8sManager.GetClient(),
 		Scheme: k8sManager.GetScheme(),
 	}).SetupWithManager(k8sManager)
@@ -92,11 +144,31 @@ var _ = BeforeSuite(func() {
 		err = k8sManager.Start(ctx)
 		Expect(err).ToNot(HaveOccurred(), "failed to run manager")
 	}()
+
+	// Tenants are stored as v2, so nothing can be written until the
+	// conversion webhook is serving
+	dialer := &net.Dialer{Timeout: time.Second}
+	addrPort := fmt.Sprintf("%s:%d", webhookOptions.LocalServingHost, webhookOptions.LocalServingPort)
+	Eventually(func() error {
+		conn, err := tls.DialWithDialer(dialer, "tcp", addrPort, &tls.Config{InsecureSkipVerify: true})
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}).Should(Succeed())
 })
 
 var _ = AfterSuite(func() {
-	cancel()
-	By("tearing down the test environment")
-	err := testEnv.Stop()
-	Expect(err).NotTo(HaveOccurred())
+	// BeforeSuite may have failed before starting everything
+	if cancel != nil {
+		cancel()
+	}
+	if mockAuthServer != nil {
+		mockAuthServer.Close()
+	}
+	if cfg != nil {
+		By("tearing down the test environment")
+		err := testEnv.Stop()
+		Expect(err).NotTo(HaveOccurred())
+	}
 })
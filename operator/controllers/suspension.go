@@ -0,0 +1,87 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+)
+
+// reconcileSuspension records in the status whether the tenant is suspended.
+// The component reconcilers scale a suspended tenant's workloads to zero and
+// back to their configured replicas on resume. It returns how long until an
+// active tenant with an idle timeout becomes idle, or zero.
+func (r *TenantReconciler) reconcileSuspension(ctx context.Context, tenant *neurallogv1.Tenant, now time.Time) time.Duration {
+	logger := log.FromContext(ctx)
+
+	reason, idleIn := suspensionReason(tenant, now)
+	switch {
+	case reason == "" && tenant.Status.Suspension != nil:
+		logger.Info("Resuming Tenant", "tenant", tenant.Name)
+		tenant.Status.Suspension = nil
+	case reason != "" && tenant.Status.Suspension == nil:
+		logger.Info("Suspending Tenant", "tenant", tenant.Name, "reason", reason)
+		since := metav1.NewTime(now)
+		tenant.Status.Suspension = &neurallogv1.SuspensionStatus{Reason: reason, Since: &since}
+	case reason != "":
+		tenant.Status.Suspension.Reason = reason
+	}
+	return idleIn
+}
+
+// suspensionReason returns why the tenant should be suspended, or an empty
+// reason and how long until it becomes idle
+func suspensionReason(tenant *neurallogv1.Tenant, now time.Time) (neurallogv1.SuspensionReason, time.Duration) {
+	if tenant.Spec.Suspended {
+		return neurallogv1.SuspensionRequested, 0
+	}
+	if tenant.Spec.IdleTimeout == nil || tenant.Spec.IdleTimeout.Duration <= 0 {
+		return "", 0
+	}
+
+	// Without recorded activity, the tenant has been idle since it was created
+	lastActivity := tenant.CreationTimestamp.Time
+	if value, ok := tenant.Annotations[neurallogv1.LastActivityAnnotation]; ok {
+		if activity, err := time.Parse(time.RFC3339, value); err == nil && activity.After(lastActivity) {
+			lastActivity = activity
+		}
+	}
+
+	idleAt := lastActivity.Add(tenant.Spec.IdleTimeout.Duration)
+	if !now.Before(idleAt) {
+		return neurallogv1.SuspensionIdle, 0
+	}
+	return "", idleAt.Sub(now)
+}
+
+// stoppedReason returns why the tenant's server and Redis are scaled to zero,
+// or an empty string if they should run
+func stoppedReason(tenant *neurallogv1.Tenant) string {
+	if restore := restoreInProgress(tenant); restore != "" {
+		return fmt.Sprintf("stopped for restore %s", restore)
+	}
+	if tenant.Status.Suspension != nil {
+		return "suspended"
+	}
+	return ""
+}
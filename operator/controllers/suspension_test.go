@@ -0,0 +1,85 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+)
+
+var _ = Describe("Tenant suspension", func() {
+	var (
+		tenant  *neurallogv1.Tenant
+		created time.Time
+	)
+
+	BeforeEach(func() {
+		created = time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+		tenant = &neurallogv1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "test-tenant",
+				CreationTimestamp: metav1.NewTime(created),
+			},
+		}
+	})
+
+	It("Should suspend a tenant on request and resume it when unset", func() {
+		r := &TenantReconciler{}
+		tenant.Spec.Suspended = true
+		r.reconcileSuspension(context.Background(), tenant, created)
+		Expect(tenant.Status.Suspension).NotTo(BeNil())
+		Expect(tenant.Status.Suspension.Reason).To(Equal(neurallogv1.SuspensionRequested))
+		Expect(stoppedReason(tenant)).To(Equal("suspended"))
+
+		tenant.Spec.Suspended = false
+		r.reconcileSuspension(context.Background(), tenant, created)
+		Expect(tenant.Status.Suspension).To(BeNil())
+		Expect(stoppedReason(tenant)).To(BeEmpty())
+	})
+
+	It("Should suspend an idle tenant once its idle timeout has passed", func() {
+		tenant.Spec.IdleTimeout = &metav1.Duration{Duration: time.Hour}
+		tenant.Annotations = map[string]string{
+			neurallogv1.LastActivityAnnotation: created.Add(30 * time.Minute).Format(time.RFC3339),
+		}
+
+		reason, idleIn := suspensionReason(tenant, created.Add(time.Hour))
+		Expect(reason).To(BeEmpty())
+		Expect(idleIn).To(Equal(30 * time.Minute))
+
+		reason, _ = suspensionReason(tenant, created.Add(90*time.Minute))
+		Expect(reason).To(Equal(neurallogv1.SuspensionIdle))
+	})
+
+	It("Should treat a tenant without recorded activity as idle since its creation", func() {
+		tenant.Spec.IdleTimeout = &metav1.Duration{Duration: time.Hour}
+		reason, _ := suspensionReason(tenant, created.Add(time.Hour))
+		Expect(reason).To(Equal(neurallogv1.SuspensionIdle))
+	})
+
+	It("Should report a suspended tenant as not ready", func() {
+		tenant.Status.Suspension = &neurallogv1.SuspensionStatus{Reason: neurallogv1.SuspensionIdle}
+		updatePhase(tenant)
+		Expect(tenant.Status.Phase).To(Equal(neurallogv1.TenantSuspended))
+	})
+})
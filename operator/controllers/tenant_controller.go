@@ -17,27 +17,30 @@ limitations under the License.
 package controllers
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
-	"reflect"
 	"time"
 
 	"k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	neurallogv1 "github.com/neurallog/operator/api/v1"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -45,20 +48,56 @@ import (
 type TenantReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// AuthClient is used to register tenants with the Auth service
+	AuthClient AuthClient
+
+	// AuthTenants caches the tenants registered with the Auth service
+	AuthTenants *AuthTenantCache
+
+	// AuthSyncEvents receives the Tenants the Auth service sync found missing
+	AuthSyncEvents <-chan event.GenericEvent
+
+	// OpenFGA manages the tenants' authorization stores. It is nil if the
+	// operator isn't configured with an OpenFGA URL.
+	OpenFGA OpenFGAClient
+
+	// ResyncPeriod is how often a reconciled tenant is requeued even if none of
+	// its resources changed. Zero disables periodic resync.
+	ResyncPeriod time.Duration
+
+	// Recorder records events about the tenant
+	Recorder record.EventRecorder
+
+	// AuthCleanupTimeout is how long removing a deleted tenant from the Auth
+	// service is retried. Zero uses DefaultAuthCleanupTimeout.
+	AuthCleanupTimeout time.Duration
 }
 
 //+kubebuilder:rbac:groups=neurallog.io,resources=tenants,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=neurallog.io,resources=tenants/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=neurallog.io,resources=tenants/finalizers,verbs=update
+//+kubebuilder:rbac:groups=neurallog.io,resources=tenantplans,verbs=get;list;watch
 //+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core,resources=serviceaccounts,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;patch
 //+kubebuilder:rbac:groups=core,resources=persistentvolumeclaims,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=persistentvolumes,verbs=get;list;watch;patch
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+//+kubebuilder:rbac:groups=core,resources=resourcequotas,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=limitranges,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create
 //+kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=rolebindings,verbs=get;list;watch;create;update;patch;delete
 
@@ -76,6 +115,7 @@ func (r *TenantReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 			// Request object not found, could have been deleted after reconcile request.
 			// Return and don't requeue
 			logger.Info("Tenant resource not found. Ignoring since object must be deleted")
+			forgetTenantMetrics(req.Name)
 			return ctrl.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
@@ -83,11 +123,20 @@ func (r *TenantReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		return ctrl.Result{}, err
 	}
 
+	// Export the tenant's phase and readiness however the reconcile ends
+	defer recordTenantMetrics(tenant)
+
+	// Record what changed in the tenant's status as events
+	previousPhase := tenant.Status.Phase
+	previousConditions := append([]metav1.Condition(nil), tenant.Status.Conditions...)
+	defer func() { r.recordTransitions(tenant, previousPhase, previousConditions) }()
+
 	// Initialize status if it's a new tenant
 	if tenant.Status.Phase == "" {
 		tenant.Status.Phase = neurallogv1.TenantPending
-		if err := r.Status().Update(ctx, tenant); err != nil {
+		if err := r.updateStatus(ctx, tenant); err != nil {
 			logger.Error(err, "Failed to update Tenant status")
+			r.recordError(tenant, eventReconcileFailed, "Failed to update status", err)
 			return ctrl.Result{}, err
 		}
 		return ctrl.Result{Requeue: true}, nil
@@ -98,84 +147,152 @@ func (r *TenantReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		controllerutil.AddFinalizer(tenant, "neurallog.io/finalizer")
 		if err := r.Update(ctx, tenant); err != nil {
 			logger.Error(err, "Failed to add finalizer")
+			r.recordError(tenant, eventReconcileFailed, "Failed to add finalizer", err)
 			return ctrl.Result{}, err
 		}
 		return ctrl.Result{Requeue: true}, nil
 	}
 
+	// Merge the tenant's plan into its spec. A tenant being deleted goes ahead
+	// with its own settings if its plan is gone.
+	plan, planErr := mergeTenantPlan(ctx, r.Client, tenant)
+
 	// Check if the tenant is being deleted
 	if !tenant.ObjectMeta.DeletionTimestamp.IsZero() {
 		return r.reconcileDelete(ctx, tenant)
 	}
 
-	// Update status to Provisioning if it's still Pending
-	if tenant.Status.Phase == neurallogv1.TenantPending {
-		tenant.Status.Phase = neurallogv1.TenantProvisioning
-		if err := r.Status().Update(ctx, tenant); err != nil {
-			logger.Error(err, "Failed to update Tenant status")
-			return ctrl.Result{}, err
-		}
-		return ctrl.Result{Requeue: true}, nil
+	if planErr != nil {
+		logger.Error(planErr, "Failed to apply plan")
+		return r.failStep(ctx, tenant, neurallogv1.ConditionPlanApplied, planErr)
+	}
+	if plan != nil {
+		setCondition(tenant, neurallogv1.ConditionPlanApplied, metav1.ConditionTrue, neurallogv1.ReasonReconciled,
+			fmt.Sprintf("Plan %s (generation %d) is applied", plan.Name, plan.Generation))
+	} else {
+		setCondition(tenant, neurallogv1.ConditionPlanApplied, metav1.ConditionTrue, neurallogv1.ReasonDisabled, "Tenant is not on a plan")
 	}
 
 	// Create or update the namespace
+	start := time.Now()
 	namespace, err := r.reconcileNamespace(ctx, tenant)
+	observeStep("namespace", start, err)
+	if notManaged, ok := err.(*namespaceNotManagedError); ok {
+		return r.refuseNamespace(ctx, tenant, notManaged)
+	}
 	if err != nil {
 		logger.Error(err, "Failed to reconcile namespace")
-		return ctrl.Result{}, err
+		return r.failStep(ctx, tenant, neurallogv1.ConditionNamespaceReady, err)
 	}
+	setCondition(tenant, neurallogv1.ConditionNamespaceReady, metav1.ConditionTrue, neurallogv1.ReasonReconciled,
+		fmt.Sprintf("Namespace %s is ready", namespace.Name))
 
 	// Update the namespace in the status if it's not set
 	if tenant.Status.Namespace == "" {
 		tenant.Status.Namespace = namespace.Name
-		if err := r.Status().Update(ctx, tenant); err != nil {
+		updatePhase(tenant)
+		if err := r.updateStatus(ctx, tenant); err != nil {
 			logger.Error(err, "Failed to update Tenant status with namespace")
+			r.recordError(tenant, eventReconcileFailed, "Failed to update status", err)
 			return ctrl.Result{}, err
 		}
 		return ctrl.Result{Requeue: true}, nil
 	}
 
+	// Enforce the tenant's resources in its namespace
+	if err := runStep(ctx, tenant, "quota", r.reconcileResourceQuota); err != nil {
+		logger.Error(err, "Failed to reconcile resource quota")
+		return r.failStep(ctx, tenant, neurallogv1.ConditionNamespaceReady, err)
+	}
+
+	// Suspend or resume the tenant's workloads
+	idleIn := r.reconcileSuspension(ctx, tenant, time.Now())
+
 	// Reconcile Redis resources
-	if err := r.reconcileRedis(ctx, tenant); err != nil {
+	if err := runStep(ctx, tenant, "redis", r.reconcileRedis); err != nil {
 		logger.Error(err, "Failed to reconcile Redis")
-		return ctrl.Result{}, err
+		return r.failStep(ctx, tenant, neurallogv1.ConditionRedisReady, err)
 	}
+	setComponentCondition(tenant, neurallogv1.ConditionRedisReady, tenant.Status.RedisStatus.ComponentStatus)
 
 	// Reconcile Server resources
-	if err := r.reconcileServer(ctx, tenant); err != nil {
+	if err := runStep(ctx, tenant, "server", r.reconcileServer); err != nil {
 		logger.Error(err, "Failed to reconcile Server")
-		return ctrl.Result{}, err
+		return r.failStep(ctx, tenant, neurallogv1.ConditionServerReady, err)
 	}
+	setComponentCondition(tenant, neurallogv1.ConditionServerReady, tenant.Status.ServerStatus)
 
 	// Reconcile Registry resources
-	if err := r.reconcileRegistry(ctx, tenant); err != nil {
+	if err := runStep(ctx, tenant, "registry", r.reconcileRegistry); err != nil {
 		logger.Error(err, "Failed to reconcile Registry")
-		return ctrl.Result{}, err
+		return r.failStep(ctx, tenant, neurallogv1.ConditionRegistryReady, err)
 	}
+	setComponentCondition(tenant, neurallogv1.ConditionRegistryReady, tenant.Status.RegistryStatus)
 
 	// Reconcile Network Policies
-	if err := r.reconcileNetworkPolicies(ctx, tenant); err != nil {
+	if err := runStep(ctx, tenant, "network_policy", r.reconcileNetworkPolicies); err != nil {
 		logger.Error(err, "Failed to reconcile Network Policies")
-		return ctrl.Result{}, err
+		return r.failStep(ctx, tenant, neurallogv1.ConditionNetworkPoliciesReady, err)
+	}
+	if networkPoliciesEnabled(tenant) {
+		setCondition(tenant, neurallogv1.ConditionNetworkPoliciesReady, metav1.ConditionTrue, neurallogv1.ReasonReconciled, "Network policies are applied")
+	} else {
+		setCondition(tenant, neurallogv1.ConditionNetworkPoliciesReady, metav1.ConditionTrue, neurallogv1.ReasonDisabled, "Network policies are disabled")
+	}
+
+	// Expose the server outside the cluster
+	if err := runStep(ctx, tenant, "exposure", r.reconcileExposure); err != nil {
+		logger.Error(err, "Failed to reconcile exposure")
+		return r.failStep(ctx, tenant, neurallogv1.ConditionExposureReady, err)
+	}
+	if tenant.Status.URL != "" {
+		setCondition(tenant, neurallogv1.ConditionExposureReady, metav1.ConditionTrue, neurallogv1.ReasonReconciled,
+			fmt.Sprintf("Server is exposed at %s", tenant.Status.URL))
+	} else {
+		setCondition(tenant, neurallogv1.ConditionExposureReady, metav1.ConditionTrue, neurallogv1.ReasonDisabled, "Exposure is not configured")
 	}
 
 	// Reconcile Auth Service integration
-	if err := r.reconcileAuthService(ctx, tenant); err != nil {
+	if err := runStep(ctx, tenant, "auth", r.reconcileAuthService); err != nil {
 		logger.Error(err, "Failed to reconcile Auth Service integration")
+		return r.failStep(ctx, tenant, neurallogv1.ConditionAuthSynced, err)
+	}
+	setCondition(tenant, neurallogv1.ConditionAuthSynced, metav1.ConditionTrue, neurallogv1.ReasonReconciled, "Tenant is registered with the Auth service")
+
+	// Reconcile the OpenFGA store and authorization model
+	if err := runStep(ctx, tenant, "authorization", r.reconcileAuthorization); err != nil {
+		logger.Error(err, "Failed to reconcile authorization")
+		return r.failStep(ctx, tenant, neurallogv1.ConditionAuthorizationReady, err)
+	}
+	if authorization := tenant.Status.Authorization; authorization != nil {
+		setCondition(tenant, neurallogv1.ConditionAuthorizationReady, metav1.ConditionTrue, neurallogv1.ReasonReconciled,
+			fmt.Sprintf("Authorization model %s is written to OpenFGA store %s", authorization.ModelID, authorization.StoreID))
+	} else {
+		setCondition(tenant, neurallogv1.ConditionAuthorizationReady, metav1.ConditionTrue, neurallogv1.ReasonDisabled, "Authorization is not configured")
+	}
+
+	// Derive the Ready condition and phase from the step conditions
+	updatePhase(tenant)
+	if err := r.updateStatus(ctx, tenant); err != nil {
+		logger.Error(err, "Failed to update Tenant status")
+		r.recordError(tenant, eventReconcileFailed, "Failed to update status", err)
 		return ctrl.Result{}, err
 	}
 
-	// Update status to Running if everything is provisioned
-	if tenant.Status.Phase == neurallogv1.TenantProvisioning {
-		tenant.Status.Phase = neurallogv1.TenantRunning
-		if err := r.Status().Update(ctx, tenant); err != nil {
-			logger.Error(err, "Failed to update Tenant status")
-			return ctrl.Result{}, err
-		}
+	// Changes to owned resources trigger a reconcile, resync only if configured
+	result := ctrl.Result{RequeueAfter: r.ResyncPeriod}
+
+	// A Sentinel failover doesn't necessarily change a watched object, poll for it
+	if redisSentinelEnabled(tenant) && stoppedReason(tenant) == "" && (result.RequeueAfter == 0 || result.RequeueAfter > redisPrimaryPollInterval) {
+		result.RequeueAfter = redisPrimaryPollInterval
+	}
+
+	// Check again when the tenant would become idle
+	if idleIn > 0 && (result.RequeueAfter == 0 || result.RequeueAfter > idleIn) {
+		result.RequeueAfter = idleIn
 	}
 
-	// Requeue to check status periodically
-	return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+	return result, nil
 }
 
 // reconcileDelete handles the deletion of a Tenant
@@ -186,15 +303,36 @@ func (r *TenantReconciler) reconcileDelete(ctx context.Context, tenant *neurallo
 	// Update status to Terminating if it's not already
 	if tenant.Status.Phase != neurallogv1.TenantTerminating {
 		tenant.Status.Phase = neurallogv1.TenantTerminating
-		if err := r.Status().Update(ctx, tenant); err != nil {
+		setCondition(tenant, neurallogv1.ConditionReady, metav1.ConditionFalse, neurallogv1.ReasonTerminating, "Tenant is being deleted")
+		if err := r.updateStatus(ctx, tenant); err != nil {
 			logger.Error(err, "Failed to update Tenant status")
+			r.recordError(tenant, eventDeletionFailed, "Failed to update status", err)
 			return ctrl.Result{}, err
 		}
+		r.Recorder.Event(tenant, corev1.EventTypeNormal, eventDeletionPolicy, deletionPolicyMessage(tenant))
 		return ctrl.Result{Requeue: true}, nil
 	}
 
-	// Delete the namespace if it exists
-	if tenant.Status.Namespace != "" {
+	// Keep or back up the tenant's data as its deletion policy requires
+	done, err := r.applyDeletionPolicy(ctx, tenant)
+	if err != nil {
+		logger.Error(err, "Failed to apply deletion policy", "policy", deletionPolicy(tenant))
+		r.recordError(tenant, eventDeletionFailed, fmt.Sprintf("Failed to apply the %s deletion policy", deletionPolicy(tenant)), err)
+		return ctrl.Result{}, err
+	}
+	if !done {
+		return ctrl.Result{RequeueAfter: deletionPollInterval}, nil
+	}
+
+	// Release an adopted namespace, and delete the namespace otherwise
+	if tenant.Status.Namespace != "" && tenant.Status.NamespaceAdopted {
+		if err := r.releaseNamespace(ctx, tenant); err != nil {
+			logger.Error(err, "Failed to release namespace")
+			r.recordError(tenant, eventDeletionFailed, "Failed to release namespace", err)
+			return ctrl.Result{}, err
+		}
+		logger.Info("Released namespace", "namespace", tenant.Status.Namespace)
+	} else if tenant.Status.Namespace != "" {
 		namespace := &corev1.Namespace{
 			ObjectMeta: metav1.ObjectMeta{
 				Name: tenant.Status.Namespace,
@@ -203,24 +341,26 @@ func (r *TenantReconciler) reconcileDelete(ctx context.Context, tenant *neurallo
 		err := r.Delete(ctx, namespace)
 		if err != nil && !errors.IsNotFound(err) {
 			logger.Error(err, "Failed to delete namespace")
+			r.recordError(tenant, eventDeletionFailed, "Failed to delete namespace", err)
 			return ctrl.Result{}, err
 		}
 		logger.Info("Deleted namespace", "namespace", tenant.Status.Namespace)
 	}
 
-	// Delete the tenant from the Auth service
-	if err := r.deleteTenantFromAuthService(ctx, tenant.Name); err != nil {
-		logger.Error(err, "Failed to delete tenant from Auth service")
-		// Don't return an error here, as we still want to remove the finalizer
-		// The tenant will be garbage collected by the Auth service
-	} else {
-		logger.Info("Deleted tenant from Auth service", "tenant", tenant.Name)
+	// Remove the tenant from the Auth service, retrying until the cleanup timeout
+	deregistered, retryIn, err := r.deregisterTenant(ctx, tenant, time.Now())
+	if err != nil {
+		r.recordError(tenant, eventDeletionFailed, "Failed to remove the tenant from the Auth service", err)
+		return ctrl.Result{}, err
+	}
+	if !deregistered {
+		return ctrl.Result{RequeueAfter: retryIn}, nil
 	}
 
 	// Remove finalizer
-	controllerutil.RemoveFinalizer(tenant, "neurallog.io/finalizer")
-	if err := r.Update(ctx, tenant); err != nil {
+	if err := r.removeFinalizer(ctx, tenant); err != nil {
 		logger.Error(err, "Failed to remove finalizer")
+		r.recordError(tenant, eventDeletionFailed, "Failed to remove finalizer", err)
 		return ctrl.Result{}, err
 	}
 
@@ -228,1204 +368,56 @@ func (r *TenantReconciler) reconcileDelete(ctx context.Context, tenant *neurallo
 	return ctrl.Result{}, nil
 }
 
-// reconcileNamespace creates or updates the namespace for the tenant
-func (r *TenantReconciler) reconcileNamespace(ctx context.Context, tenant *neurallogv1.Tenant) (*corev1.Namespace, error) {
-	logger := log.FromContext(ctx)
-
-	// Generate namespace name if not set in status
-	namespaceName := tenant.Status.Namespace
-	if namespaceName == "" {
-		namespaceName = fmt.Sprintf("tenant-%s", tenant.Name)
-	}
-
-	// Check if namespace exists
-	namespace := &corev1.Namespace{}
-	err := r.Get(ctx, client.ObjectKey{Name: namespaceName}, namespace)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			// Create namespace
-			namespace = &corev1.Namespace{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: namespaceName,
-					Labels: map[string]string{
-						"neurallog.io/tenant":     tenant.Name,
-						"neurallog.io/managed-by": "tenant-operator",
-					},
-				},
-			}
-			if err := controllerutil.SetOwnerReference(tenant, namespace, r.Scheme); err != nil {
-				logger.Error(err, "Failed to set owner reference on namespace")
-				return nil, err
-			}
-			if err := r.Create(ctx, namespace); err != nil {
-				logger.Error(err, "Failed to create namespace")
-				return nil, err
-			}
-			logger.Info("Created namespace", "namespace", namespaceName)
-		} else {
-			logger.Error(err, "Failed to get namespace")
-			return nil, err
-		}
-	} else {
-		// Namespace exists, ensure it has the correct labels
-		updated := false
-		if namespace.Labels == nil {
-			namespace.Labels = make(map[string]string)
-			updated = true
-		}
-		if namespace.Labels["neurallog.io/tenant"] != tenant.Name {
-			namespace.Labels["neurallog.io/tenant"] = tenant.Name
-			updated = true
-		}
-		if namespace.Labels["neurallog.io/managed-by"] != "tenant-operator" {
-			namespace.Labels["neurallog.io/managed-by"] = "tenant-operator"
-			updated = true
-		}
-		if updated {
-			if err := r.Update(ctx, namespace); err != nil {
-				logger.Error(err, "Failed to update namespace")
-				return nil, err
-			}
-			logger.Info("Updated namespace", "namespace", namespaceName)
-		}
-	}
-
-	return namespace, nil
+// removeFinalizer removes the operator's finalizer from the tenant. The tenant
+// holds the spec merged from its plan by now, so only the finalizers are
+// patched; an update would write the merged spec back.
+func (r *TenantReconciler) removeFinalizer(ctx context.Context, tenant *neurallogv1.Tenant) error {
+	patch := client.MergeFromWithOptions(tenant.DeepCopy(), client.MergeFromWithOptimisticLock{})
+	controllerutil.RemoveFinalizer(tenant, "neurallog.io/finalizer")
+	return r.Patch(ctx, tenant, patch)
 }
 
-// reconcileRedis creates or updates Redis resources for the tenant
-func (r *TenantReconciler) reconcileRedis(ctx context.Context, tenant *neurallogv1.Tenant) error {
-	logger := log.FromContext(ctx)
-	logger.Info("Reconciling Redis resources", "tenant", tenant.Name)
-
-	if tenant.Status.Namespace == "" {
-		logger.Info("Namespace not yet created, skipping Redis reconciliation")
-		return nil
-	}
-
-	// Create or update Redis ConfigMap
-	configMap, err := r.reconcileRedisConfigMap(ctx, tenant)
-	if err != nil {
-		logger.Error(err, "Failed to reconcile Redis ConfigMap")
-		return err
-	}
-
-	// Create or update Redis Service
-	service, err := r.reconcileRedisService(ctx, tenant)
-	if err != nil {
-		logger.Error(err, "Failed to reconcile Redis Service")
-		return err
-	}
-
-	// Create or update Redis StatefulSet
-	statefulSet, err := r.reconcileRedisStatefulSet(ctx, tenant, configMap)
-	if err != nil {
-		logger.Error(err, "Failed to reconcile Redis StatefulSet")
+// SetupWithManager sets up the controller with the Manager.
+func (r *TenantReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// Find the Tenants to reconcile when an authorization model changes
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &neurallogv1.Tenant{}, authorizationModelRefIndex, authorizationModelRef); err != nil {
 		return err
 	}
-
-	// Update Redis status in tenant.Status.RedisStatus
-	if tenant.Status.RedisStatus == nil {
-		tenant.Status.RedisStatus = &neurallogv1.ComponentStatus{}
-	}
-
-	// Update status based on StatefulSet
-	tenant.Status.RedisStatus.TotalReplicas = statefulSet.Status.Replicas
-	tenant.Status.RedisStatus.ReadyReplicas = statefulSet.Status.ReadyReplicas
-
-	// Determine phase based on replicas
-	if statefulSet.Status.ReadyReplicas == 0 {
-		tenant.Status.RedisStatus.Phase = neurallogv1.ComponentProvisioning
-		tenant.Status.RedisStatus.Message = "Redis is being provisioned"
-	} else if statefulSet.Status.ReadyReplicas < statefulSet.Status.Replicas {
-		tenant.Status.RedisStatus.Phase = neurallogv1.ComponentDegraded
-		tenant.Status.RedisStatus.Message = fmt.Sprintf("Redis is degraded: %d/%d replicas ready", statefulSet.Status.ReadyReplicas, statefulSet.Status.Replicas)
-	} else {
-		tenant.Status.RedisStatus.Phase = neurallogv1.ComponentRunning
-		tenant.Status.RedisStatus.Message = "Redis is running"
-	}
-
-	// Update tenant status
-	if err := r.Status().Update(ctx, tenant); err != nil {
-		logger.Error(err, "Failed to update tenant status with Redis status")
+	// Find the Tenants to reconcile when their plan changes
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &neurallogv1.Tenant{}, tenantPlanIndex, tenantPlanName); err != nil {
 		return err
 	}
 
-	return nil
+	builder := ctrl.NewControllerManagedBy(mgr).
+		For(&neurallogv1.Tenant{}).
+		Owns(&appsv1.Deployment{}).
+		Owns(&appsv1.StatefulSet{}).
+		Owns(&corev1.Service{}).
+		Owns(&corev1.ConfigMap{}).
+		Owns(&corev1.ResourceQuota{}).
+		Owns(&corev1.LimitRange{}).
+		Owns(&networkingv1.NetworkPolicy{}).
+		Owns(&networkingv1.Ingress{}).
+		Owns(&autoscalingv2.HorizontalPodAutoscaler{}).
+		Owns(&policyv1.PodDisruptionBudget{}).
+		Owns(&batchv1.CronJob{}).
+		Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(namespaceToTenant)).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.configMapToTenants)).
+		Watches(&neurallogv1.TenantPlan{}, handler.EnqueueRequestsFromMapFunc(r.tenantPlanToTenants))
+	if r.AuthSyncEvents != nil {
+		builder = builder.WatchesRawSource(&source.Channel{Source: r.AuthSyncEvents}, &handler.EnqueueRequestForObject{})
+	}
+	return builder.Complete(r)
 }
 
-// reconcileServer creates or updates Server resources for the tenant
-func (r *TenantReconciler) reconcileServer(ctx context.Context, tenant *neurallogv1.Tenant) error {
-	logger := log.FromContext(ctx)
-	logger.Info("Reconciling Server resources", "tenant", tenant.Name)
-
-	if tenant.Status.Namespace == "" {
-		logger.Info("Namespace not yet created, skipping Server reconciliation")
+// namespaceToTenant maps a tenant namespace to the Tenant that manages it
+func namespaceToTenant(ctx context.Context, obj client.Object) []reconcile.Request {
+	if obj.GetLabels()["neurallog.io/managed-by"] != "tenant-operator" {
 		return nil
 	}
-
-	// Create or update Server Service
-	service, err := r.reconcileServerService(ctx, tenant)
-	if err != nil {
-		logger.Error(err, "Failed to reconcile Server Service")
-		return err
-	}
-
-	// Create or update Server Deployment
-	deployment, err := r.reconcileServerDeployment(ctx, tenant)
-	if err != nil {
-		logger.Error(err, "Failed to reconcile Server Deployment")
-		return err
-	}
-
-	// Update Server status in tenant.Status.ServerStatus
-	if tenant.Status.ServerStatus == nil {
-		tenant.Status.ServerStatus = &neurallogv1.ComponentStatus{}
-	}
-
-	// Update status based on Deployment
-	tenant.Status.ServerStatus.TotalReplicas = deployment.Status.Replicas
-	tenant.Status.ServerStatus.ReadyReplicas = deployment.Status.ReadyReplicas
-
-	// Determine phase based on replicas
-	if deployment.Status.ReadyReplicas == 0 {
-		tenant.Status.ServerStatus.Phase = neurallogv1.ComponentProvisioning
-		tenant.Status.ServerStatus.Message = "Server is being provisioned"
-	} else if deployment.Status.ReadyReplicas < deployment.Status.Replicas {
-		tenant.Status.ServerStatus.Phase = neurallogv1.ComponentDegraded
-		tenant.Status.ServerStatus.Message = fmt.Sprintf("Server is degraded: %d/%d replicas ready", deployment.Status.ReadyReplicas, deployment.Status.Replicas)
-	} else {
-		tenant.Status.ServerStatus.Phase = neurallogv1.ComponentRunning
-		tenant.Status.ServerStatus.Message = "Server is running"
-	}
-
-	// Update tenant status
-	if err := r.Status().Update(ctx, tenant); err != nil {
-		logger.Error(err, "Failed to update tenant status with Server status")
-		return err
-	}
-
-	return nil
-}
-
-// reconcileNetworkPolicies creates or updates Network Policies for the tenant
-func (r *TenantReconciler) reconcileNetworkPolicies(ctx context.Context, tenant *neurallogv1.Tenant) error {
-	logger := log.FromContext(ctx)
-	logger.Info("Reconciling Network Policies", "tenant", tenant.Name)
-
-	// TODO: Implement Network Policy reconciliation
-	// This would include:
-	// - Creating/updating Network Policies based on tenant.Spec.NetworkPolicy
-
-	return nil
-}
-
-// reconcileAuthService integrates with the Auth service to manage tenant authentication
-func (r *TenantReconciler) reconcileAuthService(ctx context.Context, tenant *neurallogv1.Tenant) error {
-	logger := log.FromContext(ctx)
-	logger.Info("Reconciling Auth Service integration", "tenant", tenant.Name)
-
-	// Skip if the tenant is being deleted
-	if !tenant.ObjectMeta.DeletionTimestamp.IsZero() {
+	tenantName := obj.GetLabels()["neurallog.io/tenant"]
+	if tenantName == "" {
 		return nil
 	}
-
-	// Check if the tenant exists in the Auth service
-	exists, err := r.tenantExistsInAuthService(ctx, tenant.Name)
-	if err != nil {
-		logger.Error(err, "Failed to check if tenant exists in Auth service")
-		return err
-	}
-
-	// If the tenant doesn't exist in the Auth service, create it
-	if !exists {
-		if err := r.createTenantInAuthService(ctx, tenant.Name); err != nil {
-			logger.Error(err, "Failed to create tenant in Auth service")
-			return err
-		}
-		logger.Info("Created tenant in Auth service", "tenant", tenant.Name)
-	}
-
-	return nil
-}
-
-// tenantExistsInAuthService checks if a tenant exists in the Auth service
-func (r *TenantReconciler) tenantExistsInAuthService(ctx context.Context, tenantId string) (bool, error) {
-	logger := log.FromContext(ctx)
-
-	// Make a request to the Auth service to list tenants
-	resp, err := http.Get("http://auth:3000/api/tenants")
-	if err != nil {
-		logger.Error(err, "Failed to connect to Auth service")
-		return false, err
-	}
-	defer resp.Body.Close()
-
-	// Read the response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		logger.Error(err, "Failed to read response from Auth service")
-		return false, err
-	}
-
-	// Parse the response
-	var response struct {
-		Status  string   `json:"status"`
-		Tenants []string `json:"tenants"`
-	}
-	if err := json.Unmarshal(body, &response); err != nil {
-		logger.Error(err, "Failed to parse response from Auth service")
-		return false, err
-	}
-
-	// Check if the tenant exists
-	for _, t := range response.Tenants {
-		if t == tenantId {
-			return true, nil
-		}
-	}
-
-	return false, nil
-}
-
-// createTenantInAuthService creates a tenant in the Auth service
-func (r *TenantReconciler) createTenantInAuthService(ctx context.Context, tenantId string) error {
-	logger := log.FromContext(ctx)
-
-	// Create the request body
-	reqBody, err := json.Marshal(map[string]string{
-		"tenantId":    tenantId,
-		"adminUserId": "system", // Default admin user
-	})
-	if err != nil {
-		logger.Error(err, "Failed to marshal request body")
-		return err
-	}
-
-	// Make a request to the Auth service to create a tenant
-	resp, err := http.Post("http://auth:3000/api/tenants", "application/json", bytes.NewBuffer(reqBody))
-	if err != nil {
-		logger.Error(err, "Failed to connect to Auth service")
-		return err
-	}
-	defer resp.Body.Close()
-
-	// Check the response status
-	if resp.StatusCode != http.StatusCreated {
-		// Read the response body for error details
-		body, _ := io.ReadAll(resp.Body)
-		logger.Error(nil, "Failed to create tenant in Auth service", "statusCode", resp.StatusCode, "response", string(body))
-		return fmt.Errorf("failed to create tenant in Auth service: %d", resp.StatusCode)
-	}
-
-	return nil
-}
-
-// deleteTenantFromAuthService deletes a tenant from the Auth service
-func (r *TenantReconciler) deleteTenantFromAuthService(ctx context.Context, tenantId string) error {
-	logger := log.FromContext(ctx)
-
-	// Create a DELETE request
-	req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("http://auth:3000/api/tenants/%s", tenantId), nil)
-	if err != nil {
-		logger.Error(err, "Failed to create DELETE request")
-		return err
-	}
-
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-
-	// Send the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		logger.Error(err, "Failed to connect to Auth service")
-		return err
-	}
-	defer resp.Body.Close()
-
-	// Check the response status
-	if resp.StatusCode != http.StatusOK {
-		// Read the response body for error details
-		body, _ := io.ReadAll(resp.Body)
-		logger.Error(nil, "Failed to delete tenant from Auth service", "statusCode", resp.StatusCode, "response", string(body))
-		return fmt.Errorf("failed to delete tenant from Auth service: %d", resp.StatusCode)
-	}
-
-	return nil
-}
-
-// reconcileRedisConfigMap creates or updates the Redis ConfigMap for the tenant
-func (r *TenantReconciler) reconcileRedisConfigMap(ctx context.Context, tenant *neurallogv1.Tenant) (*corev1.ConfigMap, error) {
-	logger := log.FromContext(ctx)
-
-	// Define ConfigMap name
-	configMapName := fmt.Sprintf("%s-redis-config", tenant.Name)
-
-	// Create Redis configuration
-	redisConfig := `# Redis configuration for NeuralLog tenant
-port 6379
-bind 0.0.0.0
-protected-mode yes
-daemonize no
-
-# Memory management
-`
-
-	// Add memory limit if specified
-	maxMemory := "256mb"
-	if tenant.Spec.Redis != nil && tenant.Spec.Redis.Resources != nil && tenant.Spec.Redis.Resources.Memory != nil && tenant.Spec.Redis.Resources.Memory.Limit != "" {
-		maxMemory = tenant.Spec.Redis.Resources.Memory.Limit
-	}
-	redisConfig += fmt.Sprintf("maxmemory %s\n", maxMemory)
-	redisConfig += "maxmemory-policy allkeys-lru\n\n"
-
-	// Add persistence configuration
-	redisConfig += `# Persistence
-appendonly yes
-appendfsync everysec
-
-# Logging
-loglevel notice
-logfile ""
-`
-
-	// Add custom configuration if specified
-	if tenant.Spec.Redis != nil && tenant.Spec.Redis.Config != nil {
-		for key, value := range tenant.Spec.Redis.Config {
-			redisConfig += fmt.Sprintf("%s %s\n", key, value)
-		}
-	}
-
-	// Define ConfigMap
-	configMap := &corev1.ConfigMap{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      configMapName,
-			Namespace: tenant.Status.Namespace,
-			Labels: map[string]string{
-				"app":                     "redis",
-				"neurallog.io/tenant":     tenant.Name,
-				"neurallog.io/component":  "redis",
-				"neurallog.io/managed-by": "tenant-operator",
-			},
-		},
-		Data: map[string]string{
-			"redis.conf": redisConfig,
-		},
-	}
-
-	// Set owner reference
-	if err := controllerutil.SetControllerReference(tenant, configMap, r.Scheme); err != nil {
-		logger.Error(err, "Failed to set owner reference on Redis ConfigMap")
-		return nil, err
-	}
-
-	// Create or update ConfigMap
-	existingConfigMap := &corev1.ConfigMap{}
-	err := r.Get(ctx, client.ObjectKey{Name: configMapName, Namespace: tenant.Status.Namespace}, existingConfigMap)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			// Create ConfigMap
-			if err := r.Create(ctx, configMap); err != nil {
-				logger.Error(err, "Failed to create Redis ConfigMap")
-				return nil, err
-			}
-			logger.Info("Created Redis ConfigMap", "configMap", configMapName)
-			return configMap, nil
-		}
-		logger.Error(err, "Failed to get Redis ConfigMap")
-		return nil, err
-	}
-
-	// Update ConfigMap if needed
-	if existingConfigMap.Data["redis.conf"] != configMap.Data["redis.conf"] {
-		existingConfigMap.Data = configMap.Data
-		if err := r.Update(ctx, existingConfigMap); err != nil {
-			logger.Error(err, "Failed to update Redis ConfigMap")
-			return nil, err
-		}
-		logger.Info("Updated Redis ConfigMap", "configMap", configMapName)
-	}
-
-	return existingConfigMap, nil
-}
-
-// reconcileRedisService creates or updates the Redis Service for the tenant
-func (r *TenantReconciler) reconcileRedisService(ctx context.Context, tenant *neurallogv1.Tenant) (*corev1.Service, error) {
-	logger := log.FromContext(ctx)
-
-	// Define Service name
-	serviceName := fmt.Sprintf("%s-redis", tenant.Name)
-
-	// Define Service
-	service := &corev1.Service{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      serviceName,
-			Namespace: tenant.Status.Namespace,
-			Labels: map[string]string{
-				"app":                     "redis",
-				"neurallog.io/tenant":     tenant.Name,
-				"neurallog.io/component":  "redis",
-				"neurallog.io/managed-by": "tenant-operator",
-			},
-		},
-		Spec: corev1.ServiceSpec{
-			Selector: map[string]string{
-				"app":                 "redis",
-				"neurallog.io/tenant": tenant.Name,
-			},
-			Ports: []corev1.ServicePort{
-				{
-					Port:       6379,
-					TargetPort: intstr.FromString("redis"),
-					Name:       "redis",
-				},
-			},
-			ClusterIP: "None", // Headless service for StatefulSet
-		},
-	}
-
-	// Set owner reference
-	if err := controllerutil.SetControllerReference(tenant, service, r.Scheme); err != nil {
-		logger.Error(err, "Failed to set owner reference on Redis Service")
-		return nil, err
-	}
-
-	// Create or update Service
-	existingService := &corev1.Service{}
-	err := r.Get(ctx, client.ObjectKey{Name: serviceName, Namespace: tenant.Status.Namespace}, existingService)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			// Create Service
-			if err := r.Create(ctx, service); err != nil {
-				logger.Error(err, "Failed to create Redis Service")
-				return nil, err
-			}
-			logger.Info("Created Redis Service", "service", serviceName)
-			return service, nil
-		}
-		logger.Error(err, "Failed to get Redis Service")
-		return nil, err
-	}
-
-	// No need to update the service as it's a headless service with minimal configuration
-	return existingService, nil
-}
-
-// reconcileRedisStatefulSet creates or updates the Redis StatefulSet for the tenant
-func (r *TenantReconciler) reconcileRedisStatefulSet(ctx context.Context, tenant *neurallogv1.Tenant, configMap *corev1.ConfigMap) (*appsv1.StatefulSet, error) {
-	logger := log.FromContext(ctx)
-
-	// Define StatefulSet name
-	statefulSetName := fmt.Sprintf("%s-redis", tenant.Name)
-
-	// Define labels
-	labels := map[string]string{
-		"app":                     "redis",
-		"neurallog.io/tenant":     tenant.Name,
-		"neurallog.io/component":  "redis",
-		"neurallog.io/managed-by": "tenant-operator",
-	}
-
-	// Set replicas
-	replicas := int32(1)
-	if tenant.Spec.Redis != nil && tenant.Spec.Redis.Replicas > 0 {
-		replicas = tenant.Spec.Redis.Replicas
-	}
-
-	// Set image
-	image := "redis:7-alpine"
-	if tenant.Spec.Redis != nil && tenant.Spec.Redis.Image != "" {
-		image = tenant.Spec.Redis.Image
-	}
-
-	// Set resource requirements
-	resources := corev1.ResourceRequirements{
-		Requests: corev1.ResourceList{
-			corev1.ResourceCPU:    resource.MustParse("100m"),
-			corev1.ResourceMemory: resource.MustParse("128Mi"),
-		},
-		Limits: corev1.ResourceList{
-			corev1.ResourceCPU:    resource.MustParse("300m"),
-			corev1.ResourceMemory: resource.MustParse("256Mi"),
-		},
-	}
-
-	if tenant.Spec.Redis != nil && tenant.Spec.Redis.Resources != nil {
-		if tenant.Spec.Redis.Resources.CPU != nil {
-			if tenant.Spec.Redis.Resources.CPU.Request != "" {
-				resources.Requests[corev1.ResourceCPU] = resource.MustParse(tenant.Spec.Redis.Resources.CPU.Request)
-			}
-			if tenant.Spec.Redis.Resources.CPU.Limit != "" {
-				resources.Limits[corev1.ResourceCPU] = resource.MustParse(tenant.Spec.Redis.Resources.CPU.Limit)
-			}
-		}
-		if tenant.Spec.Redis.Resources.Memory != nil {
-			if tenant.Spec.Redis.Resources.Memory.Request != "" {
-				resources.Requests[corev1.ResourceMemory] = resource.MustParse(tenant.Spec.Redis.Resources.Memory.Request)
-			}
-			if tenant.Spec.Redis.Resources.Memory.Limit != "" {
-				resources.Limits[corev1.ResourceMemory] = resource.MustParse(tenant.Spec.Redis.Resources.Memory.Limit)
-			}
-		}
-	}
-
-	// Set storage
-	storage := "1Gi"
-	if tenant.Spec.Redis != nil && tenant.Spec.Redis.Storage != "" {
-		storage = tenant.Spec.Redis.Storage
-	}
-
-	// Define StatefulSet
-	statefulSet := &appsv1.StatefulSet{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      statefulSetName,
-			Namespace: tenant.Status.Namespace,
-			Labels:    labels,
-		},
-		Spec: appsv1.StatefulSetSpec{
-			ServiceName: fmt.Sprintf("%s-redis", tenant.Name),
-			Replicas:    &replicas,
-			Selector: &metav1.LabelSelector{
-				MatchLabels: map[string]string{
-					"app":                 "redis",
-					"neurallog.io/tenant": tenant.Name,
-				},
-			},
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Labels: labels,
-				},
-				Spec: corev1.PodSpec{
-					Containers: []corev1.Container{
-						{
-							Name:  "redis",
-							Image: image,
-							Command: []string{
-								"redis-server",
-								"/etc/redis/redis.conf",
-							},
-							Ports: []corev1.ContainerPort{
-								{
-									ContainerPort: 6379,
-									Name:          "redis",
-								},
-							},
-							VolumeMounts: []corev1.VolumeMount{
-								{
-									Name:      "redis-data",
-									MountPath: "/data",
-								},
-								{
-									Name:      "redis-config",
-									MountPath: "/etc/redis",
-								},
-							},
-							Resources: resources,
-							LivenessProbe: &corev1.Probe{
-								ProbeHandler: corev1.ProbeHandler{
-									TCPSocket: &corev1.TCPSocketAction{
-										Port: intstr.FromString("redis"),
-									},
-								},
-								InitialDelaySeconds: 15,
-								PeriodSeconds:       20,
-							},
-							ReadinessProbe: &corev1.Probe{
-								ProbeHandler: corev1.ProbeHandler{
-									Exec: &corev1.ExecAction{
-										Command: []string{
-											"redis-cli",
-											"ping",
-										},
-									},
-								},
-								InitialDelaySeconds: 5,
-								PeriodSeconds:       10,
-							},
-						},
-					},
-					Volumes: []corev1.Volume{
-						{
-							Name: "redis-config",
-							VolumeSource: corev1.VolumeSource{
-								ConfigMap: &corev1.ConfigMapVolumeSource{
-									LocalObjectReference: corev1.LocalObjectReference{
-										Name: configMap.Name,
-									},
-									Items: []corev1.KeyToPath{
-										{
-											Key:  "redis.conf",
-											Path: "redis.conf",
-										},
-									},
-								},
-							},
-						},
-					},
-				},
-			},
-			VolumeClaimTemplates: []corev1.PersistentVolumeClaim{
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "redis-data",
-					},
-					Spec: corev1.PersistentVolumeClaimSpec{
-						AccessModes: []corev1.PersistentVolumeAccessMode{
-							corev1.ReadWriteOnce,
-						},
-						Resources: corev1.ResourceRequirements{
-							Requests: corev1.ResourceList{
-								corev1.ResourceStorage: resource.MustParse(storage),
-							},
-						},
-					},
-				},
-			},
-		},
-	}
-
-	// Set owner reference
-	if err := controllerutil.SetControllerReference(tenant, statefulSet, r.Scheme); err != nil {
-		logger.Error(err, "Failed to set owner reference on Redis StatefulSet")
-		return nil, err
-	}
-
-	// Create or update StatefulSet
-	existingStatefulSet := &appsv1.StatefulSet{}
-	err := r.Get(ctx, client.ObjectKey{Name: statefulSetName, Namespace: tenant.Status.Namespace}, existingStatefulSet)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			// Create StatefulSet
-			if err := r.Create(ctx, statefulSet); err != nil {
-				logger.Error(err, "Failed to create Redis StatefulSet")
-				return nil, err
-			}
-			logger.Info("Created Redis StatefulSet", "statefulSet", statefulSetName)
-			return statefulSet, nil
-		}
-		logger.Error(err, "Failed to get Redis StatefulSet")
-		return nil, err
-	}
-
-	// Update StatefulSet if needed
-	updated := false
-
-	// Check if replicas need to be updated
-	if existingStatefulSet.Spec.Replicas == nil || *existingStatefulSet.Spec.Replicas != replicas {
-		existingStatefulSet.Spec.Replicas = &replicas
-		updated = true
-	}
-
-	// Check if image needs to be updated
-	if existingStatefulSet.Spec.Template.Spec.Containers[0].Image != image {
-		existingStatefulSet.Spec.Template.Spec.Containers[0].Image = image
-		updated = true
-	}
-
-	// Check if resources need to be updated
-	if !resourcesEqual(existingStatefulSet.Spec.Template.Spec.Containers[0].Resources, resources) {
-		existingStatefulSet.Spec.Template.Spec.Containers[0].Resources = resources
-		updated = true
-	}
-
-	// Check if ConfigMap reference needs to be updated
-	for i, volume := range existingStatefulSet.Spec.Template.Spec.Volumes {
-		if volume.Name == "redis-config" && volume.ConfigMap != nil && volume.ConfigMap.Name != configMap.Name {
-			existingStatefulSet.Spec.Template.Spec.Volumes[i].ConfigMap.Name = configMap.Name
-			updated = true
-		}
-	}
-
-	// Update StatefulSet if needed
-	if updated {
-		if err := r.Update(ctx, existingStatefulSet); err != nil {
-			logger.Error(err, "Failed to update Redis StatefulSet")
-			return nil, err
-		}
-		logger.Info("Updated Redis StatefulSet", "statefulSet", statefulSetName)
-	}
-
-	return existingStatefulSet, nil
-}
-
-// resourcesEqual compares two ResourceRequirements for equality
-func resourcesEqual(a, b corev1.ResourceRequirements) bool {
-	// Compare CPU requests
-	if !resourceValueEqual(a.Requests.Cpu(), b.Requests.Cpu()) {
-		return false
-	}
-
-	// Compare Memory requests
-	if !resourceValueEqual(a.Requests.Memory(), b.Requests.Memory()) {
-		return false
-	}
-
-	// Compare CPU limits
-	if !resourceValueEqual(a.Limits.Cpu(), b.Limits.Cpu()) {
-		return false
-	}
-
-	// Compare Memory limits
-	if !resourceValueEqual(a.Limits.Memory(), b.Limits.Memory()) {
-		return false
-	}
-
-	return true
-}
-
-// resourceValueEqual compares two resource.Quantity pointers for equality
-func resourceValueEqual(a, b *resource.Quantity) bool {
-	if a == nil && b == nil {
-		return true
-	}
-	if a == nil || b == nil {
-		return false
-	}
-	return a.Equal(*b)
-}
-
-// reconcileServerService creates or updates the Server Service for the tenant
-func (r *TenantReconciler) reconcileServerService(ctx context.Context, tenant *neurallogv1.Tenant) (*corev1.Service, error) {
-	logger := log.FromContext(ctx)
-
-	// Define Service name
-	serviceName := fmt.Sprintf("%s-server", tenant.Name)
-
-	// Define Service
-	service := &corev1.Service{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      serviceName,
-			Namespace: tenant.Status.Namespace,
-			Labels: map[string]string{
-				"app":                     "neurallog-server",
-				"neurallog.io/tenant":     tenant.Name,
-				"neurallog.io/component":  "server",
-				"neurallog.io/managed-by": "tenant-operator",
-			},
-		},
-		Spec: corev1.ServiceSpec{
-			Selector: map[string]string{
-				"app":                 "neurallog-server",
-				"neurallog.io/tenant": tenant.Name,
-			},
-			Ports: []corev1.ServicePort{
-				{
-					Port:       3030,
-					TargetPort: intstr.FromString("http"),
-					Name:       "http",
-				},
-			},
-			Type: corev1.ServiceTypeClusterIP,
-		},
-	}
-
-	// Set owner reference
-	if err := controllerutil.SetControllerReference(tenant, service, r.Scheme); err != nil {
-		logger.Error(err, "Failed to set owner reference on Server Service")
-		return nil, err
-	}
-
-	// Create or update Service
-	existingService := &corev1.Service{}
-	err := r.Get(ctx, client.ObjectKey{Name: serviceName, Namespace: tenant.Status.Namespace}, existingService)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			// Create Service
-			if err := r.Create(ctx, service); err != nil {
-				logger.Error(err, "Failed to create Server Service")
-				return nil, err
-			}
-			logger.Info("Created Server Service", "service", serviceName)
-			return service, nil
-		}
-		logger.Error(err, "Failed to get Server Service")
-		return nil, err
-	}
-
-	// Update Service if needed
-	updated := false
-
-	// Check if selector needs to be updated
-	if !reflect.DeepEqual(existingService.Spec.Selector, service.Spec.Selector) {
-		existingService.Spec.Selector = service.Spec.Selector
-		updated = true
-	}
-
-	// Check if ports need to be updated
-	if !reflect.DeepEqual(existingService.Spec.Ports, service.Spec.Ports) {
-		existingService.Spec.Ports = service.Spec.Ports
-		updated = true
-	}
-
-	// Update Service if needed
-	if updated {
-		if err := r.Update(ctx, existingService); err != nil {
-			logger.Error(err, "Failed to update Server Service")
-			return nil, err
-		}
-		logger.Info("Updated Server Service", "service", serviceName)
-	}
-
-	return existingService, nil
-}
-
-// reconcileServerDeployment creates or updates the Server Deployment for the tenant
-func (r *TenantReconciler) reconcileServerDeployment(ctx context.Context, tenant *neurallogv1.Tenant) (*appsv1.Deployment, error) {
-	logger := log.FromContext(ctx)
-
-	// Define Deployment name
-	deploymentName := fmt.Sprintf("%s-server", tenant.Name)
-
-	// Define labels
-	labels := map[string]string{
-		"app":                     "neurallog-server",
-		"neurallog.io/tenant":     tenant.Name,
-		"neurallog.io/component":  "server",
-		"neurallog.io/managed-by": "tenant-operator",
-	}
-
-	// Set replicas
-	replicas := int32(1)
-	if tenant.Spec.Server != nil && tenant.Spec.Server.Replicas > 0 {
-		replicas = tenant.Spec.Server.Replicas
-	}
-
-	// Set image
-	image := "neurallog/server:latest"
-	if tenant.Spec.Server != nil && tenant.Spec.Server.Image != "" {
-		image = tenant.Spec.Server.Image
-	}
-
-	// Set resource requirements
-	resources := corev1.ResourceRequirements{
-		Requests: corev1.ResourceList{
-			corev1.ResourceCPU:    resource.MustParse("100m"),
-			corev1.ResourceMemory: resource.MustParse("128Mi"),
-		},
-		Limits: corev1.ResourceList{
-			corev1.ResourceCPU:    resource.MustParse("500m"),
-			corev1.ResourceMemory: resource.MustParse("512Mi"),
-		},
-	}
-
-	if tenant.Spec.Server != nil && tenant.Spec.Server.Resources != nil {
-		if tenant.Spec.Server.Resources.CPU != nil {
-			if tenant.Spec.Server.Resources.CPU.Request != "" {
-				resources.Requests[corev1.ResourceCPU] = resource.MustParse(tenant.Spec.Server.Resources.CPU.Request)
-			}
-			if tenant.Spec.Server.Resources.CPU.Limit != "" {
-				resources.Limits[corev1.ResourceCPU] = resource.MustParse(tenant.Spec.Server.Resources.CPU.Limit)
-			}
-		}
-		if tenant.Spec.Server.Resources.Memory != nil {
-			if tenant.Spec.Server.Resources.Memory.Request != "" {
-				resources.Requests[corev1.ResourceMemory] = resource.MustParse(tenant.Spec.Server.Resources.Memory.Request)
-			}
-			if tenant.Spec.Server.Resources.Memory.Limit != "" {
-				resources.Limits[corev1.ResourceMemory] = resource.MustParse(tenant.Spec.Server.Resources.Memory.Limit)
-			}
-		}
-	}
-
-	// Define environment variables
-	env := []corev1.EnvVar{
-		{
-			Name:  "NODE_ENV",
-			Value: "production",
-		},
-		{
-			Name:  "PORT",
-			Value: "3030",
-		},
-		{
-			Name:  "REDIS_URL",
-			Value: fmt.Sprintf("redis://%s-redis:6379", tenant.Name),
-		},
-		{
-			Name:  "LOG_LEVEL",
-			Value: "info",
-		},
-		{
-			Name:  "TENANT_ID",
-			Value: tenant.Name,
-		},
-		{
-			Name:  "AUTH_URL",
-			Value: "http://auth:3000",
-		},
-	}
-
-	// Add custom environment variables if specified
-	if tenant.Spec.Server != nil && tenant.Spec.Server.Env != nil {
-		for _, customEnv := range tenant.Spec.Server.Env {
-			// Create a new EnvVar
-			newEnv := corev1.EnvVar{
-				Name: customEnv.Name,
-			}
-
-			// Set value or valueFrom
-			if customEnv.Value != "" {
-				newEnv.Value = customEnv.Value
-			} else if customEnv.ValueFrom != nil {
-				newEnv.ValueFrom = &corev1.EnvVarSource{}
-
-				// Set ConfigMapKeyRef if specified
-				if customEnv.ValueFrom.ConfigMapKeyRef != nil {
-					newEnv.ValueFrom.ConfigMapKeyRef = &corev1.ConfigMapKeySelector{
-						LocalObjectReference: corev1.LocalObjectReference{
-							Name: customEnv.ValueFrom.ConfigMapKeyRef.Name,
-						},
-						Key:      customEnv.ValueFrom.ConfigMapKeyRef.Key,
-						Optional: customEnv.ValueFrom.ConfigMapKeyRef.Optional,
-					}
-				}
-
-				// Set SecretKeyRef if specified
-				if customEnv.ValueFrom.SecretKeyRef != nil {
-					newEnv.ValueFrom.SecretKeyRef = &corev1.SecretKeySelector{
-						LocalObjectReference: corev1.LocalObjectReference{
-							Name: customEnv.ValueFrom.SecretKeyRef.Name,
-						},
-						Key:      customEnv.ValueFrom.SecretKeyRef.Key,
-						Optional: customEnv.ValueFrom.SecretKeyRef.Optional,
-					}
-				}
-			}
-
-			// Add the environment variable
-			env = append(env, newEnv)
-		}
-	}
-
-	// Define Deployment
-	deployment := &appsv1.Deployment{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      deploymentName,
-			Namespace: tenant.Status.Namespace,
-			Labels:    labels,
-		},
-		Spec: appsv1.DeploymentSpec{
-			Replicas: &replicas,
-			Selector: &metav1.LabelSelector{
-				MatchLabels: map[string]string{
-					"app":                 "neurallog-server",
-					"neurallog.io/tenant": tenant.Name,
-				},
-			},
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Labels: labels,
-				},
-				Spec: corev1.PodSpec{
-					Containers: []corev1.Container{
-						{
-							Name:            "server",
-							Image:           image,
-							ImagePullPolicy: corev1.PullIfNotPresent,
-							Ports: []corev1.ContainerPort{
-								{
-									ContainerPort: 3030,
-									Name:          "http",
-								},
-							},
-							Env:       env,
-							Resources: resources,
-							ReadinessProbe: &corev1.Probe{
-								ProbeHandler: corev1.ProbeHandler{
-									HTTPGet: &corev1.HTTPGetAction{
-										Path: "/health",
-										Port: intstr.FromString("http"),
-									},
-								},
-								InitialDelaySeconds: 5,
-								PeriodSeconds:       10,
-								TimeoutSeconds:      5,
-								SuccessThreshold:    1,
-								FailureThreshold:    3,
-							},
-							LivenessProbe: &corev1.Probe{
-								ProbeHandler: corev1.ProbeHandler{
-									HTTPGet: &corev1.HTTPGetAction{
-										Path: "/health",
-										Port: intstr.FromString("http"),
-									},
-								},
-								InitialDelaySeconds: 15,
-								PeriodSeconds:       20,
-								TimeoutSeconds:      5,
-								SuccessThreshold:    1,
-								FailureThreshold:    3,
-							},
-							VolumeMounts: []corev1.VolumeMount{
-								{
-									Name:      "tmp-volume",
-									MountPath: "/tmp",
-								},
-							},
-						},
-					},
-					Volumes: []corev1.Volume{
-						{
-							Name: "tmp-volume",
-							VolumeSource: corev1.VolumeSource{
-								EmptyDir: &corev1.EmptyDirVolumeSource{},
-							},
-						},
-					},
-				},
-			},
-		},
-	}
-
-	// Set owner reference
-	if err := controllerutil.SetControllerReference(tenant, deployment, r.Scheme); err != nil {
-		logger.Error(err, "Failed to set owner reference on Server Deployment")
-		return nil, err
-	}
-
-	// Create or update Deployment
-	existingDeployment := &appsv1.Deployment{}
-	err := r.Get(ctx, client.ObjectKey{Name: deploymentName, Namespace: tenant.Status.Namespace}, existingDeployment)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			// Create Deployment
-			if err := r.Create(ctx, deployment); err != nil {
-				logger.Error(err, "Failed to create Server Deployment")
-				return nil, err
-			}
-			logger.Info("Created Server Deployment", "deployment", deploymentName)
-			return deployment, nil
-		}
-		logger.Error(err, "Failed to get Server Deployment")
-		return nil, err
-	}
-
-	// Update Deployment if needed
-	updated := false
-
-	// Check if replicas need to be updated
-	if existingDeployment.Spec.Replicas == nil || *existingDeployment.Spec.Replicas != replicas {
-		existingDeployment.Spec.Replicas = &replicas
-		updated = true
-	}
-
-	// Check if image needs to be updated
-	if existingDeployment.Spec.Template.Spec.Containers[0].Image != image {
-		existingDeployment.Spec.Template.Spec.Containers[0].Image = image
-		updated = true
-	}
-
-	// Check if resources need to be updated
-	if !resourcesEqual(existingDeployment.Spec.Template.Spec.Containers[0].Resources, resources) {
-		existingDeployment.Spec.Template.Spec.Containers[0].Resources = resources
-		updated = true
-	}
-
-	// Check if environment variables need to be updated
-	if !envVarsEqual(existingDeployment.Spec.Template.Spec.Containers[0].Env, env) {
-		existingDeployment.Spec.Template.Spec.Containers[0].Env = env
-		updated = true
-	}
-
-	// Update Deployment if needed
-	if updated {
-		if err := r.Update(ctx, existingDeployment); err != nil {
-			logger.Error(err, "Failed to update Server Deployment")
-			return nil, err
-		}
-		logger.Info("Updated Server Deployment", "deployment", deploymentName)
-	}
-
-	return existingDeployment, nil
-}
-
-// envVarsEqual compares two slices of EnvVar for equality
-func envVarsEqual(a, b []corev1.EnvVar) bool {
-	if len(a) != len(b) {
-		return false
-	}
-
-	// Create maps for easier comparison
-	aMap := make(map[string]corev1.EnvVar)
-	for _, env := range a {
-		aMap[env.Name] = env
-	}
-
-	bMap := make(map[string]corev1.EnvVar)
-	for _, env := range b {
-		bMap[env.Name] = env
-	}
-
-	// Check if all keys in a are in b with the same values
-	for name, envA := range aMap {
-		envB, ok := bMap[name]
-		if !ok {
-			return false
-		}
-
-		// Compare Value
-		if envA.Value != envB.Value {
-			return false
-		}
-
-		// Compare ValueFrom
-		if !envVarSourceEqual(envA.ValueFrom, envB.ValueFrom) {
-			return false
-		}
-	}
-
-	// Check if all keys in b are in a
-	for name := range bMap {
-		_, ok := aMap[name]
-		if !ok {
-			return false
-		}
-	}
-
-	return true
-}
-
-// envVarSourceEqual compares two EnvVarSource pointers for equality
-func envVarSourceEqual(a, b *corev1.EnvVarSource) bool {
-	// If both are nil, they are equal
-	if a == nil && b == nil {
-		return true
-	}
-
-	// If only one is nil, they are not equal
-	if a == nil || b == nil {
-		return false
-	}
-
-	// Compare ConfigMapKeyRef
-	if a.ConfigMapKeyRef != nil && b.ConfigMapKeyRef != nil {
-		if a.ConfigMapKeyRef.Name != b.ConfigMapKeyRef.Name ||
-			a.ConfigMapKeyRef.Key != b.ConfigMapKeyRef.Key ||
-			(a.ConfigMapKeyRef.Optional != nil && b.ConfigMapKeyRef.Optional != nil && *a.ConfigMapKeyRef.Optional != *b.ConfigMapKeyRef.Optional) {
-			return false
-		}
-	} else if a.ConfigMapKeyRef != nil || b.ConfigMapKeyRef != nil {
-		return false
-	}
-
-	// Compare SecretKeyRef
-	if a.SecretKeyRef != nil && b.SecretKeyRef != nil {
-		if a.SecretKeyRef.Name != b.SecretKeyRef.Name ||
-			a.SecretKeyRef.Key != b.SecretKeyRef.Key ||
-			(a.SecretKeyRef.Optional != nil && b.SecretKeyRef.Optional != nil && *a.SecretKeyRef.Optional != *b.SecretKeyRef.Optional) {
-			return false
-		}
-	} else if a.SecretKeyRef != nil || b.SecretKeyRef != nil {
-		return false
-	}
-
-	return true
-}
-
-// SetupWithManager sets up the controller with the Manager.
-func (r *TenantReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&neurallogv1.Tenant{}).
-		Complete(r)
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: tenantName}}}
 }
@@ -19,8 +19,6 @@ package controllers
 import (
 	"context"
 	"fmt"
-	"net/http"
-	"net/http/httptest"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -28,6 +26,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -48,6 +47,7 @@ var _ = Describe("Tenant Controller", func() {
 		It("Should create a namespace, Redis, and Server resources", func() {
 			By("Creating a new Tenant")
 			ctx := context.Background()
+			replicas := int32(1)
 			tenant := &neurallogv1.Tenant{
 				TypeMeta: metav1.TypeMeta{
 					APIVersion: "neurallog.io/v1",
@@ -59,15 +59,19 @@ var _ = Describe("Tenant Controller", func() {
 				Spec: neurallogv1.TenantSpec{
 					DisplayName: "Test Tenant",
 					Description: "A tenant for testing",
+					Auth: &neurallogv1.AuthSpec{
+						AdminUserID: "test-admin",
+						AdminEmail:  "admin@example.com",
+					},
 					Redis: &neurallogv1.RedisSpec{
-						Replicas: 1,
+						Replicas: &replicas,
 						Image:    "redis:7-alpine",
-						Resources: &neurallogv1.ResourceSpec{
-							CPU: &neurallogv1.ResourceLimitSpec{
+						Resources: &neurallogv1.ResourceRequirements{
+							CPU: &neurallogv1.ResourceLimit{
 								Request: "100m",
 								Limit:   "200m",
 							},
-							Memory: &neurallogv1.ResourceLimitSpec{
+							Memory: &neurallogv1.ResourceLimit{
 								Request: "128Mi",
 								Limit:   "256Mi",
 							},
@@ -75,14 +79,14 @@ var _ = Describe("Tenant Controller", func() {
 						Storage: "1Gi",
 					},
 					Server: &neurallogv1.ServerSpec{
-						Replicas: 1,
+						Replicas: &replicas,
 						Image:    "neurallog/server:latest",
-						Resources: &neurallogv1.ResourceSpec{
-							CPU: &neurallogv1.ResourceLimitSpec{
+						Resources: &neurallogv1.ResourceRequirements{
+							CPU: &neurallogv1.ResourceLimit{
 								Request: "100m",
 								Limit:   "300m",
 							},
-							Memory: &neurallogv1.ResourceLimitSpec{
+							Memory: &neurallogv1.ResourceLimit{
 								Request: "128Mi",
 								Limit:   "256Mi",
 							},
@@ -98,28 +102,6 @@ var _ = Describe("Tenant Controller", func() {
 			}
 			Expect(k8sClient.Create(ctx, tenant)).Should(Succeed())
 
-			// Mock Auth Service
-			mockAuthServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				switch r.Method {
-				case "GET":
-					// List tenants
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusOK)
-					fmt.Fprintln(w, `{"status":"success","tenants":[]}`)
-				case "POST":
-					// Create tenant
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusCreated)
-					fmt.Fprintln(w, `{"status":"success","message":"Tenant created successfully"}`)
-				case "DELETE":
-					// Delete tenant
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusOK)
-					fmt.Fprintln(w, `{"status":"success","message":"Tenant deleted successfully"}`)
-				}
-			}))
-			defer mockAuthServer.Close()
-
 			// Wait for the tenant to be created
 			tenantLookupKey := types.NamespacedName{Name: TenantName}
 			createdTenant := &neurallogv1.Tenant{}
@@ -139,6 +121,19 @@ var _ = Describe("Tenant Controller", func() {
 				return createdTenant.Status.Phase
 			}, Timeout, Interval).Should(Equal(neurallogv1.TenantProvisioning))
 
+			// The namespace step should be reported as ready while the components are still provisioning
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, tenantLookupKey, createdTenant)
+				if err != nil {
+					return false
+				}
+				return meta.IsStatusConditionTrue(createdTenant.Status.Conditions, neurallogv1.ConditionNamespaceReady)
+			}, Timeout, Interval).Should(BeTrue())
+			readyCondition := meta.FindStatusCondition(createdTenant.Status.Conditions, neurallogv1.ConditionReady)
+			Expect(readyCondition).NotTo(BeNil())
+			Expect(readyCondition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(readyCondition.Reason).To(Equal(neurallogv1.ReasonComponentsNotReady))
+
 			// Check if the namespace was created
 			namespaceLookupKey := types.NamespacedName{Name: TenantNamespace}
 			createdNamespace := &corev1.Namespace{}
@@ -148,21 +143,21 @@ var _ = Describe("Tenant Controller", func() {
 			}, Timeout, Interval).Should(BeTrue())
 
 			// Check if the Redis resources were created
-			redisConfigMapLookupKey := types.NamespacedName{Name: fmt.Sprintf("%s-redis-config", TenantName), Namespace: TenantNamespace}
+			redisConfigMapLookupKey := types.NamespacedName{Name: "redis-config", Namespace: TenantNamespace}
 			createdRedisConfigMap := &corev1.ConfigMap{}
 			Eventually(func() bool {
 				err := k8sClient.Get(ctx, redisConfigMapLookupKey, createdRedisConfigMap)
 				return err == nil
 			}, Timeout, Interval).Should(BeTrue())
 
-			redisServiceLookupKey := types.NamespacedName{Name: fmt.Sprintf("%s-redis", TenantName), Namespace: TenantNamespace}
+			redisServiceLookupKey := types.NamespacedName{Name: "redis", Namespace: TenantNamespace}
 			createdRedisService := &corev1.Service{}
 			Eventually(func() bool {
 				err := k8sClient.Get(ctx, redisServiceLookupKey, createdRedisService)
 				return err == nil
 			}, Timeout, Interval).Should(BeTrue())
 
-			redisStatefulSetLookupKey := types.NamespacedName{Name: fmt.Sprintf("%s-redis", TenantName), Namespace: TenantNamespace}
+			redisStatefulSetLookupKey := types.NamespacedName{Name: "redis", Namespace: TenantNamespace}
 			createdRedisStatefulSet := &appsv1.StatefulSet{}
 			Eventually(func() bool {
 				err := k8sClient.Get(ctx, redisStatefulSetLookupKey, createdRedisStatefulSet)
@@ -170,14 +165,14 @@ var _ = Describe("Tenant Controller", func() {
 			}, Timeout, Interval).Should(BeTrue())
 
 			// Check if the Server resources were created
-			serverServiceLookupKey := types.NamespacedName{Name: fmt.Sprintf("%s-server", TenantName), Namespace: TenantNamespace}
+			serverServiceLookupKey := types.NamespacedName{Name: "neurallog-server", Namespace: TenantNamespace}
 			createdServerService := &corev1.Service{}
 			Eventually(func() bool {
 				err := k8sClient.Get(ctx, serverServiceLookupKey, createdServerService)
 				return err == nil
 			}, Timeout, Interval).Should(BeTrue())
 
-			serverDeploymentLookupKey := types.NamespacedName{Name: fmt.Sprintf("%s-server", TenantName), Namespace: TenantNamespace}
+			serverDeploymentLookupKey := types.NamespacedName{Name: "neurallog-server", Namespace: TenantNamespace}
 			createdServerDeployment := &appsv1.Deployment{}
 			Eventually(func() bool {
 				err := k8sClient.Get(ctx, serverDeploymentLookupKey, createdServerDeployment)
@@ -202,6 +197,16 @@ var _ = Describe("Tenant Controller", func() {
 			Expect(createdServerDeployment.Spec.Template.Spec.Containers[0].Resources.Requests.Memory().String()).To(Equal("128Mi"))
 			Expect(createdServerDeployment.Spec.Template.Spec.Containers[0].Resources.Limits.Memory().String()).To(Equal("256Mi"))
 
+			// Verify that the admin credentials were published into the tenant namespace
+			adminSecretLookupKey := types.NamespacedName{Name: fmt.Sprintf("%s-admin-credentials", TenantName), Namespace: TenantNamespace}
+			createdAdminSecret := &corev1.Secret{}
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, adminSecretLookupKey, createdAdminSecret)
+				return err == nil
+			}, Timeout, Interval).Should(BeTrue())
+			Expect(string(createdAdminSecret.Data["userId"])).To(Equal("test-admin"))
+			Expect(string(createdAdminSecret.Data["email"])).To(Equal("admin@example.com"))
+
 			// Clean up
 			By("Deleting the Tenant")
 			Expect(k8sClient.Delete(ctx, tenant)).Should(Succeed())
@@ -212,11 +217,33 @@ var _ = Describe("Tenant Controller", func() {
 				return errors.IsNotFound(err)
 			}, Timeout, Interval).Should(BeTrue())
 
-			// Verify that the namespace is deleted
+			// Verify that the namespace is being deleted. envtest runs no
+			// namespace controller, so the namespace never goes away.
 			Eventually(func() bool {
 				err := k8sClient.Get(ctx, namespaceLookupKey, createdNamespace)
-				return errors.IsNotFound(err)
+				return errors.IsNotFound(err) || createdNamespace.DeletionTimestamp != nil
 			}, Timeout, Interval).Should(BeTrue())
 		})
 	})
+
+	Context("When a tenant namespace changes", func() {
+		It("Should map the namespace to the Tenant that manages it", func() {
+			namespace := &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: TenantNamespace,
+					Labels: map[string]string{
+						"neurallog.io/tenant":     TenantName,
+						"neurallog.io/managed-by": "tenant-operator",
+					},
+				},
+			}
+			Expect(namespaceToTenant(context.Background(), namespace)).To(ConsistOf(
+				reconcile.Request{NamespacedName: types.NamespacedName{Name: TenantName}},
+			))
+
+			By("Ignoring namespaces the operator doesn't manage")
+			delete(namespace.Labels, "neurallog.io/managed-by")
+			Expect(namespaceToTenant(context.Background(), namespace)).To(BeEmpty())
+		})
+	})
 })
@@ -0,0 +1,87 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+)
+
+// tenantPlanIndex indexes Tenants by the name of their TenantPlan
+const tenantPlanIndex = "spec.plan"
+
+// mergeTenantPlan merges the tenant's plan into its spec, so the reconcile
+// steps see the effective configuration. The merged spec is never written back
+// to the Tenant, and updateStatus keeps it across status writes. It returns
+// the plan, or nil if the tenant has none.
+func mergeTenantPlan(ctx context.Context, c client.Reader, tenant *neurallogv1.Tenant) (*neurallogv1.TenantPlan, error) {
+	if tenant.Spec.Plan == "" {
+		return nil, nil
+	}
+
+	plan := &neurallogv1.TenantPlan{}
+	if err := c.Get(ctx, client.ObjectKey{Name: tenant.Spec.Plan}, plan); err != nil {
+		return nil, fmt.Errorf("failed to get TenantPlan %s: %w", tenant.Spec.Plan, err)
+	}
+	neurallogv1.MergePlan(&tenant.Spec, &plan.Spec)
+	return plan, nil
+}
+
+// updateStatus writes the status of a tenant whose spec may hold its merged
+// plan. The API server returns the stored Tenant, which would replace the
+// merged spec, so the status is written from a copy and only the metadata and
+// status are taken back.
+func (r *TenantReconciler) updateStatus(ctx context.Context, tenant *neurallogv1.Tenant) error {
+	stored := tenant.DeepCopy()
+	if err := r.Status().Update(ctx, stored); err != nil {
+		return err
+	}
+	tenant.ObjectMeta = stored.ObjectMeta
+	tenant.Status = stored.Status
+	return nil
+}
+
+// tenantPlanName returns the index key of the tenant's plan
+func tenantPlanName(obj client.Object) []string {
+	tenant, ok := obj.(*neurallogv1.Tenant)
+	if !ok || tenant.Spec.Plan == "" {
+		return nil
+	}
+	return []string{tenant.Spec.Plan}
+}
+
+// tenantPlanToTenants maps a TenantPlan to the Tenants on it, so changes to
+// the plan roll out to all of them
+func (r *TenantReconciler) tenantPlanToTenants(ctx context.Context, obj client.Object) []reconcile.Request {
+	tenants := &neurallogv1.TenantList{}
+	if err := r.List(ctx, tenants, client.MatchingFields{tenantPlanIndex: obj.GetName()}); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list Tenants on plan", "plan", obj.GetName())
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(tenants.Items))
+	for _, tenant := range tenants.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&tenant)})
+	}
+	return requests
+}
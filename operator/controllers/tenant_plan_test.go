@@ -0,0 +1,131 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+)
+
+var _ = Describe("Tenant plans", func() {
+	var r *TenantReconciler
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(neurallogv1.AddToScheme(scheme)).To(Succeed())
+		plan := &neurallogv1.TenantPlan{
+			ObjectMeta: metav1.ObjectMeta{Name: "team"},
+			Spec: neurallogv1.TenantPlanSpec{
+				Server: &neurallogv1.ServerSpec{Image: "neurallog/server:team"},
+			},
+		}
+		onPlan := &neurallogv1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{Name: "on-plan"},
+			Spec:       neurallogv1.TenantSpec{Plan: "team"},
+		}
+		offPlan := &neurallogv1.Tenant{ObjectMeta: metav1.ObjectMeta{Name: "off-plan"}}
+
+		r = &TenantReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme).
+				WithObjects(plan, onPlan, offPlan).
+				WithIndex(&neurallogv1.Tenant{}, tenantPlanIndex, tenantPlanName).
+				Build(),
+		}
+	})
+
+	It("Should merge the plan into the tenant's spec", func() {
+		tenant := &neurallogv1.Tenant{Spec: neurallogv1.TenantSpec{Plan: "team"}}
+		plan, err := mergeTenantPlan(context.Background(), r.Client, tenant)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plan.Name).To(Equal("team"))
+		Expect(tenant.Spec.Server.Image).To(Equal("neurallog/server:team"))
+	})
+
+	It("Should fail for a plan that doesn't exist", func() {
+		tenant := &neurallogv1.Tenant{Spec: neurallogv1.TenantSpec{Plan: "enterprise"}}
+		_, err := mergeTenantPlan(context.Background(), r.Client, tenant)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should roll out plan changes to the tenants on the plan", func() {
+		plan := &neurallogv1.TenantPlan{ObjectMeta: metav1.ObjectMeta{Name: "team"}}
+		requests := r.tenantPlanToTenants(context.Background(), plan)
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].Name).To(Equal("on-plan"))
+	})
+
+	It("Should run every reconcile step with the plan's settings", func() {
+		plan := &neurallogv1.TenantPlan{
+			ObjectMeta: metav1.ObjectMeta{Name: "team"},
+			Spec: neurallogv1.TenantPlanSpec{
+				Server: &neurallogv1.ServerSpec{Image: "neurallog/server:team"},
+			},
+		}
+		tenant := &neurallogv1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{Name: "on-plan", Finalizers: []string{"neurallog.io/finalizer"}},
+			Spec:       neurallogv1.TenantSpec{Plan: "team"},
+			Status:     neurallogv1.TenantStatus{Phase: neurallogv1.TenantPending, Namespace: "tenant-on-plan"},
+		}
+		r := newFakeTenantReconciler(plan, tenant)
+
+		_, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(tenant)})
+		Expect(err).NotTo(HaveOccurred())
+
+		// The server comes after the Redis step writes the status
+		deployment := &appsv1.Deployment{}
+		Expect(r.Get(context.Background(), client.ObjectKey{Name: "neurallog-server", Namespace: "tenant-on-plan"}, deployment)).To(Succeed())
+		Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(Equal("neurallog/server:team"))
+
+		stored := &neurallogv1.Tenant{}
+		Expect(r.Get(context.Background(), client.ObjectKeyFromObject(tenant), stored)).To(Succeed())
+		Expect(stored.Spec).To(Equal(neurallogv1.TenantSpec{Plan: "team"}))
+	})
+
+	It("Should remove the finalizer without writing the merged spec back", func() {
+		deletionTimestamp := metav1.Now()
+		deleting := &neurallogv1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "deleting",
+				DeletionTimestamp: &deletionTimestamp,
+				Finalizers:        []string{"neurallog.io/finalizer", "example.com/backup"},
+			},
+			Spec: neurallogv1.TenantSpec{Plan: "team"},
+		}
+		Expect(r.Create(context.Background(), deleting)).To(Succeed())
+		Expect(r.Get(context.Background(), client.ObjectKeyFromObject(deleting), deleting)).To(Succeed())
+
+		_, err := mergeTenantPlan(context.Background(), r.Client, deleting)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deleting.Spec.Server).NotTo(BeNil())
+		Expect(r.removeFinalizer(context.Background(), deleting)).To(Succeed())
+
+		stored := &neurallogv1.Tenant{}
+		Expect(r.Get(context.Background(), client.ObjectKeyFromObject(deleting), stored)).To(Succeed())
+		Expect(stored.Finalizers).To(Equal([]string{"example.com/backup"}))
+		Expect(stored.Spec).To(Equal(neurallogv1.TenantSpec{Plan: "team"}))
+	})
+})
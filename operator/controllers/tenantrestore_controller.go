@@ -0,0 +1,565 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+)
+
+const (
+	// restoreFinalizer releases the tenant if a restore is deleted while in progress
+	restoreFinalizer = "neurallog.io/restore-finalizer"
+
+	// restorePollInterval is how often a restore checks on the tenant while
+	// waiting for it to stop or start
+	restorePollInterval = 5 * time.Second
+
+	// redisDataClaimPrefix is the prefix of the Redis StatefulSet's volume claims
+	redisDataClaimPrefix = "redis-data-redis-"
+)
+
+// restoreLoadScript replaces the Redis data with the backup at $SOURCE. Redis
+// runs with appendonly enabled and ignores dump.rdb when an AOF exists, so the
+// backup also becomes the base of a fresh multi-part AOF.
+const restoreLoadScript = `set -e
+test -s "$SOURCE"
+head -c 5 "$SOURCE" | grep -q REDIS
+rm -rf /data/appendonlydir /data/appendonly.aof /data/dump.rdb
+mkdir -p /data/appendonlydir
+cp "$SOURCE" /data/dump.rdb
+cp "$SOURCE" /data/appendonlydir/appendonly.aof.1.base.rdb
+printf 'file appendonly.aof.1.base.rdb seq 1 type b\n' > /data/appendonlydir/appendonly.aof.manifest
+`
+
+// restoreDownloadScript downloads the backup from S3 with the MinIO client
+const restoreDownloadScript = `set -e
+mc alias set backup "$S3_ENDPOINT" "$S3_ACCESS_KEY_ID" "$S3_SECRET_ACCESS_KEY" > /dev/null
+mc cp "backup/$S3_BUCKET/$S3_PREFIX/$BACKUP" /work/backup.rdb
+`
+
+// TenantRestoreReconciler reconciles a TenantRestore object
+type TenantRestoreReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=neurallog.io,resources=tenantrestores,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=neurallog.io,resources=tenantrestores/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=neurallog.io,resources=tenantrestores/finalizers,verbs=update
+//+kubebuilder:rbac:groups=neurallog.io,resources=tenantplans,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create
+
+// Reconcile moves a restore through its phases: stop the tenant's server and
+// Redis, load the backup into each Redis volume, then start the tenant again.
+func (r *TenantRestoreReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	logger.Info("Reconciling TenantRestore", "restore", req.NamespacedName)
+
+	// Fetch the TenantRestore instance
+	restore := &neurallogv1.TenantRestore{}
+	if err := r.Get(ctx, req.NamespacedName, restore); err != nil {
+		if errors.IsNotFound(err) {
+			logger.Info("TenantRestore resource not found. Ignoring since object must be deleted")
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "Failed to get TenantRestore")
+		return ctrl.Result{}, err
+	}
+
+	// Fetch the Tenant being restored
+	tenant := &neurallogv1.Tenant{}
+	if err := r.Get(ctx, client.ObjectKey{Name: restore.Spec.TenantName}, tenant); err != nil {
+		if !errors.IsNotFound(err) {
+			logger.Error(err, "Failed to get Tenant")
+			return ctrl.Result{}, err
+		}
+		tenant = nil
+	}
+
+	// Release the tenant before the restore goes away
+	if !restore.DeletionTimestamp.IsZero() {
+		if err := r.releaseTenant(ctx, restore, tenant); err != nil {
+			return ctrl.Result{}, err
+		}
+		controllerutil.RemoveFinalizer(restore, restoreFinalizer)
+		if err := r.Update(ctx, restore); err != nil {
+			logger.Error(err, "Failed to remove finalizer")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// Finished restores need no further work
+	if restore.Status.Phase == neurallogv1.RestoreCompleted || restore.Status.Phase == neurallogv1.RestoreFailed {
+		return ctrl.Result{}, nil
+	}
+
+	// Add finalizer if it doesn't exist
+	if !controllerutil.ContainsFinalizer(restore, restoreFinalizer) {
+		controllerutil.AddFinalizer(restore, restoreFinalizer)
+		if err := r.Update(ctx, restore); err != nil {
+			logger.Error(err, "Failed to add finalizer")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	if tenant == nil || !tenant.DeletionTimestamp.IsZero() {
+		return r.fail(ctx, restore, tenant, fmt.Sprintf("Tenant %s does not exist", restore.Spec.TenantName))
+	}
+
+	// Restore the tenant as its plan configures it
+	if _, err := mergeTenantPlan(ctx, r.Client, tenant); err != nil {
+		logger.Error(err, "Failed to apply Tenant plan")
+		return ctrl.Result{}, err
+	}
+
+	switch restore.Status.Phase {
+	case neurallogv1.RestoreScalingDown:
+		return r.waitForScaleDown(ctx, restore, tenant)
+	case neurallogv1.RestoreLoading:
+		return r.loadBackup(ctx, restore, tenant)
+	case neurallogv1.RestoreStarting:
+		return r.waitForTenant(ctx, restore, tenant)
+	default:
+		return r.startRestore(ctx, restore, tenant)
+	}
+}
+
+// startRestore resolves the backup and claims the tenant, which makes the
+// Tenant controller scale the server and Redis to zero
+func (r *TenantRestoreReconciler) startRestore(ctx context.Context, restore *neurallogv1.TenantRestore, tenant *neurallogv1.Tenant) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	if redisSpec(tenant).Backup == nil || tenant.Status.Namespace == "" {
+		return r.fail(ctx, restore, tenant, fmt.Sprintf("Tenant %s has no Redis backup destination", tenant.Name))
+	}
+
+	// Default to the most recent backup
+	backup := restore.Spec.Backup
+	if backup == "" && tenant.Status.RedisStatus.LastBackup != nil {
+		backup = tenant.Status.RedisStatus.LastBackup.Name
+	}
+	if backup == "" {
+		return r.fail(ctx, restore, tenant, fmt.Sprintf("Tenant %s has no backup to restore", tenant.Name))
+	}
+	if strings.Contains(backup, "/") {
+		return r.fail(ctx, restore, tenant, fmt.Sprintf("Backup %q must be a file name", backup))
+	}
+
+	// Only one restore can run against a tenant at a time
+	if holder := restoreInProgress(tenant); holder != "" && holder != restore.Name {
+		restore.Status.Phase = neurallogv1.RestorePending
+		setRestoreCondition(restore, neurallogv1.RestoreConditionComplete, metav1.ConditionFalse, neurallogv1.ReasonRestoreWaiting,
+			fmt.Sprintf("Waiting for restore %s to finish", holder))
+		if err := r.Status().Update(ctx, restore); err != nil {
+			logger.Error(err, "Failed to update TenantRestore status")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: restorePollInterval}, nil
+	}
+
+	// Claim the tenant
+	if restoreInProgress(tenant) == "" {
+		patch := client.MergeFrom(tenant.DeepCopy())
+		if tenant.Annotations == nil {
+			tenant.Annotations = map[string]string{}
+		}
+		tenant.Annotations[neurallogv1.RestoreAnnotation] = restore.Name
+		if err := r.Patch(ctx, tenant, patch); err != nil {
+			logger.Error(err, "Failed to mark Tenant for restore")
+			return ctrl.Result{}, err
+		}
+		logger.Info("Stopping Tenant for restore", "tenant", tenant.Name, "backup", backup)
+	}
+
+	now := metav1.Now()
+	restore.Status.Phase = neurallogv1.RestoreScalingDown
+	restore.Status.Backup = backup
+	restore.Status.StartTime = &now
+	setRestoreCondition(restore, neurallogv1.RestoreConditionScaledDown, metav1.ConditionFalse, neurallogv1.ReasonRestoreInProgress,
+		"Stopping the server and Redis")
+	setRestoreCondition(restore, neurallogv1.RestoreConditionComplete, metav1.ConditionFalse, neurallogv1.ReasonRestoreInProgress,
+		fmt.Sprintf("Restoring backup %s", backup))
+	if err := r.Status().Update(ctx, restore); err != nil {
+		logger.Error(err, "Failed to update TenantRestore status")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: restorePollInterval}, nil
+}
+
+// waitForScaleDown waits until no server or Redis pods are left running
+func (r *TenantRestoreReconciler) waitForScaleDown(ctx context.Context, restore *neurallogv1.TenantRestore, tenant *neurallogv1.Tenant) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	namespaceName := tenant.Status.Namespace
+
+	deployment := &appsv1.Deployment{}
+	err := r.Get(ctx, client.ObjectKey{Name: "neurallog-server", Namespace: namespaceName}, deployment)
+	if err != nil && !errors.IsNotFound(err) {
+		logger.Error(err, "Failed to get Server Deployment")
+		return ctrl.Result{}, err
+	}
+	if err == nil && deployment.Status.Replicas > 0 {
+		return ctrl.Result{RequeueAfter: restorePollInterval}, nil
+	}
+
+	// Redis volumes can only be attached once the pods using them are gone
+	for _, app := range []string{"redis", redisSentinelName} {
+		pods := &corev1.PodList{}
+		if err := r.List(ctx, pods, client.InNamespace(namespaceName), client.MatchingLabels{"app": app}); err != nil {
+			logger.Error(err, "Failed to list pods", "app", app)
+			return ctrl.Result{}, err
+		}
+		if len(pods.Items) > 0 {
+			return ctrl.Result{RequeueAfter: restorePollInterval}, nil
+		}
+	}
+
+	restore.Status.Phase = neurallogv1.RestoreLoading
+	setRestoreCondition(restore, neurallogv1.RestoreConditionScaledDown, metav1.ConditionTrue, neurallogv1.ReasonRestoreSucceeded,
+		"The server and Redis are stopped")
+	setRestoreCondition(restore, neurallogv1.RestoreConditionDataLoaded, metav1.ConditionFalse, neurallogv1.ReasonRestoreInProgress,
+		fmt.Sprintf("Loading backup into 0/%d Redis volumes", redisReplicas(tenant)))
+	if err := r.Status().Update(ctx, restore); err != nil {
+		logger.Error(err, "Failed to update TenantRestore status")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{Requeue: true}, nil
+}
+
+// loadBackup loads the backup into the Redis volumes one at a time, since a
+// backup PersistentVolumeClaim can typically only be attached to one node
+func (r *TenantRestoreReconciler) loadBackup(ctx context.Context, restore *neurallogv1.TenantRestore, tenant *neurallogv1.Tenant) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	replicas := redisReplicas(tenant)
+	index := restore.Status.RestoredVolumes
+
+	// Every volume is loaded, release the tenant so it starts again
+	if index >= replicas {
+		if err := r.releaseTenant(ctx, restore, tenant); err != nil {
+			return ctrl.Result{}, err
+		}
+		restore.Status.Phase = neurallogv1.RestoreStarting
+		setRestoreCondition(restore, neurallogv1.RestoreConditionDataLoaded, metav1.ConditionTrue, neurallogv1.ReasonRestoreSucceeded,
+			fmt.Sprintf("Loaded backup into %d Redis volumes", replicas))
+		setRestoreCondition(restore, neurallogv1.RestoreConditionTenantReady, metav1.ConditionFalse, neurallogv1.ReasonRestoreInProgress,
+			"Starting the server and Redis")
+		if err := r.Status().Update(ctx, restore); err != nil {
+			logger.Error(err, "Failed to update TenantRestore status")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: restorePollInterval}, nil
+	}
+
+	// A replica that never started has no volume and syncs from the primary
+	claimName := fmt.Sprintf("%s%d", redisDataClaimPrefix, index)
+	pvc := &corev1.PersistentVolumeClaim{}
+	err := r.Get(ctx, client.ObjectKey{Name: claimName, Namespace: tenant.Status.Namespace}, pvc)
+	if err != nil && !errors.IsNotFound(err) {
+		logger.Error(err, "Failed to get Redis volume", "pvc", claimName)
+		return ctrl.Result{}, err
+	}
+	if errors.IsNotFound(err) {
+		logger.Info("Skipping missing Redis volume", "pvc", claimName)
+		return r.volumeRestored(ctx, restore, replicas)
+	}
+
+	// Create the job for this volume
+	job := &batchv1.Job{}
+	jobName := fmt.Sprintf("redis-restore-%s-%d", string(restore.UID)[:8], index)
+	err = r.Get(ctx, client.ObjectKey{Name: jobName, Namespace: tenant.Status.Namespace}, job)
+	if errors.IsNotFound(err) {
+		job, err = r.restoreJob(restore, tenant, jobName, claimName)
+		if err != nil {
+			logger.Error(err, "Failed to build restore Job")
+			return ctrl.Result{}, err
+		}
+		if err := r.Create(ctx, job); err != nil {
+			logger.Error(err, "Failed to create restore Job", "job", jobName)
+			return ctrl.Result{}, err
+		}
+		logger.Info("Created restore Job", "job", jobName, "pvc", claimName)
+		return ctrl.Result{}, nil
+	}
+	if err != nil {
+		logger.Error(err, "Failed to get restore Job", "job", jobName)
+		return ctrl.Result{}, err
+	}
+
+	// Wait for the job to finish
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			return r.fail(ctx, restore, tenant, fmt.Sprintf("Restore Job %s failed: %s", jobName, condition.Message))
+		}
+	}
+	if job.Status.Succeeded == 0 {
+		return ctrl.Result{}, nil
+	}
+	return r.volumeRestored(ctx, restore, replicas)
+}
+
+// volumeRestored records that one more Redis volume holds the backup
+func (r *TenantRestoreReconciler) volumeRestored(ctx context.Context, restore *neurallogv1.TenantRestore, replicas int32) (ctrl.Result, error) {
+	restore.Status.RestoredVolumes++
+	setRestoreCondition(restore, neurallogv1.RestoreConditionDataLoaded, metav1.ConditionFalse, neurallogv1.ReasonRestoreInProgress,
+		fmt.Sprintf("Loading backup into %d/%d Redis volumes", restore.Status.RestoredVolumes, replicas))
+	if err := r.Status().Update(ctx, restore); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to update TenantRestore status")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{Requeue: true}, nil
+}
+
+// restoreJob builds the Job that loads the backup into one Redis volume
+func (r *TenantRestoreReconciler) restoreJob(restore *neurallogv1.TenantRestore, tenant *neurallogv1.Tenant, jobName, claimName string) (*batchv1.Job, error) {
+	redis := redisSpec(tenant)
+	redisImage := neurallogv1.DefaultRedisImage
+	if redis.Image != "" {
+		redisImage = redis.Image
+	}
+
+	env := []corev1.EnvVar{
+		{
+			Name:  "BACKUP",
+			Value: restore.Status.Backup,
+		},
+	}
+	volumeMounts := []corev1.VolumeMount{
+		{
+			Name:      "redis-data",
+			MountPath: "/data",
+		},
+	}
+	volumes := []corev1.Volume{
+		{
+			Name: "redis-data",
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: claimName,
+				},
+			},
+		},
+	}
+
+	// Make the backup available to the load container
+	var initContainers []corev1.Container
+	if redis.Backup.Destination.S3 != nil {
+		workMount := corev1.VolumeMount{Name: "work", MountPath: "/work"}
+		initContainers = append(initContainers, corev1.Container{
+			Name:         "download",
+			Image:        redisBackupUploaderImage(tenant),
+			Command:      []string{"sh", "-c", restoreDownloadScript},
+			Env:          append(env, redisBackupS3Env(tenant)...),
+			VolumeMounts: []corev1.VolumeMount{workMount},
+		})
+		env = append(env, corev1.EnvVar{Name: "SOURCE", Value: "/work/backup.rdb"})
+		volumeMounts = append(volumeMounts, workMount)
+		volumes = append(volumes, corev1.Volume{
+			Name: "work",
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		})
+	} else {
+		env = append(env, corev1.EnvVar{Name: "SOURCE", Value: "/backups/" + restore.Status.Backup})
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      "backups",
+			MountPath: "/backups",
+			ReadOnly:  true,
+		})
+		volumes = append(volumes, corev1.Volume{
+			Name: "backups",
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: redisBackupClaimName(tenant),
+					ReadOnly:  true,
+				},
+			},
+		})
+	}
+
+	labels := map[string]string{
+		"app":                    "redis-restore",
+		"neurallog.io/tenant":    tenant.Name,
+		"neurallog.io/component": "redis",
+		"neurallog.io/restore":   restore.Name,
+	}
+	backoffLimit := int32(2)
+
+	// Create Job object
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      jobName,
+			Namespace: tenant.Status.Namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy:  corev1.RestartPolicyNever,
+					InitContainers: initContainers,
+					Containers: []corev1.Container{
+						{
+							Name:         "load",
+							Image:        redisImage,
+							Command:      []string{"sh", "-c", restoreLoadScript},
+							Env:          env,
+							VolumeMounts: volumeMounts,
+						},
+					},
+					Volumes: volumes,
+				},
+			},
+		},
+	}
+
+	// The job goes away with the restore
+	if err := controllerutil.SetControllerReference(restore, job, r.Scheme); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// waitForTenant waits until the server and Redis are running again
+func (r *TenantRestoreReconciler) waitForTenant(ctx context.Context, restore *neurallogv1.TenantRestore, tenant *neurallogv1.Tenant) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	namespaceName := tenant.Status.Namespace
+
+	statefulSet := &appsv1.StatefulSet{}
+	if err := r.Get(ctx, client.ObjectKey{Name: "redis", Namespace: namespaceName}, statefulSet); err != nil {
+		logger.Error(err, "Failed to get Redis StatefulSet")
+		return ctrl.Result{}, err
+	}
+	deployment := &appsv1.Deployment{}
+	if err := r.Get(ctx, client.ObjectKey{Name: "neurallog-server", Namespace: namespaceName}, deployment); err != nil {
+		logger.Error(err, "Failed to get Server Deployment")
+		return ctrl.Result{}, err
+	}
+
+	// A suspended tenant keeps the restored data until it resumes
+	if tenant.Status.Suspension == nil &&
+		(statefulSet.Status.ReadyReplicas < redisReplicas(tenant) || deployment.Status.ReadyReplicas < serverReplicas(tenant)) {
+		return ctrl.Result{RequeueAfter: restorePollInterval}, nil
+	}
+
+	now := metav1.Now()
+	restore.Status.Phase = neurallogv1.RestoreCompleted
+	restore.Status.CompletionTime = &now
+	setRestoreCondition(restore, neurallogv1.RestoreConditionTenantReady, metav1.ConditionTrue, neurallogv1.ReasonRestoreSucceeded,
+		"The server and Redis are running")
+	setRestoreCondition(restore, neurallogv1.RestoreConditionComplete, metav1.ConditionTrue, neurallogv1.ReasonRestoreSucceeded,
+		fmt.Sprintf("Restored backup %s", restore.Status.Backup))
+	if err := r.Status().Update(ctx, restore); err != nil {
+		logger.Error(err, "Failed to update TenantRestore status")
+		return ctrl.Result{}, err
+	}
+	logger.Info("Restored Tenant", "tenant", tenant.Name, "backup", restore.Status.Backup)
+	return ctrl.Result{}, nil
+}
+
+// fail moves the restore to the Failed phase and releases the tenant. Failed
+// restores are not retried; create a new TenantRestore to try again.
+func (r *TenantRestoreReconciler) fail(ctx context.Context, restore *neurallogv1.TenantRestore, tenant *neurallogv1.Tenant, message string) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	if err := r.releaseTenant(ctx, restore, tenant); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Fail the step that was in progress
+	for _, conditionType := range []string{
+		neurallogv1.RestoreConditionScaledDown,
+		neurallogv1.RestoreConditionDataLoaded,
+		neurallogv1.RestoreConditionTenantReady,
+	} {
+		condition := meta.FindStatusCondition(restore.Status.Conditions, conditionType)
+		if condition != nil && condition.Status != metav1.ConditionTrue {
+			setRestoreCondition(restore, conditionType, metav1.ConditionFalse, neurallogv1.ReasonRestoreFailed, message)
+		}
+	}
+
+	now := metav1.Now()
+	restore.Status.Phase = neurallogv1.RestoreFailed
+	restore.Status.CompletionTime = &now
+	setRestoreCondition(restore, neurallogv1.RestoreConditionComplete, metav1.ConditionFalse, neurallogv1.ReasonRestoreFailed, message)
+	if err := r.Status().Update(ctx, restore); err != nil {
+		logger.Error(err, "Failed to update TenantRestore status")
+		return ctrl.Result{}, err
+	}
+	logger.Info("Restore failed", "tenant", restore.Spec.TenantName, "reason", message)
+	return ctrl.Result{}, nil
+}
+
+// releaseTenant removes the restore annotation from the tenant if this restore holds it
+func (r *TenantRestoreReconciler) releaseTenant(ctx context.Context, restore *neurallogv1.TenantRestore, tenant *neurallogv1.Tenant) error {
+	if tenant == nil || restoreInProgress(tenant) != restore.Name {
+		return nil
+	}
+	patch := client.MergeFrom(tenant.DeepCopy())
+	delete(tenant.Annotations, neurallogv1.RestoreAnnotation)
+	if err := r.Patch(ctx, tenant, patch); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to release Tenant after restore")
+		return err
+	}
+	return nil
+}
+
+// setRestoreCondition sets a condition on the restore for its current generation
+func setRestoreCondition(restore *neurallogv1.TenantRestore, conditionType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&restore.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: restore.Generation,
+	})
+}
+
+// restoreInProgress returns the name of the TenantRestore that has stopped the
+// tenant, or an empty string
+func restoreInProgress(tenant *neurallogv1.Tenant) string {
+	return tenant.Annotations[neurallogv1.RestoreAnnotation]
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *TenantRestoreReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&neurallogv1.TenantRestore{}).
+		Owns(&batchv1.Job{}).
+		Complete(r)
+}
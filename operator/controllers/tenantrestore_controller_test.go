@@ -0,0 +1,81 @@
+/*
+Copyright 2023 NeuralLog Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	neurallogv1 "github.com/neurallog/operator/api/v1"
+)
+
+var _ = Describe("TenantRestore Controller", func() {
+	const (
+		Timeout  = time.Second * 10
+		Interval = time.Millisecond * 250
+	)
+
+	Context("When the tenant can't be restored", func() {
+		It("Should fail a restore of a missing Tenant", func() {
+			ctx := context.Background()
+			restore := &neurallogv1.TenantRestore{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "restore-missing-tenant",
+				},
+				Spec: neurallogv1.TenantRestoreSpec{
+					TenantName: "missing-tenant",
+				},
+			}
+			Expect(k8sClient.Create(ctx, restore)).Should(Succeed())
+
+			restoreLookupKey := types.NamespacedName{Name: restore.Name}
+			createdRestore := &neurallogv1.TenantRestore{}
+			Eventually(func() neurallogv1.RestorePhase {
+				if err := k8sClient.Get(ctx, restoreLookupKey, createdRestore); err != nil {
+					return ""
+				}
+				return createdRestore.Status.Phase
+			}, Timeout, Interval).Should(Equal(neurallogv1.RestoreFailed))
+
+			condition := meta.FindStatusCondition(createdRestore.Status.Conditions, neurallogv1.RestoreConditionComplete)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(neurallogv1.ReasonRestoreFailed))
+			Expect(createdRestore.Status.CompletionTime).NotTo(BeNil())
+		})
+	})
+
+	Context("When a tenant is being restored", func() {
+		It("Should report the restore that stopped it", func() {
+			tenant := &neurallogv1.Tenant{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{neurallogv1.RestoreAnnotation: "nightly"},
+				},
+			}
+			Expect(restoreInProgress(tenant)).To(Equal("nightly"))
+
+			delete(tenant.Annotations, neurallogv1.RestoreAnnotation)
+			Expect(restoreInProgress(tenant)).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,69 @@
+#!/usr/bin/env bash
+
+# Copyright 2023 NeuralLog Authors.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# Generates the typed clientset, listers and informers for the neurallog.io
+# API under pkg/client. Deep-copy functions are generated by controller-gen
+# (make generate).
+
+set -o errexit
+set -o nounset
+set -o pipefail
+
+SCRIPT_ROOT=$(cd "$(dirname "${BASH_SOURCE[0]}")/.." && pwd)
+CODEGEN_VERSION=${CODEGEN_VERSION:-v0.28.3}
+CODEGEN_BIN=${CODEGEN_BIN:-${SCRIPT_ROOT}/bin}
+
+MODULE=github.com/neurallog/operator
+APIS_PKG=${MODULE}/api/v1
+OUTPUT_PKG=${MODULE}/pkg/client
+HEADER=${SCRIPT_ROOT}/hack/boilerplate.go.txt
+
+for tool in client-gen lister-gen informer-gen; do
+  if [ ! -x "${CODEGEN_BIN}/${tool}" ]; then
+    GOBIN=${CODEGEN_BIN} go install k8s.io/code-generator/cmd/${tool}@${CODEGEN_VERSION}
+  fi
+done
+
+# The generators write to $GOPATH/src/<package>, so generate into a
+# temporary tree and copy the result back into the module.
+OUTPUT_BASE=$(mktemp -d)
+trap 'rm -rf "${OUTPUT_BASE}"' EXIT
+
+"${CODEGEN_BIN}/client-gen" \
+  --clientset-name versioned \
+  --input-base "" \
+  --input "${APIS_PKG}" \
+  --output-package "${OUTPUT_PKG}/clientset" \
+  --output-base "${OUTPUT_BASE}" \
+  --go-header-file "${HEADER}"
+
+"${CODEGEN_BIN}/lister-gen" \
+  --input-dirs "${APIS_PKG}" \
+  --output-package "${OUTPUT_PKG}/listers" \
+  --output-base "${OUTPUT_BASE}" \
+  --go-header-file "${HEADER}"
+
+"${CODEGEN_BIN}/informer-gen" \
+  --input-dirs "${APIS_PKG}" \
+  --versioned-clientset-package "${OUTPUT_PKG}/clientset/versioned" \
+  --listers-package "${OUTPUT_PKG}/listers" \
+  --output-package "${OUTPUT_PKG}/informers" \
+  --output-base "${OUTPUT_BASE}" \
+  --go-header-file "${HEADER}"
+
+rm -rf "${SCRIPT_ROOT}/pkg/client"
+mkdir -p "${SCRIPT_ROOT}/pkg"
+cp -r "${OUTPUT_BASE}/${OUTPUT_PKG}" "${SCRIPT_ROOT}/pkg/"
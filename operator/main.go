@@ -19,6 +19,7 @@ package main
 import (
 	"flag"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -28,10 +29,14 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	neurallogv1 "github.com/neurallog/operator/api/v1"
+	neurallogv2 "github.com/neurallog/operator/api/v2"
 	"github.com/neurallog/operator/controllers"
 	//+kubebuilder:scaffold:imports
 )
@@ -45,6 +50,7 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(neurallogv1.AddToScheme(scheme))
+	utilruntime.Must(neurallogv2.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
@@ -52,11 +58,38 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var resyncPeriod time.Duration
+	var authCleanupTimeout time.Duration
+	var authSyncInterval time.Duration
+	var authDeleteOrphans bool
+	var authConfig controllers.AuthClientConfig
+	var openFGAConfig controllers.OpenFGAClientConfig
+	var operatorNamespace string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.DurationVar(&resyncPeriod, "resync-period", 0,
+		"How often tenants are reconciled when none of their resources changed. Zero disables periodic resync.")
+	flag.StringVar(&authConfig.URL, "auth-service-url", controllers.DefaultAuthServiceURL, "The base URL of the NeuralLog Auth service.")
+	flag.DurationVar(&authConfig.Timeout, "auth-service-timeout", 10*time.Second, "The timeout for requests to the Auth service.")
+	flag.StringVar(&authConfig.TokenFile, "auth-service-token-file", "", "Path to a file containing a bearer token for the Auth service.")
+	flag.StringVar(&authConfig.CAFile, "auth-service-ca-file", "", "Path to a CA bundle used to verify the Auth service certificate.")
+	flag.StringVar(&authConfig.CertFile, "auth-service-cert-file", "", "Path to a client certificate for mTLS with the Auth service.")
+	flag.StringVar(&authConfig.KeyFile, "auth-service-key-file", "", "Path to the client key for mTLS with the Auth service.")
+	flag.DurationVar(&authCleanupTimeout, "auth-cleanup-timeout", controllers.DefaultAuthCleanupTimeout,
+		"How long removing a deleted tenant from the Auth service is retried before waiting for the skip annotation.")
+	flag.DurationVar(&authSyncInterval, "auth-sync-interval", controllers.DefaultAuthSyncInterval,
+		"How often the tenants in the Auth service are listed and compared with the Tenant resources.")
+	flag.BoolVar(&authDeleteOrphans, "auth-delete-orphans", false,
+		"Delete tenants from the Auth service that have no Tenant resource.")
+	flag.StringVar(&openFGAConfig.URL, "openfga-url", "",
+		"The base URL of the OpenFGA HTTP API. Tenants with spec.authorization require it.")
+	flag.DurationVar(&openFGAConfig.Timeout, "openfga-timeout", 10*time.Second, "The timeout for requests to OpenFGA.")
+	flag.StringVar(&openFGAConfig.TokenFile, "openfga-token-file", "", "Path to a file containing a preshared key for OpenFGA.")
+	flag.StringVar(&operatorNamespace, "operator-namespace", os.Getenv("POD_NAMESPACE"),
+		"The namespace the operator runs in. Besides their own namespace, tenants may only reference Secrets in it.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -64,11 +97,12 @@ func main() {
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+	neurallogv1.OperatorNamespace = operatorNamespace
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
-		MetricsBindAddress:     metricsAddr,
-		Port:                   9443,
+		Metrics:                metricsserver.Options{BindAddress: metricsAddr},
+		WebhookServer:          webhook.NewServer(webhook.Options{Port: 9443}),
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "neurallog-operator.neurallog.io",
@@ -78,13 +112,71 @@ func main() {
 		os.Exit(1)
 	}
 
+	authClient, err := controllers.NewAuthClient(authConfig)
+	if err != nil {
+		setupLog.Error(err, "unable to create Auth service client")
+		os.Exit(1)
+	}
+
+	var openFGAClient controllers.OpenFGAClient
+	if openFGAConfig.URL != "" {
+		openFGAClient, err = controllers.NewOpenFGAClient(openFGAConfig)
+		if err != nil {
+			setupLog.Error(err, "unable to create OpenFGA client")
+			os.Exit(1)
+		}
+	}
+
+	// Share one listing of the Auth service tenants between the sync loop and reconciles
+	authTenants := controllers.NewAuthTenantCache(authClient, authSyncInterval)
+	authSyncEvents := make(chan event.GenericEvent)
+	if err = mgr.Add(&controllers.AuthSyncer{
+		Client:        mgr.GetClient(),
+		Cache:         authTenants,
+		Recorder:      mgr.GetEventRecorderFor("auth-sync"),
+		Interval:      authSyncInterval,
+		DeleteOrphans: authDeleteOrphans,
+		Events:        authSyncEvents,
+	}); err != nil {
+		setupLog.Error(err, "unable to add Auth service sync")
+		os.Exit(1)
+	}
+
 	if err = (&controllers.TenantReconciler{
+		Client:             mgr.GetClient(),
+		Scheme:             mgr.GetScheme(),
+		AuthClient:         authClient,
+		AuthTenants:        authTenants,
+		AuthSyncEvents:     authSyncEvents,
+		OpenFGA:            openFGAClient,
+		ResyncPeriod:       resyncPeriod,
+		Recorder:           mgr.GetEventRecorderFor("tenant-controller"),
+		AuthCleanupTimeout: authCleanupTimeout,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Tenant")
+		os.Exit(1)
+	}
+	if err = (&controllers.TenantRestoreReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Tenant")
+		setupLog.Error(err, "unable to create controller", "controller", "TenantRestore")
 		os.Exit(1)
 	}
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&neurallogv1.Tenant{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Tenant")
+			os.Exit(1)
+		}
+		if err = (&neurallogv2.Tenant{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Tenant", "version", "v2")
+			os.Exit(1)
+		}
+		if err = (&neurallogv1.TenantPlan{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "TenantPlan")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {